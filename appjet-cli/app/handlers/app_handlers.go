@@ -20,7 +20,8 @@ import (
 )
 
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token              string `json:"token"`
+	MustChangePassword bool   `json:"must-change-password"`
 }
 
 func HandleHelpCommand(arguments []string, config models.Configuration) {
@@ -101,6 +102,9 @@ func HandleLoginCommand(arguments []string, config models.Configuration) {
 			return
 		}
 		fmt.Println("Login successful.")
+		if tokenResponse.MustChangePassword {
+			fmt.Println("Your password must be changed before running other commands: ./appjet password")
+		}
 		// Proceed with the logic for successful login
 	} else {
 		fmt.Println("Login failed. Status code:", response.StatusCode)
@@ -112,13 +116,18 @@ func HandleLogoutCommand(arguments []string, config models.Configuration) {
 
 	token, _ := services.DecryptToken()
 
-	// Construct the logout URL with the token
-	url := fmt.Sprintf("%s/appjet/logout/%s", config.IdentityProvider.ServerURL, token)
+	// The token goes in the Authorization header, never in the URL
+	req, err := http.NewRequest("POST", config.IdentityProvider.ServerURL+"/appjet/logout", nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
 
-	// Make HTTP GET request
-	response, err := http.Get(url)
+	// Make HTTP POST request
+	response, err := http.DefaultClient.Do(req)
 	if err != nil {
-		fmt.Println("Error making HTTP GET request:", err)
+		fmt.Println("Error making HTTP POST request:", err)
 		return
 	}
 	defer response.Body.Close()
@@ -159,11 +168,11 @@ func HandleCheckAliveCommand(arguments []string, config models.Configuration) {
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/check-alive"
+		url = projectURL(config) + "/check-alive"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/check-alive/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/check-alive/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/check-alive/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/check-alive/%s/%s", projectURL(config), arguments[0], arguments[1])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -176,28 +185,41 @@ func HandleCheckAliveCommand(arguments []string, config models.Configuration) {
 
 }
 
+// HandleConfigureCommand pushes the local configuration to the decision manager, which stores it and
+// configures the servers. With --stored the configuration already stored by the decision manager is
+// re-applied instead and the local one is not sent.
 func HandleConfigureCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+	arguments, stored := extractFlag(arguments, "--stored")
+
 	token, err := services.DecryptToken()
 	if err != nil {
 		fmt.Println("Error decrypting token:", err)
 		return
 	}
 
-	// Convert the config struct to JSON
-	configJSON, err := json.Marshal(config)
-	if err != nil {
-		fmt.Println("Error marshaling configuration to JSON:", err)
-		return
+	// Convert the config struct to JSON, an empty body re-applies the stored configuration
+	var configJSON []byte
+	if !stored {
+		if !printValidationErrors("config.json", config) {
+			return
+		}
+
+		configJSON, err = json.Marshal(config)
+		if err != nil {
+			fmt.Println("Error marshaling configuration to JSON:", err)
+			return
+		}
 	}
 
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/configure"
+		url = projectURL(config) + "/configure"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/configure/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/configure/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/configure/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/configure/%s/%s", projectURL(config), arguments[0], arguments[1])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -212,19 +234,11 @@ func HandleConfigureCommand(arguments []string, config models.Configuration) {
 
 	// Set the Authorization header with the decrypted token
 	req.Header.Set("Authorization", token)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending HTTP request:", err)
-		return
+	if !stored {
+		req.Header.Set("Content-Type", "application/json")
 	}
-	defer resp.Body.Close()
 
-	// Print the response status
-	fmt.Println("Appjet configured sucessfully in the servers specified.")
+	submitJob(req, wait, token, config)
 }
 
 func HandleInspectCommand(arguments []string, config models.Configuration) {
@@ -234,13 +248,13 @@ func HandleInspectCommand(arguments []string, config models.Configuration) {
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/inspect"
+		url = projectURL(config) + "/inspect"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/inspect/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/inspect/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/inspect/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/inspect/%s/%s", projectURL(config), arguments[0], arguments[1])
 	case 3:
-		url = fmt.Sprintf("%s/appjet/inspect/%s/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1], arguments[2])
+		url = fmt.Sprintf("%s/inspect/%s/%s/%s", projectURL(config), arguments[0], arguments[1], arguments[2])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -253,19 +267,21 @@ func HandleInspectCommand(arguments []string, config models.Configuration) {
 }
 
 func HandleStartCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+
 	// Your existing code here
 	token, _ := services.DecryptToken()
 
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/start"
+		url = projectURL(config) + "/start"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/start/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/start/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/start/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/start/%s/%s", projectURL(config), arguments[0], arguments[1])
 	case 3:
-		url = fmt.Sprintf("%s/appjet/start/%s/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1], arguments[2])
+		url = fmt.Sprintf("%s/start/%s/%s/%s", projectURL(config), arguments[0], arguments[1], arguments[2])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -274,23 +290,32 @@ func HandleStartCommand(arguments []string, config models.Configuration) {
 	fmt.Println("Token:", token)
 	fmt.Println("URL:", url)
 
-	makeGETRequest(url, token)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	submitJob(req, wait, token, config)
 }
 
 func HandleRestartCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+
 	// Your existing code here
 	token, _ := services.DecryptToken()
 
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/restart"
+		url = projectURL(config) + "/restart"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/restart/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/restart/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/restart/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/restart/%s/%s", projectURL(config), arguments[0], arguments[1])
 	case 3:
-		url = fmt.Sprintf("%s/appjet/restart/%s/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1], arguments[2])
+		url = fmt.Sprintf("%s/restart/%s/%s/%s", projectURL(config), arguments[0], arguments[1], arguments[2])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -299,23 +324,32 @@ func HandleRestartCommand(arguments []string, config models.Configuration) {
 	fmt.Println("Token:", token)
 	fmt.Println("URL:", url)
 
-	makeGETRequest(url, token)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	submitJob(req, wait, token, config)
 }
 
 func HandleStopCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+
 	// Your existing code here
 	token, _ := services.DecryptToken()
 
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/stop"
+		url = projectURL(config) + "/stop"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/stop/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/stop/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/stop/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/stop/%s/%s", projectURL(config), arguments[0], arguments[1])
 	case 3:
-		url = fmt.Sprintf("%s/appjet/stop/%s/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1], arguments[2])
+		url = fmt.Sprintf("%s/stop/%s/%s/%s", projectURL(config), arguments[0], arguments[1], arguments[2])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -324,23 +358,32 @@ func HandleStopCommand(arguments []string, config models.Configuration) {
 	fmt.Println("Token:", token)
 	fmt.Println("URL:", url)
 
-	makeGETRequest(url, token)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	submitJob(req, wait, token, config)
 }
 
 func HandleCleanCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+
 	// Your existing code here
 	token, _ := services.DecryptToken()
 
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/clean"
+		url = projectURL(config) + "/clean"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/clean/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/clean/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/clean/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/clean/%s/%s", projectURL(config), arguments[0], arguments[1])
 	case 3:
-		url = fmt.Sprintf("%s/appjet/clean/%s/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1], arguments[2])
+		url = fmt.Sprintf("%s/clean/%s/%s/%s", projectURL(config), arguments[0], arguments[1], arguments[2])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -349,10 +392,19 @@ func HandleCleanCommand(arguments []string, config models.Configuration) {
 	fmt.Println("Token:", token)
 	fmt.Println("URL:", url)
 
-	makeGETRequest(url, token)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	submitJob(req, wait, token, config)
 }
 
 func HandleScriptsCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+
 	token, err := services.DecryptToken()
 	if err != nil {
 		fmt.Println("Error decrypting token:", err)
@@ -435,11 +487,11 @@ func HandleScriptsCommand(arguments []string, config models.Configuration) {
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/scripts"
+		url = projectURL(config) + "/scripts"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/scripts/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/scripts/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/scripts/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/scripts/%s/%s", projectURL(config), arguments[0], arguments[1])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -458,30 +510,22 @@ func HandleScriptsCommand(arguments []string, config models.Configuration) {
 	// Set the Authorization header with the decrypted token
 	req.Header.Set("Authorization", token)
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending HTTP request:", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	// Print the response status
-	fmt.Println("Response Status:", resp.Status)
+	submitJob(req, wait, token, config)
 }
 
 func HandleCodeCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+
 	token, _ := services.DecryptToken()
 
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/code"
+		url = projectURL(config) + "/code"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/code/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/code/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/code/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/code/%s/%s", projectURL(config), arguments[0], arguments[1])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -498,31 +542,32 @@ func HandleCodeCommand(arguments []string, config models.Configuration) {
 	}
 
 	// Make the POST request
-	resp, err := makePOSTRequest(url)
+	req, err := http.NewRequest("POST", url, nil)
 	if err != nil {
-		fmt.Println("Error making POST request:", err)
+		fmt.Println("Error creating HTTP request:", err)
 		return
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Authorization", token)
 
-	// Print the response status
-	fmt.Println("Response Status:", resp.Status)
+	submitJob(req, wait, token, config)
 }
 
 func HandleSCPRunCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+
 	// Your existing code here
 	token, _ := services.DecryptToken()
 
 	var url string
 	switch len(arguments) {
 	case 0:
-		url = config.IdentityProvider.ServerURL + "/appjet/scp/run"
+		url = projectURL(config) + "/scp/run"
 	case 1:
-		url = fmt.Sprintf("%s/appjet/scp/run/%s", config.IdentityProvider.ServerURL, arguments[0])
+		url = fmt.Sprintf("%s/scp/run/%s", projectURL(config), arguments[0])
 	case 2:
-		url = fmt.Sprintf("%s/appjet/scp/run/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1])
+		url = fmt.Sprintf("%s/scp/run/%s/%s", projectURL(config), arguments[0], arguments[1])
 	case 3:
-		url = fmt.Sprintf("%s/appjet/scp/run/%s/%s/%s", config.IdentityProvider.ServerURL, arguments[0], arguments[1], arguments[2])
+		url = fmt.Sprintf("%s/scp/run/%s/%s/%s", projectURL(config), arguments[0], arguments[1], arguments[2])
 	default:
 		fmt.Println("Invalid number of arguments")
 		return
@@ -531,7 +576,14 @@ func HandleSCPRunCommand(arguments []string, config models.Configuration) {
 	fmt.Println("Token:", token)
 	fmt.Println("URL:", url)
 
-	makeGETRequest(url, token)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	submitJob(req, wait, token, config)
 }
 
 func HandleUnknownCommand(arguments []string, config models.Configuration) {
@@ -557,6 +609,11 @@ func makeGETRequest(url string, token string) {
 	}
 	defer response.Body.Close()
 
+	printResponse(response)
+}
+
+// printResponse prints the formatted JSON body and, for fan-out commands, the overall outcome.
+func printResponse(response *http.Response) {
 	// Read the response body, if needed
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
@@ -564,24 +621,16 @@ func makeGETRequest(url string, token string) {
 		return
 	}
 
-	var formattedJSON bytes.Buffer
-	err = json.Indent(&formattedJSON, body, "", "    ")
-	if err != nil {
-		fmt.Println("Error formatting JSON:", err)
-		return
-	}
-
-	fmt.Println("Response:")
-	fmt.Println(formattedJSON.String())
-}
+	printJSON(body)
 
-func makePOSTRequest(url string) (*http.Response, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return nil, err
+	var fanOut struct {
+		Outcome string `json:"outcome"`
+		Total   int    `json:"total"`
+		Failed  int    `json:"failed"`
+	}
+	if err := json.Unmarshal(body, &fanOut); err == nil && fanOut.Outcome != "" {
+		fmt.Printf("Outcome: %s (%d of %d servers failed)\n", fanOut.Outcome, fanOut.Failed, fanOut.Total)
 	}
-	return client.Do(req)
 }
 
 func sendFilesViaSCP(serverURL, username, password string, arguments []string) error {
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+const auditUsage = `Usage:
+  ./appjet audit [--since 24h] [--until :time] [--user :username] [--project :project] [--action :action] [--cluster :cluster] [--server :server] [--outcome :outcome] [--limit 100]
+  ./appjet audit --export [filters] > audit.jsonl`
+
+// auditFilters are the flags of the audit command, sent as query parameters of the same name
+var auditFilters = []string{"since", "until", "user", "action", "cluster", "server", "outcome", "limit"}
+
+// HandleAuditCommand queries the audit log, or exports it as JSON lines with --export
+func HandleAuditCommand(arguments []string, config models.Configuration) {
+	arguments, export := extractFlag(arguments, "--export")
+
+	query := url.Values{}
+	for _, filter := range auditFilters {
+		var value string
+		arguments, value = extractFlagValue(arguments, "--"+filter)
+		if value != "" {
+			query.Set(filter, value)
+		}
+	}
+	if len(arguments) != 0 {
+		fmt.Println(auditUsage)
+		return
+	}
+	// --project is read for every command, here it filters the events
+	if project := services.SelectedProject(); project != "" {
+		query.Set("project", project)
+	}
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	if !export {
+		makeGETRequest(config.IdentityProvider.ServerURL+"/appjet/audit?"+query.Encode(), token)
+		return
+	}
+
+	query.Set("format", "jsonl")
+	req, err := http.NewRequest(http.MethodGet, config.IdentityProvider.ServerURL+"/appjet/audit?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("Error making HTTP GET request:", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		printResponse(response)
+		return
+	}
+
+	// the events are streamed as they are read, one JSON object per line
+	if _, err := io.Copy(os.Stdout, response.Body); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading response body:", err)
+	}
+}
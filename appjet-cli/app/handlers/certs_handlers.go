@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const certsUsage = `Usage:
+  ./appjet certs list
+  ./appjet certs enroll :cluster :server --csr daemon.csr [--out :dir]
+  ./appjet certs delete :cluster :server`
+
+// HandleCertsCommand enrolls daemons in the internal CA of the decision manager
+func HandleCertsCommand(arguments []string, config models.Configuration) {
+	arguments, csrFile := extractFlagValue(arguments, "--csr")
+	arguments, outDir := extractFlagValue(arguments, "--out")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	switch {
+	case len(arguments) == 1 && arguments[0] == "list":
+		makeGETRequest(config.IdentityProvider.ServerURL+"/appjet/certificates", token)
+	case len(arguments) == 3 && arguments[0] == "enroll" && csrFile != "":
+		enrollDaemon(config, token, arguments[1], arguments[2], csrFile, outDir)
+	case len(arguments) == 3 && arguments[0] == "delete":
+		makeJSONRequest(http.MethodDelete, certificateURL(config, arguments[1], arguments[2]), token, nil)
+	default:
+		fmt.Println(certsUsage)
+	}
+}
+
+// enrollDaemon sends the CSR of a daemon and writes the issued daemon.crt and the ca.crt to outDir
+func enrollDaemon(config models.Configuration, token string, cluster string, server string, csrFile string, outDir string) {
+	csr, err := os.ReadFile(csrFile)
+	if err != nil {
+		fmt.Println("Error reading the certificate request:", err)
+		return
+	}
+
+	payload, err := json.Marshal(map[string]string{"csr": string(csr)})
+	if err != nil {
+		fmt.Println("Error marshaling request to JSON:", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, certificateURL(config, cluster, server), bytes.NewBuffer(payload))
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("Error making HTTP POST request:", err)
+		return
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		fmt.Println("Error reading response body:", err)
+		return
+	}
+
+	if response.StatusCode != http.StatusOK {
+		printJSON(body)
+		fmt.Println("Enrollment failed. Status code:", response.StatusCode)
+		return
+	}
+
+	var enrolled struct {
+		Certificate string `json:"certificate"`
+		CA          string `json:"ca"`
+		Fingerprint string `json:"fingerprint"`
+		NotAfter    string `json:"not-after"`
+	}
+	if err := json.Unmarshal(body, &enrolled); err != nil {
+		fmt.Println("Error parsing JSON response:", err)
+		return
+	}
+
+	if outDir == "" {
+		outDir = "."
+	}
+	certificateFile, caFile := filepath.Join(outDir, "daemon.crt"), filepath.Join(outDir, "ca.crt")
+	if err := os.WriteFile(certificateFile, []byte(enrolled.Certificate), 0644); err != nil {
+		fmt.Println("Error writing the certificate:", err)
+		return
+	}
+	if err := os.WriteFile(caFile, []byte(enrolled.CA), 0644); err != nil {
+		fmt.Println("Error writing the CA certificate:", err)
+		return
+	}
+
+	fmt.Println("Certificate pinned, fingerprint", enrolled.Fingerprint, "valid until", enrolled.NotAfter)
+	fmt.Println("Copy", certificateFile, "and", caFile, "to the tls directory of the daemon and restart it")
+}
+
+func certificateURL(config models.Configuration, cluster string, server string) string {
+	return config.IdentityProvider.ServerURL + "/appjet/certificates/" + url.PathEscape(cluster) + "/" + url.PathEscape(server)
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/websocket"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const execUsage = `Usage:
+  ./appjet exec :cluster :server :container [--timeout 30s] -- :command [args...]
+  ./appjet exec :cluster :server :container -it -- :command [args...]   interactive, attached to a TTY`
+
+// timedOutExitCode is the exit code of a command killed after its timeout, as with timeout(1)
+const timedOutExitCode = 124
+
+// execResult is the answer of the daemon to a one-shot command
+type execResult struct {
+	ExitCode  int    `json:"exit-code"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated"`
+	TimedOut  bool   `json:"timed-out"`
+	KillError string `json:"kill-error"`
+}
+
+// execControl is a control message of an interactive session, the TTY goes through binary frames
+type execControl struct {
+	Type     string `json:"type"`
+	ExitCode int    `json:"exit-code"`
+	Error    string `json:"error"`
+}
+
+// execFrame is a websocket frame with its payload type, to tell control messages apart from the TTY
+type execFrame struct {
+	payloadType byte
+	data        []byte
+}
+
+var execFrameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		frame := v.(execFrame)
+		return frame.data, frame.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*execFrame)
+		frame.data, frame.payloadType = data, payloadType
+		return nil
+	},
+}
+
+// HandleExecCommand runs a command in a container of a server. The output of the command is printed as
+// is and the CLI exits with its exit code. With -it the command is attached to a TTY instead.
+func HandleExecCommand(arguments []string, config models.Configuration) {
+	// everything after -- is the command, its flags are not ours
+	var command []string
+	for index, argument := range arguments {
+		if argument == "--" {
+			arguments, command = arguments[:index], arguments[index+1:]
+			break
+		}
+	}
+	arguments, interactive := extractFlag(arguments, "-it")
+	arguments, timeout := extractFlagValue(arguments, "--timeout")
+	if len(arguments) != 3 || len(command) == 0 {
+		fmt.Println(execUsage)
+		return
+	}
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	execURL := fmt.Sprintf("%s/exec/%s/%s/%s", projectURL(config),
+		url.PathEscape(arguments[0]), url.PathEscape(arguments[1]), url.PathEscape(arguments[2]))
+
+	if interactive {
+		os.Exit(runInteractiveExec(execURL+"/tty", command, token, config))
+	}
+	os.Exit(runExec(execURL, command, timeout, token))
+}
+
+// runExec runs a one-shot command, prints its stdout and stderr and returns its exit code
+func runExec(execURL string, command []string, timeout string, token string) int {
+	payload, err := json.Marshal(map[string]interface{}{"command": command, "timeout": timeout})
+	if err != nil {
+		fmt.Println("Error encoding the command:", err)
+		return 1
+	}
+
+	req, err := http.NewRequest(http.MethodPost, execURL, bytes.NewReader(payload))
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return 1
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("Error making HTTP POST request:", err)
+		return 1
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		fmt.Println("Error reading response body:", err)
+		return 1
+	}
+
+	var fanOut struct {
+		Results []struct {
+			Server string          `json:"server"`
+			Status string          `json:"status"`
+			Error  string          `json:"error"`
+			Body   json.RawMessage `json:"body"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &fanOut); err != nil || len(fanOut.Results) == 0 {
+		printJSON(body)
+		return 1
+	}
+
+	server := fanOut.Results[0]
+	if server.Status != "ok" {
+		fmt.Fprintf(os.Stderr, "Error on server %s: %s\n", server.Server, server.Error)
+		return 1
+	}
+
+	var result execResult
+	if err := json.Unmarshal(server.Body, &result); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading the result of the command:", err)
+		return 1
+	}
+
+	fmt.Print(result.Stdout)
+	fmt.Fprint(os.Stderr, result.Stderr)
+	if result.Truncated {
+		fmt.Fprintln(os.Stderr, "(output truncated)")
+	}
+	if result.TimedOut && result.KillError != "" {
+		fmt.Fprintln(os.Stderr, "The command timed out and could not be killed:", result.KillError)
+		return timedOutExitCode
+	}
+	if result.TimedOut {
+		fmt.Fprintln(os.Stderr, "The command timed out and was killed")
+		return timedOutExitCode
+	}
+
+	return result.ExitCode
+}
+
+// runInteractiveExec attaches the terminal to a command running in a TTY and returns its exit code
+func runInteractiveExec(ttyURL string, command []string, token string, config models.Configuration) int {
+	query := url.Values{"command": command}
+	if rows, cols, ok := terminalSize(); ok {
+		query.Set("rows", rows)
+		query.Set("cols", cols)
+	}
+
+	// http:// becomes ws:// and https:// becomes wss://
+	location := "ws" + strings.TrimPrefix(ttyURL, "http") + "?" + query.Encode()
+	wsConfig, err := websocket.NewConfig(location, config.IdentityProvider.ServerURL)
+	if err != nil {
+		fmt.Println("Error creating the session:", err)
+		return 1
+	}
+	wsConfig.Header.Set("Authorization", token)
+
+	ws, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		fmt.Println("Error opening the session, check the container is running and your role allows exec:", err)
+		return 1
+	}
+	defer ws.Close()
+
+	restore := makeTerminalRaw()
+	defer restore()
+
+	go func() {
+		buffer := make([]byte, 1024)
+		for {
+			n, err := os.Stdin.Read(buffer)
+			if n > 0 {
+				if execFrameCodec.Send(ws, execFrame{websocket.BinaryFrame, buffer[:n]}) != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	exitCode := -1
+	for {
+		var frame execFrame
+		if err := execFrameCodec.Receive(ws, &frame); err != nil {
+			break
+		}
+
+		if frame.payloadType == websocket.BinaryFrame {
+			os.Stdout.Write(frame.data)
+			continue
+		}
+
+		var control execControl
+		if json.Unmarshal(frame.data, &control) != nil {
+			continue
+		}
+		switch control.Type {
+		case "exit":
+			exitCode = control.ExitCode
+		case "error":
+			fmt.Fprintf(os.Stderr, "\r\nError: %s\r\n", control.Error)
+		}
+	}
+
+	if exitCode < 0 {
+		return 1
+	}
+	return exitCode
+}
+
+// makeTerminalRaw switches the terminal to raw mode with stty, so keys reach the remote TTY as they are
+// typed, and returns the function restoring it. Nothing changes when stdin is not a terminal.
+func makeTerminalRaw() func() {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return func() {}
+	}
+
+	state, err := stty("-g")
+	if err != nil {
+		return func() {}
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return func() {}
+	}
+
+	return func() {
+		stty(strings.TrimSpace(state))
+	}
+}
+
+// terminalSize returns the rows and columns of the terminal, as stty prints them
+func terminalSize() (string, string, bool) {
+	size, err := stty("size")
+	if err != nil {
+		return "", "", false
+	}
+
+	fields := strings.Fields(size)
+	if len(fields) != 2 {
+		return "", "", false
+	}
+
+	return fields[0], fields[1], true
+}
+
+func stty(arguments ...string) (string, error) {
+	cmd := exec.Command("stty", arguments...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// jobPollInterval is how often --wait asks the decision manager for the job status
+const jobPollInterval = 2 * time.Second
+
+type jobResponse struct {
+	Job struct {
+		ID      string `json:"id"`
+		Status  string `json:"status"`
+		Outcome string `json:"outcome"`
+		Tasks   []struct {
+			Status string `json:"status"`
+		} `json:"tasks"`
+	} `json:"job"`
+}
+
+func HandleJobsCommand(arguments []string, config models.Configuration) {
+	token, _ := services.DecryptToken()
+
+	url := projectURL(config) + "/jobs"
+	if len(arguments) == 1 {
+		url = fmt.Sprintf("%s?status=%s", url, arguments[0])
+	}
+
+	makeGETRequest(url, token)
+}
+
+func HandleJobCommand(arguments []string, config models.Configuration) {
+	token, _ := services.DecryptToken()
+
+	if len(arguments) != 1 {
+		fmt.Println("Usage: ./appjet job :id")
+		return
+	}
+
+	makeGETRequest(fmt.Sprintf("%s/jobs/%s", projectURL(config), arguments[0]), token)
+}
+
+// extractFlag removes a boolean flag from the arguments and reports whether it was present
+func extractFlag(arguments []string, flag string) ([]string, bool) {
+	remaining := make([]string, 0, len(arguments))
+	found := false
+	for _, argument := range arguments {
+		if argument == flag {
+			found = true
+			continue
+		}
+		remaining = append(remaining, argument)
+	}
+
+	return remaining, found
+}
+
+// extractFlagValue removes a "--flag value" pair from the arguments and returns its value
+func extractFlagValue(arguments []string, flag string) ([]string, string) {
+	remaining := make([]string, 0, len(arguments))
+	value := ""
+	for index := 0; index < len(arguments); index++ {
+		if arguments[index] == flag && index+1 < len(arguments) {
+			value = arguments[index+1]
+			index++
+			continue
+		}
+		remaining = append(remaining, arguments[index])
+	}
+
+	return remaining, value
+}
+
+// submitJob sends a request that starts a job, prints the answer and, with --wait,
+// follows the job until it finishes
+func submitJob(req *http.Request, wait bool, token string, config models.Configuration) {
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("Error sending HTTP request:", err)
+		return
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		fmt.Println("Error reading response body:", err)
+		return
+	}
+
+	printJSON(body)
+
+	if !wait || response.StatusCode != http.StatusAccepted {
+		return
+	}
+
+	var submitted jobResponse
+	if err := json.Unmarshal(body, &submitted); err != nil || submitted.Job.ID == "" {
+		fmt.Println("Error reading job id from response:", err)
+		return
+	}
+
+	waitForJob(submitted.Job.ID, token, config)
+}
+
+// waitForJob polls the job until it succeeded or failed and prints its final state. It exits with 1 when
+// the job cannot be read.
+func waitForJob(id string, token string, config models.Configuration) {
+	url := fmt.Sprintf("%s/jobs/%s", projectURL(config), id)
+	lastProgress := ""
+
+	for {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			fmt.Println("Error creating HTTP request:", err)
+			return
+		}
+		req.Header.Set("Authorization", token)
+
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			fmt.Println("Error making HTTP GET request:", err)
+			return
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			fmt.Println("Error reading response body:", err)
+			return
+		}
+
+		// an expired session, a missing scope or an unknown job never reaches a final status
+		if response.StatusCode != http.StatusOK {
+			var failure struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(body, &failure); err != nil || failure.Error == "" {
+				failure.Error = string(body)
+			}
+			fmt.Fprintf(os.Stderr, "Error waiting for job %s (status code %d): %s\n", id, response.StatusCode, failure.Error)
+			os.Exit(1)
+		}
+
+		var current jobResponse
+		if err := json.Unmarshal(body, &current); err != nil {
+			fmt.Println("Error parsing job:", err)
+			return
+		}
+
+		// the servers of the batches a halted rollout never started are skipped
+		done, skipped := 0, 0
+		for _, task := range current.Job.Tasks {
+			switch task.Status {
+			case "succeeded", "failed":
+				done++
+			case "skipped":
+				done++
+				skipped++
+			}
+		}
+
+		progress := fmt.Sprintf("Job %s: %s (%d/%d servers done)", id, current.Job.Status, done, len(current.Job.Tasks))
+		if skipped > 0 {
+			progress = fmt.Sprintf("Job %s: %s (%d/%d servers done, %d skipped)", id, current.Job.Status, done, len(current.Job.Tasks), skipped)
+		}
+		if progress != lastProgress {
+			fmt.Println(progress)
+			lastProgress = progress
+		}
+
+		if current.Job.Status == "succeeded" || current.Job.Status == "failed" {
+			printJSON(body)
+			fmt.Println("Outcome:", current.Job.Outcome)
+			return
+		}
+
+		time.Sleep(jobPollInterval)
+	}
+}
+
+func printJSON(body []byte) {
+	var formattedJSON bytes.Buffer
+	if err := json.Indent(&formattedJSON, body, "", "    "); err != nil {
+		fmt.Println(string(body))
+		return
+	}
+
+	fmt.Println("Response:")
+	fmt.Println(formattedJSON.String())
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const joinTokensUsage = `Usage:
+  ./appjet join-tokens list
+  ./appjet join-tokens create --cluster :cluster [--expires 24h]
+  ./appjet join-tokens revoke :id`
+
+// HandleJoinTokensCommand manages the one-time tokens daemons join the decision manager with
+func HandleJoinTokensCommand(arguments []string, config models.Configuration) {
+	arguments, cluster := extractFlagValue(arguments, "--cluster")
+	arguments, expires := extractFlagValue(arguments, "--expires")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	baseURL := config.IdentityProvider.ServerURL + "/appjet/join-tokens"
+	switch {
+	case len(arguments) == 1 && arguments[0] == "list":
+		makeGETRequest(baseURL, token)
+	case len(arguments) == 1 && arguments[0] == "create" && cluster != "":
+		makeJSONRequest(http.MethodPost, baseURL, token, map[string]string{"cluster": cluster, "expires": expires})
+	case len(arguments) == 2 && arguments[0] == "revoke":
+		makeJSONRequest(http.MethodDelete, baseURL+"/"+url.PathEscape(arguments[1]), token, nil)
+	default:
+		fmt.Println(joinTokensUsage)
+	}
+}
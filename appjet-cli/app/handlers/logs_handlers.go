@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const logsUsage = `Usage:
+  ./appjet logs :cluster :server :container [-f] [--tail N] [--since 10m] [--timestamps]
+  ./appjet logs :cluster :container [-f] [--tail N] [--since 10m] [--timestamps]   every server of the cluster`
+
+// logLine is a "log" or "error" event of the logs stream
+type logLine struct {
+	Cluster string `json:"cluster"`
+	Server  string `json:"server"`
+	Line    string `json:"line"`
+	Error   string `json:"error"`
+}
+
+// HandleLogsCommand prints the logs of a container on one server, or on every server of a cluster with
+// each line prefixed by its server. With -f the logs are followed until interrupted.
+func HandleLogsCommand(arguments []string, config models.Configuration) {
+	arguments, follow := extractFlag(arguments, "-f")
+	arguments, timestamps := extractFlag(arguments, "--timestamps")
+	arguments, tail := extractFlagValue(arguments, "--tail")
+	arguments, since := extractFlagValue(arguments, "--since")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	query := url.Values{}
+	logsURL := projectURL(config) + "/logs/"
+	switch len(arguments) {
+	case 2:
+		logsURL += url.PathEscape(arguments[0])
+		query.Set("container", arguments[1])
+	case 3:
+		logsURL += url.PathEscape(arguments[0]) + "/" + url.PathEscape(arguments[1]) + "/" + url.PathEscape(arguments[2])
+	default:
+		fmt.Println(logsUsage)
+		return
+	}
+	if follow {
+		query.Set("follow", "true")
+	}
+	if timestamps {
+		query.Set("timestamps", "true")
+	}
+	if tail != "" {
+		query.Set("tail", tail)
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, logsURL+"?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Accept", "text/event-stream")
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("Error making HTTP GET request:", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		printResponse(response)
+		return
+	}
+
+	printLogEvents(bufio.NewScanner(response.Body), len(arguments) == 2)
+}
+
+// printLogEvents prints the server-sent events of the logs stream as they arrive
+func printLogEvents(scanner *bufio.Scanner, prefixServer bool) {
+	scanner.Buffer(make([]byte, 64*1024), 2*1024*1024)
+
+	event := ""
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(text, "event:"))
+		case strings.HasPrefix(text, "data:"):
+			var line logLine
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(text, "data:")), &line); err != nil {
+				continue
+			}
+
+			prefix := ""
+			if prefixServer {
+				prefix = "[" + line.Server + "] "
+			}
+			if event == "error" {
+				fmt.Fprintln(os.Stderr, prefix+"Error: "+line.Error)
+			} else {
+				fmt.Println(prefix + line.Line)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading the logs:", err)
+	}
+}
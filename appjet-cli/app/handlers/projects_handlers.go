@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const projectsUsage = `Usage:
+  ./appjet projects [list]
+  ./appjet projects create :project [--description "text"]
+  ./appjet projects delete :project
+  ./appjet projects use :project        (default goes back to the default project)`
+
+// projectURL returns the base url of the routes of the current project, /appjet for the default one
+func projectURL(config models.Configuration) string {
+	baseURL := config.IdentityProvider.ServerURL + "/appjet"
+	if project := services.CurrentProject(); project != services.DefaultProject {
+		baseURL += "/projects/" + url.PathEscape(project)
+	}
+
+	return baseURL
+}
+
+// HandleProjectsCommand manages the projects of the decision manager and chooses the one the next
+// commands work on
+func HandleProjectsCommand(arguments []string, config models.Configuration) {
+	arguments, description := extractFlagValue(arguments, "--description")
+
+	// choosing a project does not need the decision manager
+	if len(arguments) == 2 && arguments[0] == "use" {
+		if err := services.SaveProject(arguments[1]); err != nil {
+			fmt.Println("Error saving the project:", err)
+			return
+		}
+		fmt.Println("The next commands work on project " + arguments[1])
+		return
+	}
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	baseURL := config.IdentityProvider.ServerURL + "/appjet/projects"
+	switch {
+	case len(arguments) == 0 || (len(arguments) == 1 && arguments[0] == "list"):
+		fmt.Println("Current project:", services.CurrentProject())
+		makeGETRequest(baseURL, token)
+	case len(arguments) == 2 && arguments[0] == "create":
+		makeJSONRequest(http.MethodPost, baseURL, token, map[string]interface{}{
+			"name":        arguments[1],
+			"description": description,
+		})
+	case len(arguments) == 2 && arguments[0] == "delete":
+		makeJSONRequest(http.MethodDelete, baseURL+"/"+url.PathEscape(arguments[1]), token, nil)
+	default:
+		fmt.Println(projectsUsage)
+	}
+}
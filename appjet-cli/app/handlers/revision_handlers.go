@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+func HandleRevisionsCommand(arguments []string, config models.Configuration) {
+	token, _ := services.DecryptToken()
+
+	makeGETRequest(projectURL(config)+"/revisions", token)
+}
+
+func HandleRevisionCommand(arguments []string, config models.Configuration) {
+	token, _ := services.DecryptToken()
+
+	if len(arguments) != 1 {
+		fmt.Println("Usage: ./appjet revision :id")
+		return
+	}
+
+	makeGETRequest(fmt.Sprintf("%s/revisions/%s", projectURL(config), arguments[0]), token)
+}
+
+func HandleRollbackCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+	arguments, revision := extractFlagValue(arguments, "--to")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	if revision == "" {
+		fmt.Println("Usage: ./appjet rollback [:cluster [:server]] --to :revision [--wait]")
+		return
+	}
+
+	var rollbackURL string
+	switch len(arguments) {
+	case 0:
+		rollbackURL = projectURL(config) + "/rollback"
+	case 1:
+		rollbackURL = fmt.Sprintf("%s/rollback/%s", projectURL(config), arguments[0])
+	case 2:
+		rollbackURL = fmt.Sprintf("%s/rollback/%s/%s", projectURL(config), arguments[0], arguments[1])
+	default:
+		fmt.Println("Invalid number of arguments")
+		return
+	}
+	rollbackURL += "?to=" + url.QueryEscape(revision)
+
+	req, err := http.NewRequest("POST", rollbackURL, nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	submitJob(req, wait, token, config)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func HandleRolloutCommand(arguments []string, config models.Configuration) {
+	arguments, wait := extractFlag(arguments, "--wait")
+	arguments, maxUnavailable := extractFlagValue(arguments, "--max-unavailable")
+	arguments, healthTimeout := extractFlagValue(arguments, "--health-timeout")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	if len(arguments) != 1 {
+		fmt.Println("Usage: ./appjet rollout :cluster [--max-unavailable N] [--health-timeout 5m] [--wait]")
+		return
+	}
+
+	options := map[string]interface{}{}
+	if maxUnavailable != "" {
+		value, err := strconv.Atoi(maxUnavailable)
+		if err != nil {
+			fmt.Println("Invalid --max-unavailable:", maxUnavailable)
+			return
+		}
+		options["maxUnavailable"] = value
+	}
+	if healthTimeout != "" {
+		options["healthTimeout"] = healthTimeout
+	}
+
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		fmt.Println("Error marshaling rollout options to JSON:", err)
+		return
+	}
+
+	url := fmt.Sprintf("%s/rollout/%s", projectURL(config), arguments[0])
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(optionsJSON))
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	submitJob(req, wait, token, config)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const secretsUsage = `Usage:
+  ./appjet secrets list
+  ./appjet secrets set :cluster :server [--secret :secret]
+  ./appjet secrets delete :cluster :server`
+
+// HandleSecretsCommand registers the secrets generated by the daemons, which every call to them is signed with
+func HandleSecretsCommand(arguments []string, config models.Configuration) {
+	arguments, secret := extractFlagValue(arguments, "--secret")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	switch {
+	case len(arguments) == 1 && arguments[0] == "list":
+		makeGETRequest(config.IdentityProvider.ServerURL+"/appjet/secrets", token)
+	case len(arguments) == 3 && arguments[0] == "set":
+		if secret == "" {
+			secret = prompt("Secret printed by appjet-server-daemon secret generate|rotate: ")
+		}
+		makeJSONRequest(http.MethodPut, secretURL(config, arguments[1], arguments[2]), token, map[string]string{"secret": secret})
+	case len(arguments) == 3 && arguments[0] == "delete":
+		makeJSONRequest(http.MethodDelete, secretURL(config, arguments[1], arguments[2]), token, nil)
+	default:
+		fmt.Println(secretsUsage)
+	}
+}
+
+func secretURL(config models.Configuration, cluster string, server string) string {
+	return config.IdentityProvider.ServerURL + "/appjet/secrets/" + url.PathEscape(cluster) + "/" + url.PathEscape(server)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const serversUsage = `Usage:
+  ./appjet servers [list]
+  ./appjet servers delete :cluster :server`
+
+// HandleServersCommand lists and removes the servers whose daemons joined the decision manager
+func HandleServersCommand(arguments []string, config models.Configuration) {
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	switch {
+	case len(arguments) == 0 || (len(arguments) == 1 && arguments[0] == "list"):
+		makeGETRequest(config.IdentityProvider.ServerURL+"/appjet/servers", token)
+	case len(arguments) == 3 && arguments[0] == "delete":
+		makeJSONRequest(http.MethodDelete, config.IdentityProvider.ServerURL+"/appjet/servers/"+url.PathEscape(arguments[1])+"/"+url.PathEscape(arguments[2]), token, nil)
+	default:
+		fmt.Println(serversUsage)
+	}
+}
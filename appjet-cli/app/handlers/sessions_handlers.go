@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const sessionsUsage = `Usage:
+  ./appjet sessions
+  ./appjet sessions --user :username
+  ./appjet sessions revoke :id`
+
+// HandleSessionsCommand lists active sessions and revokes them
+func HandleSessionsCommand(arguments []string, config models.Configuration) {
+	arguments, username := extractFlagValue(arguments, "--user")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	switch {
+	case len(arguments) == 0 && username == "":
+		makeGETRequest(config.IdentityProvider.ServerURL+"/appjet/sessions", token)
+	case len(arguments) == 0:
+		makeGETRequest(config.IdentityProvider.ServerURL+"/appjet/users/"+url.PathEscape(username)+"/sessions", token)
+	case len(arguments) == 2 && arguments[0] == "revoke":
+		makeJSONRequest(http.MethodDelete, config.IdentityProvider.ServerURL+"/appjet/sessions/"+url.PathEscape(arguments[1]), token, nil)
+	default:
+		fmt.Println(sessionsUsage)
+	}
+}
+
+// HandleRefreshCommand replaces the saved session token by a new one with a new expiry
+func HandleRefreshCommand(arguments []string, config models.Configuration) {
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	req, err := http.NewRequest("POST", config.IdentityProvider.ServerURL+"/appjet/sessions/refresh", nil)
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+	req.Header.Set("Authorization", token)
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("Error making HTTP POST request:", err)
+		return
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		fmt.Println("Error reading response body:", err)
+		return
+	}
+
+	if response.StatusCode != http.StatusOK {
+		printJSON(body)
+		fmt.Println("Refresh failed. Status code:", response.StatusCode)
+		return
+	}
+
+	var refreshResponse struct {
+		Token     string `json:"token"`
+		ExpiresAt string `json:"expires-at"`
+	}
+	if err := json.Unmarshal(body, &refreshResponse); err != nil {
+		fmt.Println("Error parsing JSON response:", err)
+		return
+	}
+
+	if err := services.EncryptAndSaveToken(refreshResponse.Token); err != nil {
+		fmt.Println("Error generating token security file:", err)
+		return
+	}
+
+	fmt.Println("Session refreshed, it expires at", refreshResponse.ExpiresAt)
+}
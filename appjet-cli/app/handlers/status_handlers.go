@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/url"
+)
+
+const statusUsage = `Usage:
+  ./appjet status [:cluster [:server]] [--window 24h]`
+
+// HandleStatusCommand shows the health recorded by the health monitor of the decision manager
+func HandleStatusCommand(arguments []string, config models.Configuration) {
+	arguments, window := extractFlagValue(arguments, "--window")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	statusURL := projectURL(config) + "/status"
+	switch len(arguments) {
+	case 0:
+	case 1:
+		statusURL += "/" + url.PathEscape(arguments[0])
+	case 2:
+		statusURL += "/" + url.PathEscape(arguments[0]) + "/" + url.PathEscape(arguments[1])
+	default:
+		fmt.Println(statusUsage)
+		return
+	}
+	if window != "" {
+		statusURL += "?window=" + url.QueryEscape(window)
+	}
+
+	makeGETRequest(statusURL, token)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/url"
+)
+
+const templatesUsage = `Usage:
+  ./appjet templates [:cluster [:server]]`
+
+// HandleTemplatesCommand lists the runtime templates of the daemons, the languages and frameworks the
+// Dockerfile of the application can be built for
+func HandleTemplatesCommand(arguments []string, config models.Configuration) {
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	templatesURL := projectURL(config) + "/templates"
+	switch len(arguments) {
+	case 0:
+	case 1:
+		templatesURL += "/" + url.PathEscape(arguments[0])
+	case 2:
+		templatesURL += "/" + url.PathEscape(arguments[0]) + "/" + url.PathEscape(arguments[1])
+	default:
+		fmt.Println(templatesUsage)
+		return
+	}
+
+	makeGETRequest(templatesURL, token)
+}
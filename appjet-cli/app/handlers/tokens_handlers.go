@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const tokensUsage = `Usage:
+  ./appjet tokens list
+  ./appjet tokens create [:name] --scope start,check-alive [--expires 90d]
+  ./appjet tokens revoke :id`
+
+// defaultTokenName names the tokens created without a name
+const defaultTokenName = "ci"
+
+// HandleTokensCommand lists, creates and revokes API tokens
+func HandleTokensCommand(arguments []string, config models.Configuration) {
+	arguments, scopes := extractFlagValue(arguments, "--scope")
+	arguments, expires := extractFlagValue(arguments, "--expires")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	switch {
+	case len(arguments) == 1 && arguments[0] == "list":
+		makeGETRequest(config.IdentityProvider.ServerURL+"/appjet/tokens", token)
+	case len(arguments) >= 1 && len(arguments) <= 2 && arguments[0] == "create" && scopes != "":
+		name := defaultTokenName
+		if len(arguments) == 2 {
+			name = arguments[1]
+		}
+		makeJSONRequest(http.MethodPost, config.IdentityProvider.ServerURL+"/appjet/tokens", token, map[string]interface{}{
+			"name":    name,
+			"scopes":  strings.Split(scopes, ","),
+			"expires": expires,
+		})
+	case len(arguments) == 2 && arguments[0] == "revoke":
+		makeJSONRequest(http.MethodDelete, config.IdentityProvider.ServerURL+"/appjet/tokens/"+url.PathEscape(arguments[1]), token, nil)
+	default:
+		fmt.Println(tokensUsage)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const usersUsage = `Usage:
+  ./appjet users list
+  ./appjet users create :username :role [--name "Full Name"]
+  ./appjet users delete :username
+  ./appjet users set-role :username :role
+  ./appjet users reset-password :username`
+
+// HandleUsersCommand manages users (admin only): list, create, delete, set-role and reset-password
+func HandleUsersCommand(arguments []string, config models.Configuration) {
+	arguments, name := extractFlagValue(arguments, "--name")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	if len(arguments) == 0 {
+		fmt.Println(usersUsage)
+		return
+	}
+
+	usersURL := config.IdentityProvider.ServerURL + "/appjet/users"
+
+	switch {
+	case arguments[0] == "list" && len(arguments) == 1:
+		makeGETRequest(usersURL, token)
+
+	case arguments[0] == "create" && len(arguments) == 3:
+		password := prompt("Temporary password: ")
+		makeJSONRequest(http.MethodPost, usersURL, token, map[string]string{
+			"username": arguments[1],
+			"name":     name,
+			"role":     arguments[2],
+			"password": password,
+		})
+
+	case arguments[0] == "delete" && len(arguments) == 2:
+		makeJSONRequest(http.MethodDelete, usersURL+"/"+url.PathEscape(arguments[1]), token, nil)
+
+	case arguments[0] == "set-role" && len(arguments) == 3:
+		makeJSONRequest(http.MethodPut, usersURL+"/"+url.PathEscape(arguments[1])+"/role", token, map[string]string{
+			"role": arguments[2],
+		})
+
+	case arguments[0] == "reset-password" && len(arguments) == 2:
+		password := prompt("Temporary password: ")
+		makeJSONRequest(http.MethodPut, usersURL+"/"+url.PathEscape(arguments[1])+"/password", token, map[string]string{
+			"password": password,
+		})
+
+	default:
+		fmt.Println(usersUsage)
+	}
+}
+
+// HandlePasswordCommand changes the password of the logged in user
+func HandlePasswordCommand(arguments []string, config models.Configuration) {
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	currentPassword := prompt("Current password: ")
+	newPassword := prompt("New password: ")
+	if prompt("Repeat the new password: ") != newPassword {
+		fmt.Println("Passwords do not match")
+		return
+	}
+
+	makeJSONRequest(http.MethodPut, config.IdentityProvider.ServerURL+"/appjet/password", token, map[string]string{
+		"current-password": currentPassword,
+		"new-password":     newPassword,
+	})
+}
+
+func prompt(label string) string {
+	var value string
+	fmt.Print(label)
+	fmt.Scanln(&value)
+
+	return value
+}
+
+// makeJSONRequest sends payload (if any) as JSON and prints the response
+func makeJSONRequest(method string, url string, token string, payload interface{}) {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			fmt.Println("Error marshaling request to JSON:", err)
+			return
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		fmt.Println("Error creating HTTP request:", err)
+		return
+	}
+
+	req.Header.Set("Authorization", token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("Error making HTTP request:", err)
+		return
+	}
+	defer response.Body.Close()
+
+	printResponse(response)
+}
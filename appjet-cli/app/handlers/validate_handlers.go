@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"os"
+)
+
+// HandleValidateCommand checks config.json (or the given file) locally, without contacting the
+// decision manager, and exits with status 1 when it is not valid so it can be used in CI.
+func HandleValidateCommand(arguments []string, config models.Configuration) {
+	path := "config.json"
+	switch len(arguments) {
+	case 0:
+	case 1:
+		path = arguments[0]
+	default:
+		fmt.Println("Usage: ./appjet validate [:file]")
+		return
+	}
+
+	config, err := services.ReadConfiguration(path)
+	if err != nil {
+		fmt.Println("Error reading configuration:", err)
+		os.Exit(1)
+	}
+
+	if !printValidationErrors(path, config) {
+		os.Exit(1)
+	}
+
+	fmt.Println(path, "is valid")
+}
+
+// printValidationErrors prints every problem found in the configuration and reports whether it is valid
+func printValidationErrors(path string, config models.Configuration) bool {
+	errs := config.Validate()
+	if len(errs) == 0 {
+		return true
+	}
+
+	fmt.Printf("%s is not valid (%d errors):\n", path, len(errs))
+	for _, fieldError := range errs {
+		fmt.Printf("  %s: %s\n", fieldError.Field, fieldError.Message)
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"appjet-cli/app/models"
+	"appjet-cli/app/services"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const webhooksUsage = `Usage:
+  ./appjet webhooks list
+  ./appjet webhooks create :name --url :url --events server.down,deploy.failed [--format json|slack|discord] [--secret :secret]
+  ./appjet webhooks delete :id
+  ./appjet webhooks test :id
+  ./appjet webhooks deliveries :id [--limit 50]`
+
+// HandleWebhooksCommand manages the outbound webhooks notified of the fleet events
+func HandleWebhooksCommand(arguments []string, config models.Configuration) {
+	arguments, webhookURL := extractFlagValue(arguments, "--url")
+	arguments, events := extractFlagValue(arguments, "--events")
+	arguments, format := extractFlagValue(arguments, "--format")
+	arguments, secret := extractFlagValue(arguments, "--secret")
+	arguments, limit := extractFlagValue(arguments, "--limit")
+
+	token, err := services.DecryptToken()
+	if err != nil {
+		fmt.Println("Error decrypting token:", err)
+		return
+	}
+
+	baseURL := config.IdentityProvider.ServerURL + "/appjet/webhooks"
+	switch {
+	case len(arguments) == 1 && arguments[0] == "list":
+		makeGETRequest(baseURL, token)
+	case len(arguments) == 2 && arguments[0] == "create" && webhookURL != "" && events != "":
+		makeJSONRequest(http.MethodPost, baseURL, token, map[string]interface{}{
+			"name":   arguments[1],
+			"url":    webhookURL,
+			"events": strings.Split(events, ","),
+			"format": format,
+			"secret": secret,
+		})
+	case len(arguments) == 2 && arguments[0] == "delete":
+		makeJSONRequest(http.MethodDelete, baseURL+"/"+url.PathEscape(arguments[1]), token, nil)
+	case len(arguments) == 2 && arguments[0] == "test":
+		makeJSONRequest(http.MethodPost, baseURL+"/"+url.PathEscape(arguments[1])+"/test", token, nil)
+	case len(arguments) == 2 && arguments[0] == "deliveries":
+		deliveriesURL := baseURL + "/" + url.PathEscape(arguments[1]) + "/deliveries"
+		if limit != "" {
+			deliveriesURL += "?limit=" + url.QueryEscape(limit)
+		}
+		makeGETRequest(deliveriesURL, token)
+	default:
+		fmt.Println(webhooksUsage)
+	}
+}
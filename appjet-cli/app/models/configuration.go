@@ -11,8 +11,10 @@ type Configuration struct {
 		Name    string `json:"name"`
 		Servers []struct {
 			Name          string `json:"name"`
-			IP            string `json:"ip"`
-			Port          int    `json:"port"`
+			Scheme        string `json:"scheme"`    // scheme of the daemon, when empty https once it is enrolled and http before (APPJET_DAEMON_TLS)
+			IP            string `json:"ip"`        // IPv4, IPv6 or host name of the daemon
+			Port          int    `json:"port"`      // port of the daemon, 8080 when empty
+			BasePath      string `json:"base-path"` // prefix of the daemon api, e.g. behind a reverse proxy
 			User          string `json:"user"`
 			Password      string `json:"password"`
 			DeployDetails struct {
@@ -52,6 +54,10 @@ type Configuration struct {
 			} `json:"ports"`
 		} `json:"database"`
 
+		// Services replace the application and database containers when set: every service is a
+		// container of the compose file, named after the service
+		Services []Service `json:"services"`
+
 		ExtraCommands struct {
 			LocalScriptFolderDir string `json:"local-script-folder-dir"`
 			Commands             struct {
@@ -83,3 +89,31 @@ type Configuration struct {
 		} `json:"code-checkout"`
 	} `json:"artifact"`
 }
+
+// Roles of a service with a meaning of their own, any other role is only a label
+const (
+	// ServiceRoleApplication services without an image or a build are built from the Dockerfile
+	// generated for the application section
+	ServiceRoleApplication = "application"
+	// ServiceRoleDatabase services are waited for by the application before it starts
+	ServiceRoleDatabase = "database"
+)
+
+// Service is one container of the compose file of the servers
+type Service struct {
+	Name      string            `json:"name"`
+	Role      string            `json:"role"`
+	Image     string            `json:"image"` // image to run, or
+	Build     string            `json:"build"` // directory with the Dockerfile to build
+	Ports     []ServicePort     `json:"ports"`
+	Env       map[string]string `json:"env"`
+	DependsOn []string          `json:"depends-on"`
+	Command   []string          `json:"command"`
+	Volumes   []string          `json:"volumes"`
+}
+
+// ServicePort maps a port of a service container to a port of the server
+type ServicePort struct {
+	InternalDocker int `json:"internal-docker"`
+	ExternalDocker int `json:"external-docker"` // 0 keeps the port private to the other services
+}
@@ -7,10 +7,15 @@ import (
 )
 
 func GetConfiguration() (models.Configuration, error) {
+	return ReadConfiguration("config.json")
+}
+
+// ReadConfiguration reads a configuration file, without validating it
+func ReadConfiguration(path string) (models.Configuration, error) {
 	var config models.Configuration
 
-	// Read the contents of config.json
-	configJSON, err := ioutil.ReadFile("config.json")
+	// Read the contents of the configuration file
+	configJSON, err := ioutil.ReadFile(path)
 	if err != nil {
 		return config, err
 	}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DefaultProject is the project of the commands when none is chosen, the routes without /projects/:project
+const DefaultProject = "default"
+
+// projectEnvironmentVariable chooses the project of the commands, unless --project is given
+const projectEnvironmentVariable = "APPJET_PROJECT"
+
+// projectFile keeps the project chosen with ./appjet projects use, next to the saved session
+const projectFile = ".appjet-project"
+
+// selectedProject is the --project flag of the command line
+var selectedProject string
+
+// SelectProject makes the commands of this run work on a project, whatever was chosen before
+func SelectProject(project string) {
+	selectedProject = project
+}
+
+// SelectedProject returns the project given with --project, "" without one
+func SelectedProject() string {
+	return selectedProject
+}
+
+// CurrentProject returns the project of the commands: --project, APPJET_PROJECT, the project chosen with
+// ./appjet projects use, or the default project
+func CurrentProject() string {
+	if selectedProject != "" {
+		return selectedProject
+	}
+	if project := os.Getenv(projectEnvironmentVariable); project != "" {
+		return project
+	}
+	if content, err := ioutil.ReadFile(projectFile); err == nil {
+		if project := strings.TrimSpace(string(content)); project != "" {
+			return project
+		}
+	}
+
+	return DefaultProject
+}
+
+// SaveProject remembers the project of the next commands, the default project forgets the choice
+func SaveProject(project string) error {
+	if project == DefaultProject {
+		if err := os.Remove(projectFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing %s: %w", projectFile, err)
+		}
+		return nil
+	}
+
+	if err := ioutil.WriteFile(projectFile, []byte(project+"\n"), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", projectFile, err)
+	}
+
+	return nil
+}
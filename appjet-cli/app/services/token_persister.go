@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"strings"
 )
 
@@ -40,8 +41,15 @@ func EncryptAndSaveToken(token string) error {
 	return nil
 }
 
-// DecryptToken decrypts the token from the encrypted file.
+// tokenEnvironmentVariable holds an API token for CI, used instead of the saved session token
+const tokenEnvironmentVariable = "APPJET_TOKEN"
+
+// DecryptToken decrypts the token from the encrypted file, unless APPJET_TOKEN is set.
 func DecryptToken() (string, error) {
+	if token := os.Getenv(tokenEnvironmentVariable); token != "" {
+		return token, nil
+	}
+
 	// Find the .security file in the current directory
 	files, err := ioutil.ReadDir(".")
 	if err != nil {
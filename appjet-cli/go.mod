@@ -2,11 +2,15 @@ module appjet-cli
 
 go 1.21
 
+require (
+	github.com/pkg/sftp v1.13.6
+	golang.org/x/crypto v0.18.0
+	golang.org/x/net v0.20.0
+)
+
 require (
 	github.com/howeyc/gopass v0.0.0-20210920133722-c8aef6fb66ef // indirect
 	github.com/kr/fs v0.1.0 // indirect
-	github.com/pkg/sftp v1.13.6 // indirect
-	golang.org/x/crypto v0.18.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/term v0.16.0 // indirect
 )
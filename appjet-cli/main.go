@@ -17,8 +17,8 @@ func main() {
 |  | |  ||  | --' |  | --' |  '-'  /|  ` + "`" + `---.   |  |    
 ` + "`" + `--' ` + "`" + `--'` + "`" + `--'     ` + "`" + `--'      ` + "`" + `-----' ` + "`" + `------'   ` + "`" + `--'  ` + "`")
 
-	// Retrieve command-line arguments
-	args := os.Args
+	// Retrieve command-line arguments, --project applies to any command
+	args := extractProjectFlag(os.Args)
 
 	if len(args) < 2 {
 		fmt.Println("No command specified.")
@@ -32,6 +32,10 @@ func main() {
 		"check-alive": handlers.HandleCheckAliveCommand, //ok
 		"configure":   handlers.HandleConfigureCommand,  //ok
 		"inspect":     handlers.HandleInspectCommand,    //ok
+		"templates":   handlers.HandleTemplatesCommand,
+		"status":      handlers.HandleStatusCommand,
+		"logs":        handlers.HandleLogsCommand,
+		"exec":        handlers.HandleExecCommand,
 		"start":       handlers.HandleStartCommand,
 		"restart":     handlers.HandleRestartCommand,
 		"stop":        handlers.HandleStopCommand,
@@ -39,6 +43,25 @@ func main() {
 		"scripts":     handlers.HandleScriptsCommand,
 		"code":        handlers.HandleCodeCommand,
 		"scp/run":     handlers.HandleSCPRunCommand,
+		"rollout":     handlers.HandleRolloutCommand,
+		"revisions":   handlers.HandleRevisionsCommand,
+		"revision":    handlers.HandleRevisionCommand,
+		"rollback":    handlers.HandleRollbackCommand,
+		"validate":    handlers.HandleValidateCommand,
+		"users":       handlers.HandleUsersCommand,
+		"password":    handlers.HandlePasswordCommand,
+		"sessions":    handlers.HandleSessionsCommand,
+		"refresh":     handlers.HandleRefreshCommand,
+		"tokens":      handlers.HandleTokensCommand,
+		"audit":       handlers.HandleAuditCommand,
+		"webhooks":    handlers.HandleWebhooksCommand,
+		"secrets":     handlers.HandleSecretsCommand,
+		"certs":       handlers.HandleCertsCommand,
+		"servers":     handlers.HandleServersCommand,
+		"join-tokens": handlers.HandleJoinTokensCommand,
+		"jobs":        handlers.HandleJobsCommand,
+		"job":         handlers.HandleJobCommand,
+		"projects":    handlers.HandleProjectsCommand,
 		"default":     handlers.HandleUnknownCommand,
 	}
 
@@ -53,3 +76,22 @@ func main() {
 	// Call the command handler
 	handler(args[2:], config)
 }
+
+// extractProjectFlag removes "--project :project" from the arguments and makes the command work on that
+// project. The arguments after "--" belong to the command run by exec and are left alone.
+func extractProjectFlag(args []string) []string {
+	remaining := make([]string, 0, len(args))
+	for index := 0; index < len(args); index++ {
+		if args[index] == "--" {
+			return append(remaining, args[index:]...)
+		}
+		if args[index] == "--project" && index+1 < len(args) {
+			services.SelectProject(args[index+1])
+			index++
+			continue
+		}
+		remaining = append(remaining, args[index])
+	}
+
+	return remaining
+}
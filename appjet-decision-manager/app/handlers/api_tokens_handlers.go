@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// apiTokenResponse is the public view of an API token, without its hash
+func apiTokenResponse(token *models.APIToken) gin.H {
+	return gin.H{
+		"id":           token.ID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       token.ScopeList(),
+		"created-at":   token.CreatedAt,
+		"expires-at":   token.ExpiresAt,
+		"last-used-at": token.LastUsedAt,
+	}
+}
+
+// ListAPITokensHandler lists the API tokens of the authenticated user
+func ListAPITokensHandler(c *gin.Context) {
+	tokens, err := services.ListAPITokens(currentUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load api tokens"})
+		return
+	}
+
+	response := make([]gin.H, len(tokens))
+	for index := range tokens {
+		response[index] = apiTokenResponse(&tokens[index])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": response})
+}
+
+// CreateAPITokenHandler mints an API token from {"name", "scopes", "expires"}. The secret is only
+// part of this response.
+func CreateAPITokenHandler(c *gin.Context) {
+	var request services.APITokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, secret, err := services.CreateAPIToken(currentUser(c).ID, request)
+	if errors.Is(err, services.ErrInvalidAPITokenRequest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":  apiTokenResponse(token),
+		"secret": secret,
+		"note":   "Store the secret now, it cannot be shown again",
+	})
+}
+
+// RevokeAPITokenHandler deletes an API token. Users can revoke their own tokens, admins any token.
+func RevokeAPITokenHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid api token"})
+		return
+	}
+
+	token, err := services.GetAPIToken(uint(id))
+	if errors.Is(err, services.ErrAPITokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if token.UserID != user.ID && !models.HasPermission(user.Role, models.PermissionUsersManage) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Forbidden: role '" + user.Role + "' is missing the '" + models.PermissionUsersManage + "' permission",
+			"permission": models.PermissionUsersManage,
+			"role":       user.Role,
+		})
+		return
+	}
+
+	if err := services.RevokeAPIToken(token.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking the api token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// requestIDHeader carries the request id, taken from the client when present and echoed in the response
+const requestIDHeader = "X-Request-ID"
+
+// maxAuditLimit bounds the number of events returned as JSON, exports are not bounded
+const maxAuditLimit = 1000
+
+// AuditMiddlewareHandler records an audit event for every authenticated request to a protected route,
+// once the request is handled. It must run before AuthMiddlewareHandler.
+func AuditMiddlewareHandler(c *gin.Context) {
+	start := time.Now()
+
+	requestID := c.GetHeader(requestIDHeader)
+	if requestID == "" || len(requestID) > 64 {
+		requestID = uuid.New().String()
+	}
+	c.Set("request-id", requestID)
+	c.Header(requestIDHeader, requestID)
+
+	c.Next()
+
+	// requests without valid credentials cannot be attributed to anyone
+	value, ok := c.Get("user")
+	if !ok {
+		return
+	}
+	user := value.(*User)
+
+	event := models.AuditEvent{
+		RequestID:  requestID,
+		Username:   user.Username,
+		Role:       user.Role,
+		Method:     c.Request.Method,
+		Route:      c.FullPath(),
+		Path:       c.Request.URL.Path,
+		Project:    c.GetString("project"),
+		Action:     routeScope(c.FullPath()),
+		Cluster:    c.Param("cluster"),
+		Server:     c.Param("server"),
+		Container:  c.Param("container"),
+		StatusCode: c.Writer.Status(),
+		DurationMs: time.Since(start).Milliseconds(),
+		ClientIP:   c.ClientIP(),
+		Detail:     c.GetString("audit-detail"),
+	}
+	if value, ok := c.Get("api-token"); ok {
+		event.APIToken = value.(*models.APIToken).Name
+	}
+
+	switch status := c.Writer.Status(); {
+	case c.GetString("audit-job") != "":
+		event.JobID = c.GetString("audit-job")
+		event.Outcome = models.AuditOutcomePending
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		event.Outcome = models.AuditOutcomeDenied
+	case status >= http.StatusBadRequest:
+		event.Outcome = models.OutcomeFailed
+	default:
+		event.Outcome = models.OutcomeSuccess
+	}
+	if value, ok := c.Get("audit-response"); ok {
+		response := value.(models.FanOutResponse)
+		event.Outcome = response.Outcome
+		event.Results = services.NewAuditServerResults(response)
+	}
+
+	if err := services.RecordAuditEvent(&event); err != nil {
+		log.Printf("Error recording the audit event of request %s: %s", requestID, err)
+	}
+}
+
+// auditResults adds the per-server results of a fan-out command to the audit event of the request
+func auditResults(c *gin.Context, response models.FanOutResponse) {
+	c.Set("audit-response", response)
+}
+
+// auditJob links the audit event of the request to a job, the event is completed when the job finishes
+func auditJob(c *gin.Context, jobID string) {
+	c.Set("audit-job", jobID)
+}
+
+// auditDetail adds what the request did, beyond its route and parameters, to its audit event
+func auditDetail(c *gin.Context, detail string) {
+	c.Set("audit-detail", detail)
+}
+
+// ListAuditHandler returns the audit events matching the since, until, user, project, action, cluster,
+// server and outcome filters. With ?format=jsonl every matching event is exported as one JSON object per line.
+func ListAuditHandler(c *gin.Context) {
+	query := services.AuditQuery{
+		User:    c.Query("user"),
+		Project: c.Query("project"),
+		Action:  c.Query("action"),
+		Cluster: c.Query("cluster"),
+		Server:  c.Query("server"),
+		Outcome: c.Query("outcome"),
+	}
+
+	var err error
+	if since := c.Query("since"); since != "" {
+		if query.Since, err = services.ParseAuditTime(since); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + err.Error()})
+			return
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if query.Until, err = services.ParseAuditTime(until); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + err.Error()})
+			return
+		}
+	}
+
+	export := c.Query("format") == "jsonl"
+	defaultLimit := "100"
+	if export {
+		defaultLimit = "0"
+	}
+	query.Limit, err = strconv.Atoi(c.DefaultQuery("limit", defaultLimit))
+	if err != nil || query.Limit < 0 || (!export && (query.Limit == 0 || query.Limit > maxAuditLimit)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	if export {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		err := services.ExportAuditEvents(query, func(event *models.AuditEvent) error {
+			return encoder.Encode(event)
+		})
+		if err != nil {
+			log.Printf("Error exporting the audit log: %s", err)
+		}
+		return
+	}
+
+	events, err := services.ListAuditEvents(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
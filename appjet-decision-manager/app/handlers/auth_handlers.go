@@ -3,28 +3,31 @@ package handlers
 import (
 	"appjet-decision-manager/app/models" // Import your models package
 	"appjet-decision-manager/app/services"
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"net/http"
+	"strings"
 )
 
 // Assuming you have a User model defined in the "models" package
 type User = models.User
 
+// LogoutHandler ends the session whose token is in the Authorization header
 func LogoutHandler(c *gin.Context) {
-	// Extract the token from the URL parameter
-	token := c.Param("token")
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
 
-	// Check if the token exists in the database
-	if !validateToken(token) {
+	// Check if the token exists in the database, an expired session is already gone
+	if _, _, err := services.ValidateSession(token); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 		return
 	}
 
 	// Delete the user session with the provided token from the database
-	err := services.GetDBConnection().Unscoped().Where("token = ?", token).Delete(&models.UserSession{}).Error
-	if err != nil {
+	if err := services.DeleteSession(token); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting user session"})
 		return
 	}
@@ -34,93 +37,94 @@ func LogoutHandler(c *gin.Context) {
 
 func LoginHandler(c *gin.Context) {
 	// Get username and password from the request
-
-	_, err := services.CreateDbConnection()
-	if err != nil {
-		return
-	}
-
 	username := c.PostForm("username")
 	password := c.PostForm("password")
 
 	// Authenticate the user
-	user, err := authenticateUser(username, password)
+	user, err := services.AuthenticateUser(username, password)
 	if err != nil {
 		// Authentication failed
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	// Assuming the user is authenticated successfully, generate a UUID token
-	token := generateToken()
-
-	persistedToken, err := persistToken(user.ID, token)
+	// Start a session for the authenticated user
+	session, err := services.CreateSession(user.ID, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Error persisting user session token."})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": persistedToken})
+	c.JSON(http.StatusOK, gin.H{"token": session.Token, "expires-at": session.ExpiresAt, "must-change-password": user.MustChangePassword})
 }
 
-func authenticateUser(username string, password string) (*User, error) {
-	var user User
-	print(password)
-	// Query the database to find the user with the provided username and password
-	result := services.GetDBConnection().Unscoped().Where("username = ? AND password = ?", username, password).First(&user)
-
-	if result.Error != nil {
-
-		return nil, result.Error
-	}
-
-	return &user, nil
-}
-
-func generateToken() string {
-	// Generate a new UUID (Version 4)
-	uuidToken := uuid.New().String()
-	return uuidToken
-}
-
-func validateToken(token string) bool {
-	var userSession models.UserSession
-
-	// Query the database to find a user session with the provided token
-	result := services.GetDBConnection().Unscoped().Where("token = ?", token).First(&userSession)
+// changePasswordRoute is the only route open to users that must change their password
+const changePasswordRoute = "/appjet/password"
 
-	// Check if the token exists in the database
-	return result.RowsAffected > 0
-}
-
-// Example usage in your middleware or handler
+// AuthMiddlewareHandler rejects requests without a valid session or API token and stores the user and
+// the session (or the API token) in the context
 func AuthMiddlewareHandler(c *gin.Context) {
-	token := c.GetHeader("Authorization")
-	if token == "" || !validateToken(token) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		c.Abort()
 		return
 	}
 
-	c.Next()
-}
+	// CI pipelines authenticate with API tokens, limited to their scopes by AuthorizationMiddlewareHandler
+	if services.IsAPIToken(token) {
+		apiToken, user, err := services.ValidateAPIToken(token)
+		if errors.Is(err, services.ErrAPITokenExpired) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API token expired"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+		if user.MustChangePassword {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password change required, use PUT " + changePasswordRoute + " (./appjet password)"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("api-token", apiToken)
+		c.Next()
+		return
+	}
 
-func persistToken(userID uint, token string) (string, error) {
+	session, user, err := services.ValidateSession(token)
+	if errors.Is(err, services.ErrSessionExpired) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired, please login again"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
 
-	// Create a new UserSession instance
-	userSession := models.UserSession{
-		UserID: userID,
-		Token:  token,
+	// until the password is changed, the session can only be used to change it
+	if user.MustChangePassword && c.FullPath() != changePasswordRoute {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password change required, use PUT " + changePasswordRoute + " (./appjet password)"})
+		c.Abort()
+		return
 	}
 
-	// Insert the UserSession into the database
-	result := services.GetDBConnection().Unscoped().Create(&userSession)
+	c.Set("user", user)
+	c.Set("session", session)
+	c.Next()
+}
 
-	if result.Error != nil {
-		// Error occurred during insert
-		return "", fmt.Errorf("error inserting token into the database: %w", result.Error)
+// currentUsername returns the authenticated username, or "anonymous" on open endpoints
+func currentUsername(c *gin.Context) string {
+	if user, ok := c.Get("user"); ok {
+		return user.(*User).Username
 	}
 
-	// Token successfully inserted
-	return token, nil
+	return "anonymous"
 }
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// routePermissions is the permission matrix of the protected routes, keyed by "METHOD /route/:param".
+// Routes missing from the matrix are denied to everyone.
+var routePermissions = map[string]string{
+	"PUT /appjet/password":                         models.PermissionAccountSelf,
+	"GET /appjet/sessions":                         models.PermissionAccountSelf,
+	"POST /appjet/sessions/refresh":                models.PermissionAccountSelf,
+	"DELETE /appjet/sessions/:id":                  models.PermissionAccountSelf,
+	"GET /appjet/users/:username/sessions":         models.PermissionUsersManage,
+	"GET /appjet/servers":                          models.PermissionStatusRead,
+	"DELETE /appjet/servers/:cluster/:server":      models.PermissionServersManage,
+	"GET /appjet/join-tokens":                      models.PermissionServersManage,
+	"POST /appjet/join-tokens":                     models.PermissionServersManage,
+	"DELETE /appjet/join-tokens/:id":               models.PermissionServersManage,
+	"GET /appjet/certificates":                     models.PermissionServersManage,
+	"POST /appjet/certificates/:cluster/:server":   models.PermissionServersManage,
+	"DELETE /appjet/certificates/:cluster/:server": models.PermissionServersManage,
+	"GET /appjet/secrets":                          models.PermissionServersManage,
+	"PUT /appjet/secrets/:cluster/:server":         models.PermissionServersManage,
+	"DELETE /appjet/secrets/:cluster/:server":      models.PermissionServersManage,
+	"GET /appjet/audit":                            models.PermissionAuditRead,
+	"GET /appjet/webhooks":                         models.PermissionWebhooksManage,
+	"POST /appjet/webhooks":                        models.PermissionWebhooksManage,
+	"DELETE /appjet/webhooks/:id":                  models.PermissionWebhooksManage,
+	"POST /appjet/webhooks/:id/test":               models.PermissionWebhooksManage,
+	"GET /appjet/webhooks/:id/deliveries":          models.PermissionWebhooksManage,
+	"GET /appjet/tokens":                           models.PermissionAccountSelf,
+	"POST /appjet/tokens":                          models.PermissionAccountSelf,
+	"DELETE /appjet/tokens/:id":                    models.PermissionAccountSelf,
+
+	"GET /appjet/projects":             models.PermissionStatusRead,
+	"POST /appjet/projects":            models.PermissionProjectsManage,
+	"DELETE /appjet/projects/:project": models.PermissionProjectsManage,
+
+	"GET /appjet/users":                    models.PermissionUsersManage,
+	"POST /appjet/users":                   models.PermissionUsersManage,
+	"DELETE /appjet/users/:username":       models.PermissionUsersManage,
+	"PUT /appjet/users/:username/role":     models.PermissionUsersManage,
+	"PUT /appjet/users/:username/password": models.PermissionUsersManage,
+
+	"GET /appjet/jobs":          models.PermissionConfigRead,
+	"GET /appjet/jobs/:id":      models.PermissionConfigRead,
+	"GET /appjet/config":        models.PermissionConfigRead,
+	"GET /appjet/revisions":     models.PermissionConfigRead,
+	"GET /appjet/revisions/:id": models.PermissionConfigRead,
+
+	"GET /appjet/check-alive":                  models.PermissionStatusRead,
+	"GET /appjet/check-alive/:cluster":         models.PermissionStatusRead,
+	"GET /appjet/check-alive/:cluster/:server": models.PermissionStatusRead,
+	"GET /appjet/status":                       models.PermissionStatusRead,
+	"GET /appjet/status/:cluster":              models.PermissionStatusRead,
+	"GET /appjet/status/:cluster/:server":      models.PermissionStatusRead,
+	"GET /appjet/inspect":                      models.PermissionStatusRead,
+	"GET /appjet/inspect/:cluster":             models.PermissionStatusRead,
+	"GET /appjet/inspect/:cluster/:server":     models.PermissionStatusRead,
+
+	"GET /appjet/templates":                  models.PermissionConfigRead,
+	"GET /appjet/templates/:cluster":         models.PermissionConfigRead,
+	"GET /appjet/templates/:cluster/:server": models.PermissionConfigRead,
+
+	"GET /appjet/logs/:cluster":                    models.PermissionLogsRead,
+	"GET /appjet/logs/:cluster/:server/:container": models.PermissionLogsRead,
+
+	"GET /appjet/start":                               models.PermissionContainersControl,
+	"GET /appjet/start/:cluster":                      models.PermissionContainersControl,
+	"GET /appjet/start/:cluster/:server":              models.PermissionContainersControl,
+	"GET /appjet/start/:cluster/:server/:container":   models.PermissionContainersControl,
+	"GET /appjet/restart":                             models.PermissionContainersControl,
+	"GET /appjet/restart/:cluster":                    models.PermissionContainersControl,
+	"GET /appjet/restart/:cluster/:server":            models.PermissionContainersControl,
+	"GET /appjet/restart/:cluster/:server/:container": models.PermissionContainersControl,
+	"GET /appjet/stop":                                models.PermissionContainersControl,
+	"GET /appjet/stop/:cluster":                       models.PermissionContainersControl,
+	"GET /appjet/stop/:cluster/:server":               models.PermissionContainersControl,
+	"GET /appjet/stop/:cluster/:server/:container":    models.PermissionContainersControl,
+
+	"POST /appjet/exec/:cluster/:server/:container":    models.PermissionContainersExec,
+	"GET /appjet/exec/:cluster/:server/:container/tty": models.PermissionContainersExec,
+
+	"GET /appjet/clean":                  models.PermissionInfrastructureClean,
+	"GET /appjet/clean/:cluster":         models.PermissionInfrastructureClean,
+	"GET /appjet/clean/:cluster/:server": models.PermissionInfrastructureClean,
+
+	"PUT /appjet/config":                      models.PermissionConfigWrite,
+	"PATCH /appjet/config/:cluster":           models.PermissionConfigWrite,
+	"PATCH /appjet/config/:cluster/:server":   models.PermissionConfigWrite,
+	"POST /appjet/configure":                  models.PermissionConfigWrite,
+	"POST /appjet/configure/:cluster":         models.PermissionConfigWrite,
+	"POST /appjet/configure/:cluster/:server": models.PermissionConfigWrite,
+	"POST /appjet/rollout/:cluster":           models.PermissionConfigWrite,
+	"POST /appjet/rollback":                   models.PermissionConfigWrite,
+	"POST /appjet/rollback/:cluster":          models.PermissionConfigWrite,
+	"POST /appjet/rollback/:cluster/:server":  models.PermissionConfigWrite,
+
+	"POST /appjet/scripts":                         models.PermissionScriptsWrite,
+	"POST /appjet/scripts/:cluster":                models.PermissionScriptsWrite,
+	"POST /appjet/scripts/:cluster/:server":        models.PermissionScriptsWrite,
+	"POST /appjet/code":                            models.PermissionScriptsWrite,
+	"POST /appjet/code/:cluster":                   models.PermissionScriptsWrite,
+	"POST /appjet/code/:cluster/:server":           models.PermissionScriptsWrite,
+	"GET /appjet/scp/run/:script":                  models.PermissionScriptsWrite,
+	"GET /appjet/scp/run/:script/:cluster":         models.PermissionScriptsWrite,
+	"GET /appjet/scp/run/:script/:cluster/:server": models.PermissionScriptsWrite,
+}
+
+// projectRoutePrefix is where the project routes are registered again for every project
+const projectRoutePrefix = "/projects/:project"
+
+// matrixRoute returns the route a full path has in the permission matrix: the project routes of every
+// project share the entry of the default project (e.g. "/appjet/projects/:project/start" is "/appjet/start")
+func matrixRoute(fullPath string) string {
+	prefix := "/appjet" + projectRoutePrefix + "/"
+	if strings.HasPrefix(fullPath, prefix) {
+		return "/appjet/" + strings.TrimPrefix(fullPath, prefix)
+	}
+
+	return fullPath
+}
+
+// routeScope returns the API token scope of a route, the first segment after /appjet and the project
+// (e.g. "/appjet/start/:cluster" and "/appjet/projects/:project/start/:cluster" are "start")
+func routeScope(fullPath string) string {
+	return strings.SplitN(strings.TrimPrefix(matrixRoute(fullPath), "/appjet/"), "/", 2)[0]
+}
+
+// AuthorizationMiddlewareHandler checks the permission matrix for the authenticated user.
+// It must run after AuthMiddlewareHandler.
+func AuthorizationMiddlewareHandler(c *gin.Context) {
+	permission, ok := routePermissions[c.Request.Method+" "+matrixRoute(c.FullPath())]
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: no permission is defined for this route"})
+		c.Abort()
+		return
+	}
+
+	value, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+
+	// an API token is limited to its scopes, on top of the role of its owner
+	if value, ok := c.Get("api-token"); ok {
+		apiToken := value.(*models.APIToken)
+		scope := routeScope(c.FullPath())
+		if !apiToken.AllowsScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Forbidden: API token '" + apiToken.Name + "' is missing the '" + scope + "' scope",
+				"scope": scope,
+			})
+			c.Abort()
+			return
+		}
+	}
+
+	user := value.(*User)
+	if !models.HasPermission(user.Role, permission) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Forbidden: role '" + user.Role + "' is missing the '" + permission + "' permission",
+			"permission": permission,
+			"role":       user.Role,
+		})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
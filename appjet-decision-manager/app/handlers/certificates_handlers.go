@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ListDaemonCertificatesHandler lists the pinned daemon certificates
+func ListDaemonCertificatesHandler(c *gin.Context) {
+	certificates, err := services.ListDaemonCertificates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load daemon certificates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"certificates": certificates})
+}
+
+// EnrollDaemonHandler signs the CSR of a daemon from {"csr"}, as created by
+// "appjet-server-daemon tls request", pins the certificate and returns it with the CA certificate
+func EnrollDaemonHandler(c *gin.Context) {
+	var request struct {
+		CSR string `json:"csr" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrolled, err := services.EnrollDaemon(c.Param("cluster"), c.Param("server"), request.CSR)
+	switch {
+	case errors.Is(err, services.ErrServerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found in the configuration"})
+	case errors.Is(err, services.ErrInvalidCSR):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, enrolled)
+	}
+}
+
+// DeleteDaemonCertificateHandler unpins the certificate of a server
+func DeleteDaemonCertificateHandler(c *gin.Context) {
+	err := services.DeleteDaemonCertificate(c.Param("cluster"), c.Param("server"))
+	if errors.Is(err, services.ErrDaemonCertificateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Daemon certificate not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting the daemon certificate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Daemon certificate deleted"})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+)
+
+// storedConfig returns the configuration in use by the project of the request, or answers 409 and returns
+// nil when none was stored yet.
+func storedConfig(c *gin.Context) *models.Configuration {
+	config, err := services.CurrentConfig(currentProject(c))
+	if err != nil {
+		writeConfigError(c, err)
+		return nil
+	}
+
+	return config
+}
+
+// noConfigError tells how to store the first configuration of the project of the request
+func noConfigError(c *gin.Context) string {
+	configPath := "/appjet/config"
+	if project := c.Param("project"); project != "" {
+		configPath = "/appjet/projects/" + project + "/config"
+	}
+
+	return "No configuration stored yet, run configure or PUT " + configPath + " first"
+}
+
+// writeConfigError maps config store errors to HTTP answers.
+func writeConfigError(c *gin.Context, err error) {
+	var validationErrs models.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid configuration", "errors": validationErrs})
+	case errors.Is(err, services.ErrNoConfig):
+		c.JSON(http.StatusConflict, gin.H{"error": noConfigError(c)})
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project " + currentProject(c) + " not found"})
+	case errors.Is(err, services.ErrConfigNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func GetConfigHandler(c *gin.Context) {
+	config := storedConfig(c)
+	if config == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// PutConfigHandler replaces the whole configuration. It is validated, recorded as a revision and
+// used by the next commands, nothing is pushed to the daemons until configure is called.
+func PutConfigHandler(c *gin.Context) {
+	var config models.Configuration
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revision, err := services.ReplaceConfig(currentProject(c), &config, currentUsername(c), "", "", "config update")
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revision": revision, "config": &config})
+}
+
+// PatchClusterConfigHandler merges the JSON body into one cluster of the configuration.
+func PatchClusterConfigHandler(c *gin.Context) {
+	patch, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil || len(patch) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing patch body"})
+		return
+	}
+
+	revision, err := services.PatchClusterConfig(currentProject(c), c.Param("cluster"), patch, currentUsername(c))
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	config, _ := services.CurrentConfig(currentProject(c))
+	c.JSON(http.StatusOK, gin.H{"revision": revision, "config": config})
+}
+
+// PatchServerConfigHandler merges the JSON body into one server of a cluster.
+func PatchServerConfigHandler(c *gin.Context) {
+	patch, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil || len(patch) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing patch body"})
+		return
+	}
+
+	revision, err := services.PatchServerConfig(currentProject(c), c.Param("cluster"), c.Param("server"), patch, currentUsername(c))
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	config, _ := services.CurrentConfig(currentProject(c))
+	c.JSON(http.StatusOK, gin.H{"revision": revision, "config": config})
+}
@@ -1,121 +1,53 @@
 package handlers
 
 import (
+	"appjet-decision-manager/app/models"
+	"context"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
 )
 import services "appjet-decision-manager/app/services"
 
-func ConfigureAllClustersAllServersHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		clusterResponses := make([]map[string]interface{}, 0)
-
-		for sIndex := range config.Clusters[cIndex].Servers {
-			serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-			daemonResponse, _ := services.ForwardConfigToDaemon(config, "http://"+serverIP+":8080/api/configure")
-
-			// Build response structure
-			serverResponse := map[string]interface{}{
-				config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-			}
-
-			clusterResponses = append(clusterResponses, serverResponse)
-		}
-
-		// Build response structure for the cluster
-		clusterResponse := map[string]interface{}{
-			config.Clusters[cIndex].Name: clusterResponses,
-		}
-
-		daemonResponses = append(daemonResponses, clusterResponse)
+func configureCall(config *models.Configuration) services.DaemonCall {
+	return func(ctx context.Context, target services.DaemonTarget) (*http.Response, []byte, error) {
+		return services.ForwardConfigToDaemon(ctx, config, target.ConfigureURL())
 	}
-
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
-	}
-
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
 }
 
-func ConfigureSpecificClusterAllServersHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-	cluster := c.Param("cluster")
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		if config.Clusters[cIndex].Name == cluster {
-			clusterResponses := make([]map[string]interface{}, 0)
-
-			for sIndex := range config.Clusters[cIndex].Servers {
-				serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-				daemonResponse, _ := services.ForwardConfigToDaemon(config, "http://"+serverIP+":8080/api/configure")
-
-				// Build response structure
-				serverResponse := map[string]interface{}{
-					config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-				}
-
-				clusterResponses = append(clusterResponses, serverResponse)
-			}
-
-			// Build response structure for the cluster
-			clusterResponse := map[string]interface{}{
-				config.Clusters[cIndex].Name: clusterResponses,
-			}
+// configure pushes a configuration to the selected servers. With a JSON body the body becomes the new
+// configuration: it is validated, recorded as a revision and reloaded before being pushed. Without a
+// body the stored configuration is re-applied as is.
+func configure(c *gin.Context, cluster string, server string) {
+	if c.Request.ContentLength == 0 {
+		config := storedConfig(c)
+		dispatchAsJob(c, "configure", "stored", config, cluster, server, configureCall(config))
+		return
+	}
 
-			daemonResponses = append(daemonResponses, clusterResponse)
-		}
+	var config models.Configuration
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
+	revision, err := services.ReplaceConfig(currentProject(c), &config, currentUsername(c), cluster, server, "configure")
+	if err != nil {
+		writeConfigError(c, err)
+		return
 	}
 
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+	dispatchAsJob(c, "configure", "revision="+strconv.FormatUint(uint64(revision.ID), 10), &config, cluster, server, configureCall(&config))
 }
 
-func ConfigureSpecificClusterSpecificServerHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-	cluster := c.Param("cluster")
-	server := c.Param("server")
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		if config.Clusters[cIndex].Name == cluster {
-			for sIndex := range config.Clusters[cIndex].Servers {
-				if config.Clusters[cIndex].Servers[sIndex].Name == server {
-					serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-					daemonResponse, _ := services.ForwardConfigToDaemon(config, "http://"+serverIP+":8080/api/configure")
-
-					// Build response structure for the server
-					serverResponse := map[string]interface{}{
-						config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-					}
-
-					daemonResponses = append(daemonResponses, serverResponse)
-				}
-			}
-		}
-	}
+func ConfigureAllClustersAllServersHandler(c *gin.Context) {
+	configure(c, "", "")
+}
 
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
-	}
+func ConfigureSpecificClusterAllServersHandler(c *gin.Context) {
+	configure(c, c.Param("cluster"), "")
+}
 
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+func ConfigureSpecificClusterSpecificServerHandler(c *gin.Context) {
+	configure(c, c.Param("cluster"), c.Param("server"))
 }
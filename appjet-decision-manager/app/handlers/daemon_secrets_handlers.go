@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ListDaemonSecretsHandler lists the servers with a registered secret, without the secrets
+func ListDaemonSecretsHandler(c *gin.Context) {
+	secrets, err := services.ListDaemonSecrets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load daemon secrets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"secrets": secrets})
+}
+
+// SetDaemonSecretHandler registers the secret of a server from {"secret"}, as printed by
+// "appjet-server-daemon secret generate" or "appjet-server-daemon secret rotate"
+func SetDaemonSecretHandler(c *gin.Context) {
+	var request struct {
+		Secret string `json:"secret" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.SetDaemonSecret(c.Param("cluster"), c.Param("server"), request.Secret)
+	if errors.Is(err, services.ErrInvalidDaemonSecret) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving the daemon secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Daemon secret saved"})
+}
+
+// DeleteDaemonSecretHandler removes the secret of a server
+func DeleteDaemonSecretHandler(c *gin.Context) {
+	err := services.DeleteDaemonSecret(c.Param("cluster"), c.Param("server"))
+	if errors.Is(err, services.ErrDaemonSecretNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Daemon secret not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting the daemon secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Daemon secret deleted"})
+}
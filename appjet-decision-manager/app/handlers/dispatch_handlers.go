@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// dispatchToDaemons forwards a command to every server selected by cluster/server (empty means all)
+// concurrently and writes one result per server, ordered by cluster and server.
+func dispatchToDaemons(c *gin.Context, config *models.Configuration, cluster string, server string, call services.DaemonCall) {
+	if config == nil {
+		return
+	}
+
+	targets := services.ResolveTargets(currentProject(c), config, cluster, server)
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers match the requested cluster/server"})
+		return
+	}
+
+	response := services.NewFanOutResponse(services.Dispatch(c.Request.Context(), targets, call))
+	auditResults(c, response)
+
+	c.JSON(services.FanOutStatusCode(response.Outcome), response)
+}
+
+// dispatchAsJob starts a job that forwards a mutating command to every server selected by
+// cluster/server and answers right away with the job, whose progress is read from /jobs/:id.
+func dispatchAsJob(c *gin.Context, command string, argument string, config *models.Configuration, cluster string, server string, call services.DaemonCall) {
+	if config == nil {
+		return
+	}
+
+	targets := services.ResolveTargets(currentProject(c), config, cluster, server)
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers match the requested cluster/server"})
+		return
+	}
+
+	job, err := services.StartJob(services.JobRequest{
+		Project:   currentProject(c),
+		Command:   command,
+		Argument:  argument,
+		Cluster:   cluster,
+		Server:    server,
+		CreatedBy: currentUsername(c),
+	}, targets, call)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditJob(c, job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
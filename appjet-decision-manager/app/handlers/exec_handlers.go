@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func execCall(container string, request services.ExecRequest) services.DaemonCall {
+	return func(ctx context.Context, target services.DaemonTarget) (*http.Response, []byte, error) {
+		return services.ForwardExecToDaemon(ctx, request, target.URL("/api/exec/"+url.PathEscape(container)))
+	}
+}
+
+// execDetail describes a command run in a container for the audit log, with its exit code once known
+func execDetail(mode string, command []string, exitCode *int) string {
+	commandJSON, _ := json.Marshal(command)
+	detail := mode + " " + string(commandJSON)
+	if exitCode != nil {
+		detail += " exit-code " + strconv.Itoa(*exitCode)
+	}
+
+	return detail
+}
+
+// ExecHandler runs a one-shot command in a container of a server and returns its stdout, stderr and
+// exit code, from a {"command": ["ls", "-la"], "timeout": "30s"} body
+func ExecHandler(c *gin.Context) {
+	config := storedConfig(c)
+	if config == nil {
+		return
+	}
+
+	var request services.ExecRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exec request: " + err.Error()})
+		return
+	}
+	if err := services.ValidateExecRequest(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	auditDetail(c, execDetail("exec", request.Command, nil))
+
+	targets := services.ResolveTargets(currentProject(c), config, c.Param("cluster"), c.Param("server"))
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers match the requested cluster/server"})
+		return
+	}
+
+	response := services.NewFanOutResponse(services.Dispatch(c.Request.Context(), targets, execCall(c.Param("container"), request)))
+	auditResults(c, response)
+	if result, ok := response.Results[0].Body.(map[string]interface{}); ok {
+		if exitCode, ok := result["exit-code"].(float64); ok {
+			code := int(exitCode)
+			auditDetail(c, execDetail("exec", request.Command, &code))
+		}
+	}
+
+	c.JSON(services.FanOutStatusCode(response.Outcome), response)
+}
+
+// ExecTTYHandler runs an interactive command in a container of a server and relays its TTY over a
+// websocket. The command comes from ?command= (repeated for every argument), the initial size from
+// ?rows= and ?cols=. Binary frames carry the TTY, text frames carry JSON control messages: "resize"
+// with rows and cols from the client, "exit" with the exit-code or "error" from the daemon.
+func ExecTTYHandler(c *gin.Context) {
+	config := storedConfig(c)
+	if config == nil {
+		return
+	}
+
+	command := c.QueryArray("command")
+	if len(command) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The command is required, pass it as ?command="})
+		return
+	}
+	rows, _ := strconv.ParseUint(c.Query("rows"), 10, 16)
+	cols, _ := strconv.ParseUint(c.Query("cols"), 10, 16)
+	auditDetail(c, execDetail("tty", command, nil))
+
+	targets := services.ResolveTargets(currentProject(c), config, c.Param("cluster"), c.Param("server"))
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers match the requested cluster/server"})
+		return
+	}
+
+	// the daemon session is opened before the upgrade, so its errors are still plain HTTP errors
+	daemon, err := services.DialDaemonExec(c.Request.Context(), targets[0], c.Param("container"), command, uint(rows), uint(cols))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to open the session on server " + targets[0].Server + ": " + err.Error()})
+		return
+	}
+	defer daemon.Close()
+
+	exitCode := -1
+	// clients are not browsers, they authenticate with the Authorization header, so the origin is not checked
+	server := websocket.Server{Handler: func(client *websocket.Conn) {
+		exitCode = services.ProxyExecSession(client, daemon)
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+
+	if exitCode >= 0 {
+		auditDetail(c, execDetail("tty", command, &exitCode))
+	}
+}
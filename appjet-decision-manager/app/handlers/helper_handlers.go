@@ -9,15 +9,46 @@ func HelpHandler(c *gin.Context) {
 	// Map to store endpoint descriptions
 	endpointDescriptions := map[string]map[string]string{
 		"no-auth-commands": {
-			"./appjet login": "For user authentication.",
-			"./appjet help":  "To see available appjet commands.",
+			"./appjet login":            "For user authentication.",
+			"./appjet help":             "To see available appjet commands.",
+			"./appjet validate [:file]": "Validate config.json (or the given file) locally and list every invalid field",
 		},
 		"needed-auth-commands": {
+			"./appjet password":   "Change your password (required after the first login with a temporary password)",
+			"./appjet users list": "List users (admin)",
+			"./appjet users create :username :role [--name \"Full Name\"]": "Create a user with a temporary password, role is admin, operator or viewer (admin)",
+			"./appjet users delete :username":                              "Delete a user and its sessions (admin)",
+			"./appjet users set-role :username :role":                      "Change the role of a user (admin)",
+			"./appjet users reset-password :username":                      "Set a temporary password, to be changed on the next login (admin)",
+
+			"./appjet sessions":                  "List your active sessions",
+			"./appjet sessions --user :username": "List the active sessions of a user (admin)",
+			"./appjet sessions revoke :id":       "Revoke one of your sessions, or any session (admin)",
+			"./appjet refresh":                   "Replace your session token by a new one and reset its idle expiry, the session still ends at the absolute expiry of the login",
+
+			"./appjet tokens list": "List your API tokens",
+			"./appjet tokens create [:name] --scope start,check-alive [--expires 90d]": "Create a scoped API token for CI, the secret is shown once (use it as APPJET_TOKEN)",
+			"./appjet tokens revoke :id": "Revoke one of your API tokens, or any token (admin)",
+
 			"./appjet logout":                       "To cancel user authentication.",
 			"./appjet check-alive":                  "Check if all containers are alive in all servers in all clusters",
 			"./appjet check-alive :cluster":         "Check if all containers are alive in all servers in specific cluster",
 			"./appjet check-alive :cluster :server": "Check if all containers are alive in specific server in specific cluster",
 
+			"./appjet status [:cluster [:server]] [--window 24h]": "Show the health recorded by the monitor: state, recent transitions and uptime over the window",
+
+			"./appjet logs :cluster :server :container [-f] [--tail N] [--since 10m]": "Show the logs of a container, -f follows them",
+			"./appjet logs :cluster :container [-f] [--tail N] [--since 10m]":         "Show the logs of a container on every server of a cluster, each line prefixed with its server",
+
+			"./appjet exec :cluster :server :container [--timeout 30s] -- :command": "Run a command in a container and exit with its exit code (admin)",
+			"./appjet exec :cluster :server :container -it -- :command":             "Run an interactive command in a container, attached to a TTY (admin)",
+
+			"./appjet webhooks list": "List the outbound webhooks (admin)",
+			"./appjet webhooks create :name --url :url --events server.down,deploy.failed [--format json|slack|discord] [--secret :secret]": "Create a webhook, the signing secret is shown once (admin)",
+			"./appjet webhooks delete :id":                  "Delete a webhook with its delivery log (admin)",
+			"./appjet webhooks test :id":                    "Send a webhook.test event to a webhook (admin)",
+			"./appjet webhooks deliveries :id [--limit 50]": "Show the delivery log of a webhook (admin)",
+
 			"./appjet configure":                  "Load config and install all Docker containers (without starting them)",
 			"./appjet configure :cluster":         "Load config and install all Docker containers in a specific cluster (without starting them)",
 			"./appjet configure :cluster :server": "Load config and install all Docker containers in a specific server in a specific cluster (without starting them)",
@@ -26,6 +57,8 @@ func HelpHandler(c *gin.Context) {
 			"./appjet inspect :cluster":         "Return the config.json present in all servers on a specific cluster",
 			"./appjet inspect :cluster :server": "Return the config.json present in a specific server on a specific cluster",
 
+			"./appjet templates [:cluster [:server]]": "List the runtime templates (language, framework, builders) the servers build the Dockerfile with",
+
 			"./appjet start":                             "Start all infrastructure in all servers on all clusters",
 			"./appjet start :cluster":                    "Start all infrastructure in all servers on a specific cluster",
 			"./appjet start :cluster :server":            "Start all infrastructure in a specific server on a specific cluster",
@@ -56,6 +89,42 @@ func HelpHandler(c *gin.Context) {
 			"./appjet scp run :script":                  "Run a pre-loaded SCP script in all servers in all clusters",
 			"./appjet scp run :script :cluster":         "Run a pre-loaded SCP script in all servers in a specific cluster",
 			"./appjet scp run :script :cluster :server": "Run a pre-loaded SCP script in a specific server in a specific cluster",
+
+			"./appjet rollout :cluster [--max-unavailable N] [--health-timeout 5m]": "Rolling deployment: configure, start and health-check N servers at a time, halting on the first failed batch (the servers of the later batches are skipped)",
+
+			"./appjet revisions":                                "List the configuration revisions accepted by the decision manager",
+			"./appjet revision :id":                             "Show a configuration revision",
+			"./appjet rollback --to :revision":                  "Re-push an older configuration revision to all servers in all clusters and restart them",
+			"./appjet rollback :cluster --to :revision":         "Re-push an older configuration revision to all servers in a specific cluster and restart them",
+			"./appjet rollback :cluster :server --to :revision": "Re-push an older configuration revision to a specific server in a specific cluster and restart it",
+
+			"./appjet servers":                                               "List the joined servers with their status and last heartbeat",
+			"./appjet servers delete :cluster :server":                       "Remove a joined server with its secret and certificate (admin)",
+			"./appjet join-tokens create --cluster :cluster [--expires 24h]": "Create a one-time token for appjet-server-daemon join, the secret is shown once (admin)",
+			"./appjet join-tokens list":                                      "List the join tokens (admin)",
+			"./appjet join-tokens revoke :id":                                "Revoke a join token (admin)",
+
+			"./appjet certs list": "List the pinned daemon certificates (admin)",
+			"./appjet certs enroll :cluster :server --csr daemon.csr [--out dir]": "Sign the certificate request of a daemon, pin it and write daemon.crt and ca.crt (admin)",
+			"./appjet certs delete :cluster :server":                              "Unpin the certificate of a daemon (admin)",
+
+			"./appjet secrets list":                    "List the servers whose daemon secret is registered (admin)",
+			"./appjet secrets set :cluster :server":    "Register the secret printed by appjet-server-daemon secret generate|rotate (admin)",
+			"./appjet secrets delete :cluster :server": "Remove the daemon secret of a server (admin)",
+
+			"./appjet audit [--since 24h] [--until :time] [--user :username] [--project :project] [--action clean] [--cluster :cluster] [--server :server] [--outcome failed]": "Query the audit log of the protected routes (admin)",
+			"./appjet audit --export [--since 7d]": "Export the audit log as JSON lines",
+
+			"./appjet jobs [:status]": "List the most recent jobs, optionally filtered by status (pending, running, succeeded, failed)",
+			"./appjet job :id":        "Show a job and its progress on each server",
+			"--stored":                "Add to configure to re-apply the configuration stored in the decision manager instead of pushing the local config.json",
+			"--wait":                  "Add to configure, rollout, rollback, start, restart, stop, clean, scripts, code and scp run to wait for the job to finish",
+
+			"./appjet projects": "List the projects, each one with its own configuration, revisions, jobs and health",
+			"./appjet projects create :project [--description \"text\"]": "Create a project (admin)",
+			"./appjet projects delete :project":                          "Delete a project, its history is kept and its containers are left on the servers (admin)",
+			"./appjet projects use :project":                             "Run the next commands on a project, ./appjet projects use default goes back to the default one",
+			"--project :project":                                         "Add to any command to run it on a project, instead of the one chosen with projects use or APPJET_PROJECT",
 		},
 	}
 
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+)
+
+// JoinHandler adds a daemon to the inventory. It is public, the daemon authenticates with a join token.
+func JoinHandler(c *gin.Context) {
+	var request services.JoinRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := services.JoinServer(request, c.ClientIP())
+	switch {
+	case errors.Is(err, services.ErrInvalidJoinToken):
+		log.Printf("Rejected join of server %s of cluster %s from %s: %s", request.Name, request.Cluster, c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or already used join token for cluster " + request.Cluster})
+	case errors.Is(err, services.ErrServerExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Server " + request.Name + " already exists in cluster " + request.Cluster})
+	case errors.Is(err, services.ErrInvalidCSR):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// HeartbeatHandler records the heartbeat of a joined daemon. It is public, the daemon signs the request
+// with its secret.
+func HeartbeatHandler(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the request body"})
+		return
+	}
+
+	cluster, server, err := services.VerifyDaemonRequest(c.Request, body)
+	if errors.Is(err, services.ErrInvalidDaemonSignature) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var heartbeat services.Heartbeat
+	if err := json.Unmarshal(body, &heartbeat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = services.RecordHeartbeat(cluster, server, heartbeat)
+	if errors.Is(err, services.ErrInventoryServerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found in the inventory, join it again"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error recording the heartbeat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"heartbeat-interval": services.GetHeartbeatSettings().Interval.String()})
+}
+
+// ListServersHandler returns the inventory of joined servers with their last heartbeat
+func ListServersHandler(c *gin.Context) {
+	servers, err := services.ListServers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the server inventory"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"servers": servers})
+}
+
+// DeleteServerHandler removes a joined server with its secret and certificate
+func DeleteServerHandler(c *gin.Context) {
+	err := services.DeleteServer(c.Param("cluster"), c.Param("server"))
+	if errors.Is(err, services.ErrInventoryServerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Server not found in the inventory"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting the server"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Server deleted"})
+}
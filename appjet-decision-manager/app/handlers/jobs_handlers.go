@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+func ListJobsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	jobs, err := services.ListJobs(currentProject(c), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+func GetJobHandler(c *gin.Context) {
+	job, err := services.GetJob(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && job.Project != currentProject(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
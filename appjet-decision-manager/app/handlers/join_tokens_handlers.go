@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// ListJoinTokensHandler lists the join tokens
+func ListJoinTokensHandler(c *gin.Context) {
+	tokens, err := services.ListJoinTokens()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load join tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// CreateJoinTokenHandler creates a one-time join token from {"cluster", "expires"}. The secret is
+// only part of this response.
+func CreateJoinTokenHandler(c *gin.Context) {
+	var request struct {
+		Cluster string `json:"cluster" binding:"required"`
+		Expires string `json:"expires"` // "1h", "2d"; 24h when empty
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, secret, err := services.CreateJoinToken(request.Cluster, request.Expires, currentUsername(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":  token,
+		"secret": secret,
+		"note":   "Store the secret now, it cannot be shown again",
+	})
+}
+
+// RevokeJoinTokenHandler deletes a join token
+func RevokeJoinTokenHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid join token"})
+		return
+	}
+
+	err = services.RevokeJoinToken(uint(id))
+	if errors.Is(err, services.ErrJoinTokenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Join token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking the join token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Join token revoked"})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// streamLogs proxies the logs of a container on the selected servers as server-sent events: a "log"
+// event per line and an "error" event when the stream of a server fails, with the cluster and server of
+// each line. ?follow=true, ?tail=N, ?since=10m and ?timestamps=true are passed to the daemons.
+func streamLogs(c *gin.Context, cluster string, server string, container string) {
+	config := storedConfig(c)
+	if config == nil {
+		return
+	}
+	if container == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The container is required"})
+		return
+	}
+
+	targets := services.ResolveTargets(currentProject(c), config, cluster, server)
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers match the requested cluster/server"})
+		return
+	}
+
+	query := url.Values{}
+	for _, option := range []string{"follow", "tail", "since", "timestamps"} {
+		if value := c.Query(option); value != "" {
+			query.Set(option, value)
+		}
+	}
+
+	lines := make(chan services.LogLine, 256)
+	go services.StreamDaemonLogs(c.Request.Context(), targets, container, query, lines)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		line, ok := <-lines
+		if !ok {
+			return false
+		}
+
+		if line.Error != "" {
+			c.SSEvent("error", line)
+		} else {
+			c.SSEvent("log", line)
+		}
+		return true
+	})
+
+	// let the streams of the other servers end once the client went away
+	for range lines {
+	}
+}
+
+// LogsSpecificClusterHandler streams the logs of a container on every server of a cluster, ?container=
+func LogsSpecificClusterHandler(c *gin.Context) {
+	streamLogs(c, c.Param("cluster"), "", c.Query("container"))
+}
+
+// LogsSpecificClusterSpecificServerHandler streams the logs of a container on one server
+func LogsSpecificClusterSpecificServerHandler(c *gin.Context) {
+	streamLogs(c, c.Param("cluster"), c.Param("server"), c.Param("container"))
+}
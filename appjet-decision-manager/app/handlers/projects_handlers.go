@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// currentProject returns the project of the request: the :project parameter, or the default project
+// for the routes without /projects/:project
+func currentProject(c *gin.Context) string {
+	if project := c.Param("project"); project != "" {
+		return project
+	}
+
+	return models.DefaultProject
+}
+
+// ProjectMiddlewareHandler runs before the handlers of the project routes: it answers 404 for a project
+// that does not exist and records the project of the request for the audit log
+func ProjectMiddlewareHandler(c *gin.Context) {
+	project := currentProject(c)
+	if !services.ProjectExists(project) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project " + project + " not found, create it with POST /appjet/projects"})
+		c.Abort()
+		return
+	}
+
+	c.Set("project", project)
+	c.Next()
+}
+
+// ListProjectsHandler lists the projects
+func ListProjectsHandler(c *gin.Context) {
+	projects, err := services.ListProjects()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load projects"})
+		return
+	}
+
+	response := make([]gin.H, len(projects))
+	for index, project := range projects {
+		_, err := services.CurrentConfig(project.Name)
+		response[index] = gin.H{
+			"name":        project.Name,
+			"description": project.Description,
+			"configured":  err == nil,
+			"created-by":  project.CreatedBy,
+			"created-at":  project.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"projects": response})
+}
+
+// CreateProjectHandler creates a project from {"name", "description"}. Its routes are under
+// /appjet/projects/:project, starting with PUT /appjet/projects/:project/config.
+func CreateProjectHandler(c *gin.Context) {
+	var request services.ProjectRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := services.CreateProject(request, currentUsername(c))
+	switch {
+	case errors.Is(err, services.ErrInvalidProjectRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProjectExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Project " + request.Name + " already exists"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"project": project})
+	}
+}
+
+// DeleteProjectHandler deletes a project. Its history is kept and its containers are left running.
+func DeleteProjectHandler(c *gin.Context) {
+	err := services.DeleteProject(c.Param("project"))
+	switch {
+	case errors.Is(err, services.ErrInvalidProjectRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, services.ErrProjectBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": "Project " + c.Param("project") + " has jobs that did not finish, wait for them before deleting it"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Project deleted, clean its servers to remove its containers"})
+	}
+}
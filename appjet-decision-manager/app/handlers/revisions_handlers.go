@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+func ListRevisionsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	revisions, err := services.ListConfigRevisions(currentProject(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+func GetRevisionHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	revision, config, err := services.GetConfigRevision(currentProject(c), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revision": revision, "config": config})
+}
+
+// rollback re-pushes the revision given by ?to= to the selected servers and restarts them.
+// The rollback is itself recorded as a new revision.
+func rollback(c *gin.Context, cluster string, server string) {
+	to, err := strconv.ParseUint(c.Query("to"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid 'to' revision"})
+		return
+	}
+
+	_, config, err := services.GetConfigRevision(currentProject(c), uint(to))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(services.ResolveTargets(currentProject(c), config, cluster, server)) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers of the revision match the requested cluster/server"})
+		return
+	}
+
+	// revisions recorded before validation existed may not be valid anymore
+	if errs := config.Validate(); len(errs) > 0 {
+		writeConfigError(c, errs)
+		return
+	}
+
+	// a fleet wide rollback also becomes the configuration in use
+	comment := fmt.Sprintf("rollback to revision %d", to)
+	var revision *models.ConfigRevision
+	if cluster == "" {
+		revision, err = services.ReplaceConfig(currentProject(c), config, currentUsername(c), cluster, server, comment)
+	} else {
+		revision, err = services.RecordConfigRevision(currentProject(c), config, currentUsername(c), cluster, server, comment)
+	}
+	if err != nil {
+		writeConfigError(c, err)
+		return
+	}
+
+	argument := fmt.Sprintf("to=%d revision=%d", to, revision.ID)
+	dispatchAsJob(c, "rollback", argument, config, cluster, server, services.RollbackCall(config))
+}
+
+func RollbackAllClustersAllServersHandler(c *gin.Context) {
+	rollback(c, "", "")
+}
+
+func RollbackSpecificClusterAllServersHandler(c *gin.Context) {
+	rollback(c, c.Param("cluster"), "")
+}
+
+func RollbackSpecificClusterSpecificServerHandler(c *gin.Context) {
+	rollback(c, c.Param("cluster"), c.Param("server"))
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// RolloutSpecificClusterHandler starts a rolling deployment of the stored configuration over a cluster.
+// Options come from the JSON body ({"maxUnavailable": 1, "healthTimeout": "5m"}) or the query string.
+func RolloutSpecificClusterHandler(c *gin.Context) {
+	cluster := c.Param("cluster")
+
+	config := storedConfig(c)
+	if config == nil {
+		return
+	}
+
+	var options services.RolloutOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&options); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if value := c.Query("maxUnavailable"); value != "" {
+		maxUnavailable, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maxUnavailable"})
+			return
+		}
+		options.MaxUnavailable = maxUnavailable
+	}
+	if value := c.Query("healthTimeout"); value != "" {
+		options.HealthTimeout = value
+	}
+
+	if _, err := options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	targets := services.ResolveTargets(currentProject(c), config, cluster, "")
+	if len(targets) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers match the requested cluster/server"})
+		return
+	}
+
+	job, err := services.StartRollout(services.JobRequest{
+		Project:   currentProject(c),
+		Command:   "rollout",
+		Argument:  "maxUnavailable=" + strconv.Itoa(options.MaxUnavailable),
+		Cluster:   cluster,
+		CreatedBy: currentUsername(c),
+	}, config, targets, options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditJob(c, job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"sort"
+	"strings"
+)
+
+// publicRoutes is the allowlist of routes that can be called without authentication, keyed by
+// "METHOD /route/:param". Every other route must be registered with RouteRegistry.Protected.
+var publicRoutes = map[string]bool{
+	"POST /appjet/login":  true,
+	"POST /appjet/logout": true,
+	"GET /appjet/help":    true,
+	// called by the daemons, authenticated by a join token and by the daemon secret
+	"POST /appjet/join":      true,
+	"POST /appjet/heartbeat": true,
+}
+
+// RouteRegistry registers the routes of the decision manager and remembers how each one was
+// registered, so that Verify can refuse to serve a route left without authentication.
+type RouteRegistry struct {
+	public    *gin.RouterGroup
+	protected *gin.RouterGroup
+	projects  *gin.RouterGroup
+	routes    map[string]bool // route -> registered as protected
+	errors    []string
+}
+
+// NewRouteRegistry creates the public and protected groups under basePath
+func NewRouteRegistry(r *gin.Engine, basePath string) *RouteRegistry {
+	public := r.Group(basePath)
+	protected := public.Group("/")
+	protected.Use(AuditMiddlewareHandler, AuthMiddlewareHandler, AuthorizationMiddlewareHandler)
+
+	return &RouteRegistry{
+		public:    public,
+		protected: protected,
+		projects:  protected.Group(projectRoutePrefix),
+		routes:    map[string]bool{},
+	}
+}
+
+// Public registers a route reachable without authentication. The route must be in the allowlist.
+func (registry *RouteRegistry) Public(method string, path string, handler gin.HandlerFunc) {
+	key := method + " " + joinPaths(registry.public.BasePath(), path)
+	if !publicRoutes[key] {
+		registry.errors = append(registry.errors, key+" is registered as public but is not in the public allowlist")
+	}
+
+	registry.routes[key] = false
+	registry.public.Handle(method, path, handler)
+}
+
+// Protected registers a route behind authentication and the permission matrix
+func (registry *RouteRegistry) Protected(method string, path string, handlers ...gin.HandlerFunc) {
+	key := method + " " + joinPaths(registry.protected.BasePath(), path)
+	if _, ok := routePermissions[key]; !ok {
+		registry.errors = append(registry.errors, key+" has no entry in the permission matrix")
+	}
+
+	registry.routes[key] = true
+	registry.protected.Handle(method, path, handlers...)
+}
+
+// Project registers a protected route that works on a project: at path for the default project and under
+// /projects/:project for every project. Both share the entry of path in the permission matrix.
+func (registry *RouteRegistry) Project(method string, path string, handler gin.HandlerFunc) {
+	registry.Protected(method, path, ProjectMiddlewareHandler, handler)
+
+	registry.routes[method+" "+joinPaths(registry.projects.BasePath(), path)] = true
+	registry.projects.Handle(method, path, ProjectMiddlewareHandler, handler)
+}
+
+// Verify checks every route served by the engine: it must be either an allowlisted public route
+// or registered as protected. Routes added directly on the engine or on a group are reported.
+func (registry *RouteRegistry) Verify(r *gin.Engine) error {
+	problems := append([]string{}, registry.errors...)
+
+	for _, route := range r.Routes() {
+		key := route.Method + " " + route.Path
+		protected, registered := registry.routes[key]
+		switch {
+		case !registered:
+			problems = append(problems, key+" was registered outside the route registry and may be unprotected")
+		case !protected && !publicRoutes[key]:
+			problems = append(problems, key+" is not protected")
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("refusing to serve, route self-check failed:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func joinPaths(basePath string, path string) string {
+	return strings.TrimSuffix(basePath, "/") + "/" + strings.TrimPrefix(path, "/")
+}
@@ -1,259 +1,97 @@
 package handlers
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
+	"net/url"
 )
 import services "appjet-decision-manager/app/services"
 
-func SCPAllClustersAllServersHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
+func scpCall(script []byte, filename string) services.DaemonCall {
+	return func(ctx context.Context, target services.DaemonTarget) (*http.Response, []byte, error) {
+		return services.ForwardSCPToDaemon(ctx, script, filename, target.URL("/api/scripts"))
+	}
+}
 
+func scpRunCall(script string) services.DaemonCall {
+	return func(ctx context.Context, target services.DaemonTarget) (*http.Response, []byte, error) {
+		return services.ForwardSCPRunToDaemon(ctx, target.URL("/api/scp/run/"+url.PathEscape(script)))
+	}
+}
+
+// readUploadedScript reads the uploaded script once so it can be forwarded to every daemon.
+func readUploadedScript(c *gin.Context) ([]byte, string, bool) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(400, gin.H{"error": "No file provided"})
-		return
+		return nil, "", false
 	}
 	defer file.Close()
 
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		clusterResponses := make([]map[string]interface{}, 0)
-
-		for sIndex := range config.Clusters[cIndex].Servers {
-			serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-			daemonResponse, _ := services.ForwardSCPToDaemon(file, header.Filename, "http://"+serverIP+":8080/api/scripts")
-
-			// Build response structure
-			serverResponse := map[string]interface{}{
-				config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-			}
-
-			clusterResponses = append(clusterResponses, serverResponse)
-		}
+	script, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Failed to read the provided file"})
+		return nil, "", false
+	}
 
-		// Build response structure for the cluster
-		clusterResponse := map[string]interface{}{
-			config.Clusters[cIndex].Name: clusterResponses,
-		}
+	return script, header.Filename, true
+}
 
-		daemonResponses = append(daemonResponses, clusterResponse)
+func SCPAllClustersAllServersHandler(c *gin.Context) {
+	config := storedConfig(c)
+	if config == nil {
+		return
 	}
 
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
+	script, filename, ok := readUploadedScript(c)
+	if !ok {
+		return
 	}
 
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+	dispatchAsJob(c, "scripts", filename, config, "", "", scpCall(script, filename))
 }
 
 func SCPSpecificClusterAllServersHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-	cluster := c.Param("cluster")
-
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(400, gin.H{"error": "No file provided"})
+	config := storedConfig(c)
+	if config == nil {
 		return
 	}
-	defer file.Close()
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		if config.Clusters[cIndex].Name == cluster {
-			clusterResponses := make([]map[string]interface{}, 0)
-
-			for sIndex := range config.Clusters[cIndex].Servers {
-				serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-				daemonResponse, _ := services.ForwardSCPToDaemon(file, header.Filename, "http://"+serverIP+":8080/api/scripts")
-
-				// Build response structure
-				serverResponse := map[string]interface{}{
-					config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-				}
-
-				clusterResponses = append(clusterResponses, serverResponse)
-			}
-
-			// Build response structure for the cluster
-			clusterResponse := map[string]interface{}{
-				config.Clusters[cIndex].Name: clusterResponses,
-			}
-
-			daemonResponses = append(daemonResponses, clusterResponse)
-		}
-	}
 
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
+	script, filename, ok := readUploadedScript(c)
+	if !ok {
+		return
 	}
 
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+	dispatchAsJob(c, "scripts", filename, config, c.Param("cluster"), "", scpCall(script, filename))
 }
 
 func SCPSpecificClusterSpecificServerHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-	cluster := c.Param("cluster")
-	server := c.Param("server")
-
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(400, gin.H{"error": "No file provided"})
+	config := storedConfig(c)
+	if config == nil {
 		return
 	}
-	defer file.Close()
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		if config.Clusters[cIndex].Name == cluster {
-			for sIndex := range config.Clusters[cIndex].Servers {
-				if config.Clusters[cIndex].Servers[sIndex].Name == server {
-					serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-					daemonResponse, _ := services.ForwardSCPToDaemon(file, header.Filename, "http://"+serverIP+":8080/api/scripts")
-
-					// Build response structure for the server
-					serverResponse := map[string]interface{}{
-						config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-					}
-
-					daemonResponses = append(daemonResponses, serverResponse)
-				}
-			}
-		}
-	}
 
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
+	script, filename, ok := readUploadedScript(c)
+	if !ok {
+		return
 	}
 
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+	dispatchAsJob(c, "scripts", filename, config, c.Param("cluster"), c.Param("server"), scpCall(script, filename))
 }
 
 func SCPRunAllClustersAllServersHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-	script := c.Param("script")
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		clusterResponses := make([]map[string]interface{}, 0)
-
-		for sIndex := range config.Clusters[cIndex].Servers {
-			serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-			daemonResponse, _ := services.ForwardSCPRunToDaemon("http://" + serverIP + ":8080/api/scp/run/" + script)
-
-			// Build response structure
-			serverResponse := map[string]interface{}{
-				config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-			}
-
-			clusterResponses = append(clusterResponses, serverResponse)
-		}
-
-		// Build response structure for the cluster
-		clusterResponse := map[string]interface{}{
-			config.Clusters[cIndex].Name: clusterResponses,
-		}
-
-		daemonResponses = append(daemonResponses, clusterResponse)
-	}
-
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
-	}
-
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+	config := storedConfig(c)
+	dispatchAsJob(c, "scp-run", c.Param("script"), config, "", "", scpRunCall(c.Param("script")))
 }
 
 func SCPRunSpecificClusterAllServersHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-	cluster := c.Param("cluster")
-	script := c.Param("script")
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		if config.Clusters[cIndex].Name == cluster {
-			clusterResponses := make([]map[string]interface{}, 0)
-
-			for sIndex := range config.Clusters[cIndex].Servers {
-				serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-				daemonResponse, _ := services.ForwardSCPRunToDaemon("http://" + serverIP + ":8080/api/scp/run/" + script)
-
-				// Build response structure
-				serverResponse := map[string]interface{}{
-					config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-				}
-
-				clusterResponses = append(clusterResponses, serverResponse)
-			}
-
-			// Build response structure for the cluster
-			clusterResponse := map[string]interface{}{
-				config.Clusters[cIndex].Name: clusterResponses,
-			}
-
-			daemonResponses = append(daemonResponses, clusterResponse)
-		}
-	}
-
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
-	}
-
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+	config := storedConfig(c)
+	dispatchAsJob(c, "scp-run", c.Param("script"), config, c.Param("cluster"), "", scpRunCall(c.Param("script")))
 }
 
 func SCPRunSpecificClusterSpecificServerHandler(c *gin.Context) {
-	config := services.GenerateConfigIfNotExist(c)
-	script := c.Param("script")
-	cluster := c.Param("cluster")
-	server := c.Param("server")
-
-	// Slice to store daemon responses
-	var daemonResponses []map[string]interface{}
-
-	for cIndex := range config.Clusters {
-		if config.Clusters[cIndex].Name == cluster {
-			for sIndex := range config.Clusters[cIndex].Servers {
-				if config.Clusters[cIndex].Servers[sIndex].Name == server {
-					serverIP := config.Clusters[cIndex].Servers[sIndex].IP
-					daemonResponse, _ := services.ForwardSCPRunToDaemon("http://" + serverIP + ":8080/api/scp/run/" + script)
-
-					// Build response structure for the server
-					serverResponse := map[string]interface{}{
-						config.Clusters[cIndex].Servers[sIndex].Name: daemonResponse, // Storing the actual response from the daemon
-					}
-
-					daemonResponses = append(daemonResponses, serverResponse)
-				}
-			}
-		}
-	}
-
-	// Build the final response structure
-	finalResponse := map[string]interface{}{
-		"daemon-responses": daemonResponses,
-	}
-
-	// Return the final response
-	c.JSON(http.StatusOK, finalResponse)
+	config := storedConfig(c)
+	dispatchAsJob(c, "scp-run", c.Param("script"), config, c.Param("cluster"), c.Param("server"), scpRunCall(c.Param("script")))
 }
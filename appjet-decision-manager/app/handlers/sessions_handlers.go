@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// sessionResponse is the public view of a session, without its token
+func sessionResponse(session *models.UserSession, current *models.UserSession) gin.H {
+	return gin.H{
+		"id":           session.ID,
+		"created-at":   session.CreatedAt,
+		"last-seen-at": session.LastSeenAt,
+		"expires-at":   session.ExpiresAt,
+		"client-ip":    session.ClientIP,
+		"user-agent":   session.UserAgent,
+		"current":      current != nil && session.ID == current.ID,
+	}
+}
+
+func currentSession(c *gin.Context) *models.UserSession {
+	value, _ := c.Get("session")
+	return value.(*models.UserSession)
+}
+
+func writeSessions(c *gin.Context, userID uint) {
+	sessions, err := services.ListSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load sessions"})
+		return
+	}
+
+	current := currentSession(c)
+	response := make([]gin.H, len(sessions))
+	for index := range sessions {
+		response[index] = sessionResponse(&sessions[index], current)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+// ListSessionsHandler lists the active sessions of the authenticated user
+func ListSessionsHandler(c *gin.Context) {
+	writeSessions(c, currentUser(c).ID)
+}
+
+// ListUserSessionsHandler lists the active sessions of any user
+func ListUserSessionsHandler(c *gin.Context) {
+	user, err := services.GetUser(c.Param("username"))
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	writeSessions(c, user.ID)
+}
+
+// RefreshSessionHandler replaces the current session by a new one, with a new token and expiry
+func RefreshSessionHandler(c *gin.Context) {
+	session, err := services.RefreshSession(currentSession(c), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error refreshing the session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": session.Token, "expires-at": session.ExpiresAt})
+}
+
+// RevokeSessionHandler ends a session by id. Users can revoke their own sessions, admins any session.
+func RevokeSessionHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	session, err := services.GetSession(uint(id))
+	if errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if session.UserID != user.ID && !models.HasPermission(user.Role, models.PermissionUsersManage) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      "Forbidden: role '" + user.Role + "' is missing the '" + models.PermissionUsersManage + "' permission",
+			"permission": models.PermissionUsersManage,
+			"role":       user.Role,
+		})
+		return
+	}
+
+	if err := services.RevokeSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking the session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// statusHandler returns the health recorded by the health monitor for the selected servers, with
+// ?window= the uptime window (default 24h)
+func statusHandler(c *gin.Context, cluster string, server string) {
+	window := c.DefaultQuery("window", "24h")
+
+	config, err := services.CurrentConfig(currentProject(c))
+	if err != nil {
+		// joined servers are monitored even without a configuration
+		config = &models.Configuration{}
+	}
+
+	servers, err := services.ServerHealthStatus(currentProject(c), config, cluster, server, window)
+	if errors.Is(err, services.ErrInvalidHealthWindow) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the server health"})
+		return
+	}
+	if len(servers) == 0 && (cluster != "" || server != "") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No servers found for cluster " + cluster + " " + server})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"window": window, "servers": servers})
+}
+
+func StatusAllClustersAllServersHandler(c *gin.Context) {
+	statusHandler(c, "", "")
+}
+
+func StatusSpecificClusterAllServersHandler(c *gin.Context) {
+	statusHandler(c, c.Param("cluster"), "")
+}
+
+func StatusSpecificClusterSpecificServerHandler(c *gin.Context) {
+	statusHandler(c, c.Param("cluster"), c.Param("server"))
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+import services "appjet-decision-manager/app/services"
+
+func templatesCall(ctx context.Context, target services.DaemonTarget) (*http.Response, []byte, error) {
+	return services.ForwardTemplatesToDaemon(ctx, target.URL("/api/templates"))
+}
+
+// TemplatesHandler lists the runtime templates of the daemons, the languages and frameworks their
+// Dockerfiles can be built for
+func TemplatesHandler(c *gin.Context) {
+	config := storedConfig(c)
+	dispatchToDaemons(c, config, c.Param("cluster"), c.Param("server"), templatesCall)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// userResponse is the public view of a user, without the password hash
+func userResponse(user *models.User) gin.H {
+	return gin.H{
+		"username":             user.Username,
+		"name":                 user.Name,
+		"role":                 user.Role,
+		"must-change-password": user.MustChangePassword,
+	}
+}
+
+// writeUserError maps user management errors to HTTP answers
+func writeUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is wrong"})
+	case errors.Is(err, services.ErrUnknownRole), errors.Is(err, services.ErrWeakPassword), errors.Is(err, services.ErrSamePassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func currentUser(c *gin.Context) *User {
+	value, _ := c.Get("user")
+	return value.(*User)
+}
+
+func ListUsersHandler(c *gin.Context) {
+	users, err := services.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load users"})
+		return
+	}
+
+	response := make([]gin.H, len(users))
+	for index := range users {
+		response[index] = userResponse(&users[index])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": response})
+}
+
+// CreateUserHandler creates a user from {"username", "name", "role", "password"}.
+// The password is temporary, the user must change it on the first login.
+func CreateUserHandler(c *gin.Context) {
+	var request struct {
+		Username string `json:"username" binding:"required"`
+		Name     string `json:"name"`
+		Role     string `json:"role" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := services.CreateUser(request.Username, request.Name, request.Role, request.Password)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"user": userResponse(user)})
+}
+
+func DeleteUserHandler(c *gin.Context) {
+	username := c.Param("username")
+	if username == currentUser(c).Username {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delete your own user"})
+		return
+	}
+
+	if err := services.DeleteUser(username); err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// SetUserRoleHandler changes the role of a user from {"role"}
+func SetUserRoleHandler(c *gin.Context) {
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	username := c.Param("username")
+	if username == currentUser(c).Username {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	if err := services.SetUserRole(username, request.Role); err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated"})
+}
+
+// ResetUserPasswordHandler sets a temporary password from {"password"}, to be changed on the next login
+func ResetUserPasswordHandler(c *gin.Context) {
+	var request struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ResetPassword(c.Param("username"), request.Password); err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset, it must be changed on the next login"})
+}
+
+// ChangePasswordHandler lets the authenticated user change their own password from
+// {"current-password", "new-password"}
+func ChangePasswordHandler(c *gin.Context) {
+	var request struct {
+		CurrentPassword string `json:"current-password" binding:"required"`
+		NewPassword     string `json:"new-password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ChangePassword(currentUser(c), request.CurrentPassword, request.NewPassword); err != nil {
+		writeUserError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"appjet-decision-manager/app/models"
+	"appjet-decision-manager/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// maxDeliveriesLimit bounds the number of deliveries returned for a webhook
+const maxDeliveriesLimit = 500
+
+// webhookResponse is the public view of a webhook, without its secret
+func webhookResponse(webhook *models.Webhook) gin.H {
+	return gin.H{
+		"id":         webhook.ID,
+		"name":       webhook.Name,
+		"url":        webhook.URL,
+		"events":     webhook.EventList(),
+		"format":     webhook.Format,
+		"created-by": webhook.CreatedBy,
+		"created-at": webhook.CreatedAt,
+	}
+}
+
+// webhookFromParam loads the webhook of the :id parameter, or answers with an error
+func webhookFromParam(c *gin.Context) *models.Webhook {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook"})
+		return nil
+	}
+
+	webhook, err := services.GetWebhook(uint(id))
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+
+	return webhook
+}
+
+// ListWebhooksHandler lists the webhooks
+func ListWebhooksHandler(c *gin.Context) {
+	webhooks, err := services.ListWebhooks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load webhooks"})
+		return
+	}
+
+	response := make([]gin.H, len(webhooks))
+	for index := range webhooks {
+		response[index] = webhookResponse(&webhooks[index])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": response, "events": models.WebhookEvents, "formats": models.WebhookFormats})
+}
+
+// CreateWebhookHandler creates a webhook from {"name", "url", "events", "format", "secret"}. The secret
+// is only part of this response.
+func CreateWebhookHandler(c *gin.Context) {
+	var request services.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, secret, err := services.CreateWebhook(request, currentUsername(c))
+	if errors.Is(err, services.ErrInvalidWebhookRequest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": webhookResponse(webhook),
+		"secret":  secret,
+		"note":    "Store the secret now to verify the " + services.WebhookSignatureHeader + " header, it cannot be shown again",
+	})
+}
+
+// DeleteWebhookHandler deletes a webhook with its delivery log
+func DeleteWebhookHandler(c *gin.Context) {
+	webhook := webhookFromParam(c)
+	if webhook == nil {
+		return
+	}
+
+	if err := services.DeleteWebhook(webhook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting the webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// TestWebhookHandler sends a webhook.test event to the webhook, its result is read from the deliveries
+func TestWebhookHandler(c *gin.Context) {
+	webhook := webhookFromParam(c)
+	if webhook == nil {
+		return
+	}
+
+	delivery, err := services.TestWebhook(webhook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+// ListWebhookDeliveriesHandler returns the last ?limit= (default 50) deliveries of a webhook
+func ListWebhookDeliveriesHandler(c *gin.Context) {
+	webhook := webhookFromParam(c)
+	if webhook == nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > maxDeliveriesLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	deliveries, err := services.ListWebhookDeliveries(webhook.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
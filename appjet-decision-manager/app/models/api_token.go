@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APITokenPrefix starts every API token secret, which tells them apart from session tokens
+const APITokenPrefix = "ajt_"
+
+// APITokenScopes are the route families an API token can be scoped to, named after the first
+// segment of the /appjet routes. Account, session, token and user management are never granted.
+var APITokenScopes = []string{
+	"check-alive", "status", "inspect", "templates", "logs", "jobs", "config", "revisions",
+	"start", "stop", "restart", "clean", "exec",
+	"configure", "rollout", "rollback",
+	"scripts", "code", "scp",
+	"audit", "projects",
+}
+
+// APIToken is a long-lived named credential for CI pipelines. Only the sha256 hash of the secret
+// is stored, the secret itself is shown once when the token is created.
+type APIToken struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:16;not null"`
+	Hash       string `gorm:"size:64;not null;uniqueIndex"`
+	Scopes     string `gorm:"size:255;not null"` // comma separated APITokenScopes
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time // nil never expires
+	LastUsedAt *time.Time
+}
+
+// ScopeList returns the scopes of the token
+func (t *APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+
+	return strings.Split(t.Scopes, ",")
+}
+
+// AllowsScope reports whether the token was granted the scope
+func (t *APIToken) AllowsScope(scope string) bool {
+	for _, granted := range t.ScopeList() {
+		if granted == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Outcomes of an audit event, on top of the fan-out outcomes
+const (
+	// AuditOutcomeDenied is recorded when the permission matrix or an API token scope refused the request
+	AuditOutcomeDenied = "denied"
+	// AuditOutcomePending is recorded for jobs until they finish
+	AuditOutcomePending = "pending"
+)
+
+// AuditEvent records a request made to a protected route: who made it, on what, and how it went
+type AuditEvent struct {
+	ID         uint                `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time           `gorm:"index" json:"created-at"`
+	RequestID  string              `gorm:"size:64;index" json:"request-id"`
+	Username   string              `gorm:"size:100;not null;index" json:"user"`
+	Role       string              `gorm:"size:20" json:"role"`
+	APIToken   string              `gorm:"size:100" json:"api-token,omitempty"` // name of the API token used, if any
+	Method     string              `gorm:"size:10;not null" json:"method"`
+	Route      string              `gorm:"size:255;not null" json:"route"`
+	Path       string              `gorm:"size:1024" json:"path"`
+	Project    string              `gorm:"size:64;index" json:"project,omitempty"`
+	Action     string              `gorm:"size:50;index" json:"action"`
+	Cluster    string              `gorm:"size:100;index" json:"cluster,omitempty"`
+	Server     string              `gorm:"size:100;index" json:"server,omitempty"`
+	Container  string              `gorm:"size:100" json:"container,omitempty"`
+	StatusCode int                 `json:"status-code"`
+	Outcome    string              `gorm:"size:20;index" json:"outcome"`
+	JobID      string              `gorm:"size:36;index" json:"job-id,omitempty"`
+	Results    []AuditServerResult `gorm:"serializer:json;type:text" json:"results,omitempty"`
+	Detail     string              `gorm:"type:text" json:"detail,omitempty"` // e.g. the command run in a container
+	DurationMs int64               `json:"duration-ms"`
+	ClientIP   string              `gorm:"size:64" json:"client-ip"`
+}
+
+// AuditServerResult summarizes the result of a command on one server
+type AuditServerResult struct {
+	Cluster string `json:"cluster"`
+	Server  string `json:"server"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DaemonCertificate is the certificate issued to the daemon of a server when it was enrolled. The
+// decision manager only talks to a daemon presenting this exact certificate.
+type DaemonCertificate struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	Cluster      string    `gorm:"size:100;not null;uniqueIndex:idx_daemon_certificates_server" json:"cluster"`
+	Server       string    `gorm:"size:100;not null;uniqueIndex:idx_daemon_certificates_server" json:"server"`
+	Fingerprint  string    `gorm:"size:64;not null" json:"fingerprint"` // hex sha256 of the DER certificate
+	SerialNumber string    `gorm:"size:64;not null" json:"serial-number"`
+	NotAfter     time.Time `json:"not-after"`
+	Certificate  string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt    time.Time `json:"created-at"`
+	UpdatedAt    time.Time `json:"updated-at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DaemonSecret is the shared secret the decision manager signs its calls to a daemon with. It is
+// generated on the server with "appjet-server-daemon secret generate" and registered here.
+type DaemonSecret struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	Cluster   string    `gorm:"size:100;not null;uniqueIndex:idx_daemon_secrets_server" json:"cluster"`
+	Server    string    `gorm:"size:100;not null;uniqueIndex:idx_daemon_secrets_server" json:"server"`
+	Secret    string    `gorm:"size:128;not null" json:"-"`
+	CreatedAt time.Time `json:"created-at"`
+	UpdatedAt time.Time `json:"updated-at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// TestSharedFilesInSync fails when a copy of a shared file in appjet-cli or appjet-server-daemon was edited,
+// or not regenerated after the file changed here
+func TestSharedFilesInSync(t *testing.T) {
+	output, err := exec.Command("go", "run", "../../tools/sharedmodels", "-check").CombinedOutput()
+	if err != nil {
+		t.Fatalf("the shared files are out of sync: %s\n%s", err, output)
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Health states of a server, as seen by the health monitor
+const (
+	HealthUp       = "up"
+	HealthDown     = "down"
+	HealthFlapping = "flapping"
+	HealthUnknown  = "unknown"
+)
+
+// HealthTransition records a change of the health state of a server
+type HealthTransition struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	Project       string    `gorm:"size:64;not null;default:default;index:idx_health_transitions_server" json:"project"`
+	Cluster       string    `gorm:"size:100;not null;index:idx_health_transitions_server" json:"cluster"`
+	Server        string    `gorm:"size:100;not null;index:idx_health_transitions_server" json:"server"`
+	State         string    `gorm:"size:20;not null" json:"state"`
+	PreviousState string    `gorm:"size:20" json:"previous-state,omitempty"`
+	Reason        string    `gorm:"size:1024" json:"reason,omitempty"` // why the server is down
+	CreatedAt     time.Time `gorm:"index:idx_health_transitions_server" json:"at"`
+}
+
+// ServerHealth is the current health of a server with its recent transitions
+type ServerHealth struct {
+	Cluster       string             `json:"cluster"`
+	Server        string             `json:"server"`
+	State         string             `json:"state"`
+	Since         *time.Time         `json:"since,omitempty"`
+	Reason        string             `json:"reason,omitempty"`
+	CheckedAt     *time.Time         `json:"checked-at,omitempty"`
+	UptimePercent *float64           `json:"uptime-percent"` // nil until the server was checked within the window
+	History       []HealthTransition `json:"history"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Job and job task statuses
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	// JobStatusSkipped is the status of the tasks of a rollout batch that never started after a halt
+	JobStatusSkipped = "skipped"
+)
+
+// Job represents a mutating command that runs asynchronously on one or more servers
+type Job struct {
+	ID         string     `gorm:"primaryKey;size:36" json:"id"`
+	Project    string     `gorm:"size:64;not null;default:default;index" json:"project"`
+	Command    string     `gorm:"not null;index" json:"command"`
+	Argument   string     `json:"argument,omitempty"`
+	Cluster    string     `json:"cluster,omitempty"`
+	Server     string     `json:"server,omitempty"`
+	Status     string     `gorm:"not null;index" json:"status"`
+	Outcome    string     `json:"outcome,omitempty"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy  string     `json:"created-by"`
+	CreatedAt  time.Time  `json:"created-at"`
+	StartedAt  *time.Time `json:"started-at,omitempty"`
+	FinishedAt *time.Time `json:"finished-at,omitempty"`
+	Tasks      []JobTask  `gorm:"foreignKey:JobID" json:"tasks,omitempty"`
+}
+
+// JobTask represents the progress of a job on one server
+type JobTask struct {
+	ID         uint       `gorm:"primaryKey" json:"-"`
+	JobID      string     `gorm:"size:36;not null;index" json:"-"`
+	Position   int        `gorm:"not null" json:"-"`
+	Cluster    string     `gorm:"not null" json:"cluster"`
+	Server     string     `gorm:"not null" json:"server"`
+	Batch      int        `json:"batch,omitempty"`
+	Phase      string     `json:"phase,omitempty"`
+	Status     string     `gorm:"not null" json:"status"`
+	Result     string     `json:"result,omitempty"`
+	HTTPCode   int        `json:"http-code,omitempty"`
+	Body       string     `gorm:"type:text" json:"body,omitempty"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	DurationMs int64      `json:"duration-ms"`
+	StartedAt  *time.Time `json:"started-at,omitempty"`
+	FinishedAt *time.Time `json:"finished-at,omitempty"`
+}
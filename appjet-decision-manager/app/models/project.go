@@ -0,0 +1,29 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultProject is the project of the routes without /projects/:project. Everything recorded before
+// projects existed belongs to it.
+const DefaultProject = "default"
+
+// projectNamePattern keeps project names usable as docker compose project names and directory names
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Project is an application deployed by the decision manager. It owns its configuration, whose
+// history is in its configuration revisions, its jobs and the health of its containers.
+type Project struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:64;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:255" json:"description,omitempty"`
+	Config      string    `gorm:"type:longtext" json:"-"` // configuration in use, config.json for the default project
+	CreatedBy   string    `gorm:"size:100;not null" json:"created-by"`
+	CreatedAt   time.Time `json:"created-at"`
+}
+
+// ValidProjectName reports whether name can be used for a project: lowercase letters, digits, - and _
+func ValidProjectName(name string) bool {
+	return projectNamePattern.MatchString(name)
+}
@@ -0,0 +1,36 @@
+package models
+
+// Statuses of a command forwarded to a single daemon
+const (
+	ServerStatusOK          = "ok"
+	ServerStatusFailed      = "failed"
+	ServerStatusUnreachable = "unreachable"
+	ServerStatusTimeout     = "timeout"
+)
+
+// Overall outcomes of a command forwarded to several daemons
+const (
+	OutcomeSuccess = "success"
+	OutcomePartial = "partial"
+	OutcomeFailed  = "failed"
+)
+
+// ServerResult represents the result of a command on one server
+type ServerResult struct {
+	Cluster    string      `json:"cluster"`
+	Server     string      `json:"server"`
+	Status     string      `json:"status"`
+	HTTPCode   int         `json:"http-code,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	DurationMs int64       `json:"duration-ms"`
+}
+
+// FanOutResponse represents the response of every endpoint that forwards a command to the daemons
+type FanOutResponse struct {
+	Outcome string         `json:"outcome"`
+	Total   int            `json:"total"`
+	Ok      int            `json:"ok"`
+	Failed  int            `json:"failed"`
+	Results []ServerResult `json:"results"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrImmutableRevision is returned when something tries to change a stored revision
+var ErrImmutableRevision = errors.New("configuration revisions are immutable")
+
+// ConfigRevision represents a configuration accepted by the decision manager
+type ConfigRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Project   string    `gorm:"size:64;not null;default:default;index" json:"project"`
+	Hash      string    `gorm:"size:64;not null;index" json:"hash"`
+	Config    string    `gorm:"type:longtext;not null" json:"-"`
+	Author    string    `gorm:"not null" json:"author"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Server    string    `json:"server,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created-at"`
+}
+
+// BeforeUpdate keeps revisions immutable
+func (ConfigRevision) BeforeUpdate(*gorm.DB) error {
+	return ErrImmutableRevision
+}
+
+// BeforeDelete keeps revisions immutable
+func (ConfigRevision) BeforeDelete(*gorm.DB) error {
+	return ErrImmutableRevision
+}
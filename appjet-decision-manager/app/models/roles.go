@@ -0,0 +1,82 @@
+package models
+
+// Roles stored in User.Role
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// Permissions granted to roles and required by the /appjet routes
+const (
+	// PermissionStatusRead allows check-alive, status and inspect, and listing the servers and projects
+	PermissionStatusRead = "status:read"
+	// PermissionLogsRead allows reading the container logs
+	PermissionLogsRead = "logs:read"
+	// PermissionConfigRead allows reading the jobs, the configuration, its revisions and the runtime templates
+	PermissionConfigRead = "config:read"
+	// PermissionContainersControl allows start, stop and restart
+	PermissionContainersControl = "containers:control"
+	// PermissionContainersExec allows running commands inside the containers, one-shot or interactive
+	PermissionContainersExec = "containers:exec"
+	// PermissionInfrastructureClean allows removing docker images, containers and volumes
+	PermissionInfrastructureClean = "infrastructure:clean"
+	// PermissionConfigWrite allows changing and pushing the configuration (configure, rollout, rollback)
+	PermissionConfigWrite = "config:write"
+	// PermissionScriptsWrite allows uploading and running scripts and code
+	PermissionScriptsWrite = "scripts:write"
+	// PermissionUsersManage allows managing users
+	PermissionUsersManage = "users:manage"
+	// PermissionServersManage allows registering the servers and the secrets their daemons trust
+	PermissionServersManage = "servers:manage"
+	// PermissionWebhooksManage allows managing the outbound webhooks and reading their deliveries
+	PermissionWebhooksManage = "webhooks:manage"
+	// PermissionProjectsManage allows creating and deleting the projects
+	PermissionProjectsManage = "projects:manage"
+	// PermissionAuditRead allows reading and exporting the audit log
+	PermissionAuditRead = "audit:read"
+	// PermissionAccountSelf allows users to manage their own account (e.g. change their password)
+	PermissionAccountSelf = "account:self"
+)
+
+// RolePermissions is the permission set of each role. Unknown roles have no permissions.
+var RolePermissions = map[string][]string{
+	RoleViewer: {
+		PermissionAccountSelf,
+		PermissionStatusRead,
+	},
+	RoleOperator: {
+		PermissionAccountSelf,
+		PermissionStatusRead,
+		PermissionLogsRead,
+		PermissionConfigRead,
+		PermissionContainersControl,
+	},
+	RoleAdmin: {
+		PermissionAccountSelf,
+		PermissionStatusRead,
+		PermissionLogsRead,
+		PermissionConfigRead,
+		PermissionContainersControl,
+		PermissionContainersExec,
+		PermissionInfrastructureClean,
+		PermissionConfigWrite,
+		PermissionScriptsWrite,
+		PermissionUsersManage,
+		PermissionServersManage,
+		PermissionWebhooksManage,
+		PermissionProjectsManage,
+		PermissionAuditRead,
+	},
+}
+
+// HasPermission reports whether the role grants the permission
+func HasPermission(role string, permission string) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
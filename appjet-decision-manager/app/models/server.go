@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Statuses of a server of the inventory
+const (
+	ServerOnline  = "online"
+	ServerOffline = "offline"
+)
+
+// Server is a server whose daemon joined the decision manager with a join token. Its daemon sends
+// heartbeats, and it is marked offline when they stop.
+type Server struct {
+	ID            uint                   `gorm:"primaryKey" json:"-"`
+	Cluster       string                 `gorm:"size:100;not null;uniqueIndex:idx_servers_name" json:"cluster"`
+	Name          string                 `gorm:"size:100;not null;uniqueIndex:idx_servers_name" json:"name"`
+	Scheme        string                 `gorm:"size:10;not null" json:"scheme"`
+	Address       string                 `gorm:"size:255;not null" json:"address"`
+	Port          int                    `gorm:"not null" json:"port"`
+	BasePath      string                 `gorm:"size:255" json:"base-path,omitempty"`
+	Status        string                 `gorm:"size:20;not null;index" json:"status"`
+	Version       string                 `gorm:"size:50" json:"version"`
+	Hostname      string                 `gorm:"size:255" json:"hostname"`
+	FreeDiskBytes uint64                 `json:"free-disk-bytes"`
+	Containers    map[string]interface{} `gorm:"serializer:json;type:text" json:"containers"`
+	JoinedAt      time.Time              `json:"joined-at"`
+	LastSeenAt    *time.Time             `json:"last-seen-at"`
+}
+
+// JoinToken lets one daemon join a cluster, once. Only the sha256 hash of the secret is stored.
+type JoinToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Prefix    string     `gorm:"size:16;not null" json:"prefix"`
+	Hash      string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Cluster   string     `gorm:"size:100;not null" json:"cluster"`
+	CreatedBy string     `gorm:"size:100;not null" json:"created-by"`
+	CreatedAt time.Time  `json:"created-at"`
+	ExpiresAt time.Time  `json:"expires-at"`
+	UsedAt    *time.Time `json:"used-at,omitempty"`
+	UsedBy    string     `gorm:"size:100" json:"used-by,omitempty"` // server that joined with the token
+}
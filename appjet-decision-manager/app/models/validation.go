@@ -0,0 +1,301 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// This file is shared by appjet-cli, appjet-decision-manager and appjet-server-daemon: edit it here, then
+// run go generate ./app/models to copy it to the other two modules.
+
+// languagePattern is what a language can be. The daemons build the Dockerfile with the runtime
+// template of the language, which they check when they are configured (GET /api/templates).
+var languagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+-]*$`)
+
+// SupportedBuilders lists the builders accepted for the languages of the builtin templates that
+// need one. Other languages are built without a builder image (python installs its requirements
+// with pip), unless their template lists builders.
+var SupportedBuilders = map[string][]string{
+	"java": {"maven", "gradle"},
+}
+
+// serviceNamePattern is what docker compose accepts as a service and container name
+var serviceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// FieldError is a validation error on one field of the configuration, addressed by its
+// json path (e.g. "clusters[0].servers[1].ip").
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors is the list of problems found in a configuration
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for index, fieldError := range e {
+		messages[index] = fieldError.Error()
+	}
+
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationErrors) add(field string, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the configuration and returns every problem found, or nil when it is valid.
+func (config *Configuration) Validate() ValidationErrors {
+	var errs ValidationErrors
+
+	config.validateClusters(&errs)
+	if config.buildsApplication() {
+		config.validateApplication(&errs)
+	}
+	if len(config.Artifact.Services) == 0 {
+		config.validateDatabase(&errs)
+	} else {
+		config.validateServices(&errs)
+	}
+	config.validateCodeCheckout(&errs)
+	config.validateExtraCommands(&errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+func (config *Configuration) validateClusters(errs *ValidationErrors) {
+	if len(config.Clusters) == 0 {
+		errs.add("clusters", "at least one cluster is required")
+	}
+
+	clusterNames := map[string]bool{}
+	for cIndex, cluster := range config.Clusters {
+		field := fmt.Sprintf("clusters[%d]", cIndex)
+
+		if cluster.Name == "" {
+			errs.add(field+".name", "is required")
+		} else if clusterNames[cluster.Name] {
+			errs.add(field+".name", "duplicate cluster name %q", cluster.Name)
+		}
+		clusterNames[cluster.Name] = true
+
+		if len(cluster.Servers) == 0 {
+			errs.add(field+".servers", "at least one server is required")
+		}
+
+		serverNames := map[string]bool{}
+		for sIndex, server := range cluster.Servers {
+			serverField := fmt.Sprintf("%s.servers[%d]", field, sIndex)
+
+			if server.Name == "" {
+				errs.add(serverField+".name", "is required")
+			} else if serverNames[server.Name] {
+				errs.add(serverField+".name", "duplicate server name %q in cluster %q", server.Name, cluster.Name)
+			}
+			serverNames[server.Name] = true
+
+			if server.IP == "" {
+				errs.add(serverField+".ip", "is required")
+			}
+			if server.Port != 0 {
+				validatePort(errs, serverField+".port", server.Port)
+			}
+			if server.Scheme != "" && server.Scheme != "https" && server.Scheme != "http" {
+				errs.add(serverField+".scheme", "unsupported scheme %q, expected https or http", server.Scheme)
+			}
+			if server.BasePath != "" && !strings.HasPrefix(server.BasePath, "/") {
+				errs.add(serverField+".base-path", "must start with /, got %q", server.BasePath)
+			}
+		}
+	}
+}
+
+func (config *Configuration) validateApplication(errs *ValidationErrors) {
+	application := config.Artifact.Application
+	field := "artifact.application"
+
+	if application.Language == "" {
+		errs.add(field+".language", "is required")
+	} else if !languagePattern.MatchString(application.Language) {
+		errs.add(field+".language", "invalid language %q, expected lowercase letters, digits, ., +, - and _", application.Language)
+	}
+
+	if application.DockerImage == "" {
+		errs.add(field+".docker-image", "is required")
+	}
+
+	validatePort(errs, field+".ports.internal-docker", application.Ports.InternalDocker)
+	validatePort(errs, field+".ports.external-docker", application.Ports.ExternalDocker)
+
+	builders, needsBuilder := SupportedBuilders[application.Language]
+	if !needsBuilder {
+		return
+	}
+
+	if application.Builder.Name == "" {
+		errs.add(field+".builder.name", "is required for %s", application.Language)
+	} else if !contains(builders, application.Builder.Name) {
+		errs.add(field+".builder.name", "unsupported builder %q for %s, expected one of %s", application.Builder.Name, application.Language, strings.Join(builders, ", "))
+	}
+	if application.Builder.DockerImage == "" {
+		errs.add(field+".builder.docker-image", "is required for %s", application.Language)
+	}
+	if application.Artifact.Target == "" {
+		errs.add(field+".artifact.target", "is required for %s", application.Language)
+	}
+}
+
+func (config *Configuration) validateDatabase(errs *ValidationErrors) {
+	database := config.Artifact.Database
+	field := "artifact.database"
+
+	if database.Driver == "" {
+		errs.add(field+".driver", "is required")
+	}
+	if database.Name == "" {
+		errs.add(field+".name", "is required")
+	}
+
+	validatePort(errs, field+".ports.internal-docker", database.Ports.InternalDocker)
+	validatePort(errs, field+".ports.external-docker", database.Ports.ExternalDocker)
+
+	// application and database are published on the same host
+	if database.Ports.ExternalDocker != 0 && database.Ports.ExternalDocker == config.Artifact.Application.Ports.ExternalDocker {
+		errs.add(field+".ports.external-docker", "port %d is already used by artifact.application.ports.external-docker", database.Ports.ExternalDocker)
+	}
+}
+
+func (config *Configuration) validateServices(errs *ValidationErrors) {
+	field := "artifact.services"
+
+	names := map[string]bool{}
+	for _, service := range config.Artifact.Services {
+		names[service.Name] = true
+	}
+
+	seen := map[string]bool{}
+	published := map[int]string{}
+	for index, service := range config.Artifact.Services {
+		serviceField := fmt.Sprintf("%s[%d]", field, index)
+
+		if service.Name == "" {
+			errs.add(serviceField+".name", "is required")
+		} else if !serviceNamePattern.MatchString(service.Name) {
+			errs.add(serviceField+".name", "invalid service name %q, use letters, digits, '_', '.' and '-'", service.Name)
+		} else if seen[service.Name] {
+			errs.add(serviceField+".name", "duplicate service name %q", service.Name)
+		}
+		seen[service.Name] = true
+
+		if service.Image != "" && service.Build != "" {
+			errs.add(serviceField+".build", "cannot be set together with image")
+		} else if service.Image == "" && service.Build == "" && service.Role != ServiceRoleApplication {
+			errs.add(serviceField+".image", "image or build is required, unless the role is %s", ServiceRoleApplication)
+		}
+
+		for pIndex, port := range service.Ports {
+			portField := fmt.Sprintf("%s.ports[%d]", serviceField, pIndex)
+			validatePort(errs, portField+".internal-docker", port.InternalDocker)
+			if port.ExternalDocker == 0 {
+				continue
+			}
+
+			validatePort(errs, portField+".external-docker", port.ExternalDocker)
+			// every service is published on the same host
+			if owner, used := published[port.ExternalDocker]; used {
+				errs.add(portField+".external-docker", "port %d is already used by service %q", port.ExternalDocker, owner)
+			}
+			published[port.ExternalDocker] = service.Name
+		}
+
+		for dIndex, dependency := range service.DependsOn {
+			dependencyField := fmt.Sprintf("%s.depends-on[%d]", serviceField, dIndex)
+			if dependency == service.Name {
+				errs.add(dependencyField, "a service cannot depend on itself")
+			} else if !names[dependency] {
+				errs.add(dependencyField, "unknown service %q", dependency)
+			}
+		}
+
+		for name := range service.Env {
+			if name == "" {
+				errs.add(serviceField+".env", "variable names cannot be empty")
+			}
+		}
+	}
+}
+
+func (config *Configuration) validateCodeCheckout(errs *ValidationErrors) {
+	checkout := config.Artifact.CodeCheckout
+	field := "artifact.code-checkout"
+
+	if !checkout.Git.Enabled && !checkout.SCP.Enabled {
+		errs.add(field, "git or scp must be enabled")
+	}
+
+	// repo-user and repo-password may stay empty for public repositories
+	if checkout.Git.Enabled && checkout.Git.RepoURL == "" {
+		errs.add(field+".git.repo-url", "is required when git is enabled")
+	}
+
+	if checkout.SCP.Enabled && checkout.SCP.Configurations.Folder == "" {
+		errs.add(field+".scp.configurations.folder", "is required when scp is enabled")
+	}
+}
+
+func (config *Configuration) validateExtraCommands(errs *ValidationErrors) {
+	field := "artifact.extra-commands.commands"
+
+	for index, command := range config.Artifact.ExtraCommands.Commands.Before {
+		if command.Command == "" {
+			errs.add(fmt.Sprintf("%s.before[%d].command", field, index), "is required")
+		}
+	}
+	for index, command := range config.Artifact.ExtraCommands.Commands.After {
+		if command.Command == "" {
+			errs.add(fmt.Sprintf("%s.after[%d].command", field, index), "is required")
+		}
+	}
+}
+
+// buildsApplication reports whether the Dockerfile of the application section is used: always without
+// services, otherwise by the application services that have no image or build of their own
+func (config *Configuration) buildsApplication() bool {
+	if len(config.Artifact.Services) == 0 {
+		return true
+	}
+
+	for _, service := range config.Artifact.Services {
+		if service.Role == ServiceRoleApplication && service.Image == "" && service.Build == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validatePort(errs *ValidationErrors, field string, port int) {
+	if port < 1 || port > 65535 {
+		errs.add(field, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Events a webhook can subscribe to
+const (
+	EventServerDown      = "server.down"
+	EventServerUp        = "server.up"
+	EventDeploySucceeded = "deploy.succeeded"
+	EventDeployFailed    = "deploy.failed"
+	EventScriptCompleted = "script.completed"
+	EventCleanExecuted   = "clean.executed"
+	// EventWebhookTest is only sent by POST /appjet/webhooks/:id/test, whatever the webhook subscribed to
+	EventWebhookTest = "webhook.test"
+)
+
+// WebhookEvents are the events webhooks can subscribe to
+var WebhookEvents = []string{
+	EventServerDown, EventServerUp,
+	EventDeploySucceeded, EventDeployFailed,
+	EventScriptCompleted, EventCleanExecuted,
+}
+
+// Formats of the webhook payloads: the signed JSON event, or a message for chat webhooks
+const (
+	WebhookFormatJSON    = "json"
+	WebhookFormatSlack   = "slack"   // {"text": ...}, also understood by Mattermost and Rocket.Chat
+	WebhookFormatDiscord = "discord" // {"content": ...}
+)
+
+// WebhookFormats are the payload formats of the webhooks
+var WebhookFormats = []string{WebhookFormatJSON, WebhookFormatSlack, WebhookFormatDiscord}
+
+// Statuses of a webhook delivery
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an outbound HTTP endpoint notified of the fleet events it subscribed to. Payloads are
+// signed with the secret, which is shown once when the webhook is created.
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	URL       string    `gorm:"size:1024;not null" json:"url"`
+	Events    string    `gorm:"size:255;not null" json:"-"` // comma separated WebhookEvents
+	Format    string    `gorm:"size:20;not null" json:"format"`
+	Secret    string    `gorm:"size:128;not null" json:"-"`
+	CreatedBy string    `gorm:"size:100;not null" json:"created-by"`
+	CreatedAt time.Time `json:"created-at"`
+}
+
+// EventList returns the events the webhook subscribed to
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return nil
+	}
+
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes reports whether the webhook subscribed to the event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, subscribed := range w.EventList() {
+		if subscribed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookDelivery records the delivery of an event to a webhook, with its attempts
+type WebhookDelivery struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	WebhookID   uint       `gorm:"not null;index" json:"webhook-id"`
+	DeliveryID  string     `gorm:"size:36;not null;uniqueIndex" json:"delivery-id"`
+	Event       string     `gorm:"size:50;not null;index" json:"event"`
+	Payload     string     `gorm:"type:text" json:"payload"`
+	Status      string     `gorm:"size:20;not null;index" json:"status"`
+	Attempts    int        `json:"attempts"`
+	StatusCode  int        `json:"status-code,omitempty"` // of the last attempt
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt   time.Time  `gorm:"index" json:"created-at"`
+	DeliveredAt *time.Time `json:"delivered-at,omitempty"`
+}
+
+// WebhookEvent is the JSON payload of the webhooks in the json format
+type WebhookEvent struct {
+	ID        string      `json:"id"` // the delivery id, the same for every attempt
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created-at"`
+	Data      interface{} `json:"data"`
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultAPITokenLifetime is used when a token is created without an expiry
+const defaultAPITokenLifetime = 90 * 24 * time.Hour
+
+var (
+	// ErrAPITokenNotFound is returned for unknown or revoked API tokens
+	ErrAPITokenNotFound = errors.New("api token not found")
+	// ErrAPITokenExpired is returned for API tokens past their expiry
+	ErrAPITokenExpired = errors.New("api token expired")
+	// ErrInvalidAPITokenRequest wraps the problems found in an APITokenRequest
+	ErrInvalidAPITokenRequest = errors.New("invalid api token request")
+)
+
+// APITokenRequest describes a token to create
+type APITokenRequest struct {
+	Name    string   `json:"name" binding:"required"`
+	Scopes  []string `json:"scopes" binding:"required"`
+	Expires string   `json:"expires"` // "90d", a duration such as "720h", or "never"; 90d when empty
+}
+
+// IsAPIToken tells API token secrets apart from session tokens
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, models.APITokenPrefix)
+}
+
+// CreateAPIToken mints a token for the user and returns it with its secret. The secret is not
+// stored and cannot be read again.
+func CreateAPIToken(userID uint, request APITokenRequest) (*models.APIToken, string, error) {
+	if err := validateScopes(request.Scopes); err != nil {
+		return nil, "", err
+	}
+
+	lifetime, err := parseTokenLifetime(request.Expires)
+	if err != nil {
+		return nil, "", err
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("error generating api token: %w", err)
+	}
+	secret := models.APITokenPrefix + hex.EncodeToString(random)
+
+	token := models.APIToken{
+		UserID: userID,
+		Name:   request.Name,
+		Prefix: secret[:12],
+		Hash:   hashSecret(secret),
+		Scopes: strings.Join(request.Scopes, ","),
+	}
+	if lifetime > 0 {
+		expiresAt := time.Now().Add(lifetime)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := GetDBConnection().Create(&token).Error; err != nil {
+		return nil, "", fmt.Errorf("error persisting api token: %w", err)
+	}
+
+	return &token, secret, nil
+}
+
+// ValidateAPIToken returns the token matching the secret and its user
+func ValidateAPIToken(secret string) (*models.APIToken, *models.User, error) {
+	var token models.APIToken
+	result := GetDBConnection().Where("hash = ?", hashSecret(secret)).Limit(1).Find(&token)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrAPITokenNotFound
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, nil, ErrAPITokenExpired
+	}
+
+	var user models.User
+	result = GetDBConnection().Unscoped().Where("id = ?", token.UserID).Limit(1).Find(&user)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrAPITokenNotFound
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastSeenResolution {
+		token.LastUsedAt = &now
+		if err := GetDBConnection().Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+			log.Printf("Error updating api token %d: %s", token.ID, err)
+		}
+	}
+
+	return &token, &user, nil
+}
+
+// ListAPITokens returns the tokens of a user, most recent first
+func ListAPITokens(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	if err := GetDBConnection().Where("user_id = ?", userID).Order("created_at desc").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetAPIToken returns a token by id
+func GetAPIToken(id uint) (*models.APIToken, error) {
+	var token models.APIToken
+	result := GetDBConnection().Where("id = ?", id).Limit(1).Find(&token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAPITokenNotFound
+	}
+
+	return &token, nil
+}
+
+// RevokeAPIToken deletes a token, it stops working right away
+func RevokeAPIToken(id uint) error {
+	return GetDBConnection().Delete(&models.APIToken{}, id).Error
+}
+
+// hashSecret returns the hex sha256 of a token secret, the only form tokens are stored in
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidAPITokenRequest)
+	}
+
+	for _, scope := range scopes {
+		known := false
+		for _, candidate := range models.APITokenScopes {
+			if scope == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: unknown scope %q, expected some of %s", ErrInvalidAPITokenRequest, scope, strings.Join(models.APITokenScopes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// parseTokenLifetime reads "90d", a Go duration such as "720h", or "never" (returned as 0)
+func parseTokenLifetime(expires string) (time.Duration, error) {
+	switch expires {
+	case "":
+		return defaultAPITokenLifetime, nil
+	case "never":
+		return 0, nil
+	}
+
+	if lifetime, ok := parseDuration(expires); ok {
+		return lifetime, nil
+	}
+
+	return 0, fmt.Errorf("%w: invalid expiry %q, use a number of days such as \"90d\", a duration such as \"720h\" or \"never\"", ErrInvalidAPITokenRequest, expires)
+}
+
+// parseDuration reads a positive number of days such as "90d" or a Go duration such as "720h"
+func parseDuration(value string) (time.Duration, bool) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		return time.Duration(days) * 24 * time.Hour, err == nil && days > 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	return duration, err == nil && duration > 0
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// errExportLimit stops an export once the requested number of events was written
+var errExportLimit = errors.New("audit export limit reached")
+
+// AuditQuery filters the audit events, empty fields match everything
+type AuditQuery struct {
+	Since   time.Time
+	Until   time.Time
+	User    string
+	Project string
+	Action  string
+	Cluster string
+	Server  string
+	Outcome string
+	Limit   int
+}
+
+// RecordAuditEvent stores an audit event. Events of jobs that already finished are completed right away.
+func RecordAuditEvent(event *models.AuditEvent) error {
+	if err := GetDBConnection().Create(event).Error; err != nil {
+		return fmt.Errorf("error persisting audit event: %w", err)
+	}
+
+	// the job may have finished before the event was stored
+	if event.JobID != "" {
+		completeJobAuditEvents(event.JobID)
+	}
+
+	return nil
+}
+
+// ListAuditEvents returns the matching audit events, most recent first
+func ListAuditEvents(query AuditQuery) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	if err := auditQuery(query).Order("created_at desc").Limit(query.Limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// ExportAuditEvents calls write for every matching audit event, oldest first, without loading them all
+func ExportAuditEvents(query AuditQuery, write func(event *models.AuditEvent) error) error {
+	var batch []models.AuditEvent
+	exported := 0
+
+	result := auditQuery(query).FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for index := range batch {
+			if query.Limit > 0 && exported == query.Limit {
+				return errExportLimit
+			}
+			if err := write(&batch[index]); err != nil {
+				return err
+			}
+			exported++
+		}
+		return nil
+	})
+	if errors.Is(result.Error, errExportLimit) {
+		return nil
+	}
+
+	return result.Error
+}
+
+// NewAuditServerResults summarizes a fan-out response for the audit log
+func NewAuditServerResults(response models.FanOutResponse) []models.AuditServerResult {
+	results := make([]models.AuditServerResult, 0, len(response.Results))
+	for _, result := range response.Results {
+		results = append(results, models.AuditServerResult{
+			Cluster: result.Cluster,
+			Server:  result.Server,
+			Status:  result.Status,
+			Error:   result.Error,
+		})
+	}
+
+	return results
+}
+
+// ParseAuditTime reads an absolute time (RFC 3339, e.g. "2024-01-31T08:00:00Z") or an age relative to
+// now such as "24h" or "7d"
+func ParseAuditTime(value string) (time.Time, error) {
+	if age, ok := parseDuration(value); ok {
+		return time.Now().Add(-age), nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use an age such as \"24h\" or \"7d\", or an RFC 3339 time", value)
+	}
+
+	return parsed, nil
+}
+
+func auditQuery(query AuditQuery) *gorm.DB {
+	tx := GetDBConnection().Model(&models.AuditEvent{})
+	if !query.Since.IsZero() {
+		tx = tx.Where("created_at >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		tx = tx.Where("created_at <= ?", query.Until)
+	}
+	if query.User != "" {
+		tx = tx.Where("username = ?", query.User)
+	}
+	if query.Project != "" {
+		tx = tx.Where("project = ?", query.Project)
+	}
+	if query.Action != "" {
+		tx = tx.Where("action = ?", query.Action)
+	}
+	if query.Cluster != "" {
+		tx = tx.Where("cluster = ?", query.Cluster)
+	}
+	if query.Server != "" {
+		tx = tx.Where("server = ?", query.Server)
+	}
+	if query.Outcome != "" {
+		tx = tx.Where("outcome = ?", query.Outcome)
+	}
+
+	return tx
+}
+
+// completeJobAuditEvents copies the outcome and per-server results of a finished job to its audit events
+func completeJobAuditEvents(jobID string) {
+	job, err := GetJob(jobID)
+	if err != nil {
+		log.Printf("Error loading job %s for the audit log: %s", jobID, err)
+		return
+	}
+	if job.FinishedAt == nil {
+		return
+	}
+
+	results := jobServerResults(job)
+
+	outcome := job.Outcome
+	if outcome == "" {
+		outcome = models.OutcomeFailed
+	}
+
+	err = GetDBConnection().Model(&models.AuditEvent{}).
+		Where("job_id = ?", jobID).
+		Updates(models.AuditEvent{
+			Outcome:    outcome,
+			Results:    results,
+			DurationMs: job.FinishedAt.Sub(job.CreatedAt).Milliseconds(),
+		}).Error
+	if err != nil {
+		log.Printf("Error completing the audit events of job %s: %s", jobID, err)
+	}
+}
+
+// jobServerResults summarizes the tasks of a job, one result per server
+func jobServerResults(job *models.Job) []models.AuditServerResult {
+	results := make([]models.AuditServerResult, 0, len(job.Tasks))
+	for _, task := range job.Tasks {
+		status := task.Result
+		if status == "" {
+			status = task.Status
+		}
+		results = append(results, models.AuditServerResult{
+			Cluster: task.Cluster,
+			Server:  task.Server,
+			Status:  status,
+			Error:   task.Error,
+		})
+	}
+
+	return results
+}
@@ -3,53 +3,257 @@ package services
 import (
 	"appjet-decision-manager/app/models"
 	"encoding/json"
-	"github.com/gin-gonic/gin"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
+	"sync"
 )
 
-func GenerateConfigIfNotExist(c *gin.Context) *models.Configuration {
-	// Check if config.json file exists
-	if _, err := os.Stat("config.json"); os.IsNotExist(err) {
-		// File doesn't exist, generate it with the provided configuration
-		var config models.Configuration
-		shouldBindJSON(c, &config)
-		generateConfigFile(&config, c)
-		return &config
-	} else {
-		// File exists, read its contents and unmarshal into the config variable
+// configFile is where the accepted configuration of the default project is persisted on the decision
+// manager host. The other projects keep theirs in the database.
+const configFile = "config.json"
+
+// ErrNoConfig is returned when no configuration was stored yet
+var ErrNoConfig = errors.New("no configuration stored yet")
+
+// ErrConfigNotFound is returned when a patch targets a cluster or server that does not exist
+var ErrConfigNotFound = errors.New("cluster or server not found in the configuration")
+
+// The configuration in use by each project, nil until one is stored. A configuration is never modified
+// in place: updates build a copy, validate it and swap it in, so jobs that already hold the previous
+// configuration keep a consistent view.
+var (
+	configMutex    sync.RWMutex
+	projectConfigs = map[string]*models.Configuration{}
+)
+
+// LoadConfig reads the configuration of every project into memory, config.json for the default project.
+// A missing configuration is not an error, the first configure or PUT /appjet/config will create it.
+func LoadConfig() error {
+	projects, err := ListProjects()
+	if err != nil {
+		return fmt.Errorf("failed to load the projects: %w", err)
+	}
+
+	configs := map[string]*models.Configuration{}
+	for _, project := range projects {
+		config, err := loadProjectConfig(project)
+		if err != nil {
+			return err
+		}
+
+		// a configuration stored before validation existed is still loaded, so it can be fixed through the api
+		if config != nil {
+			if errs := config.Validate(); len(errs) > 0 {
+				log.Printf("Warning: the configuration of project %s is not valid, fix it with PUT /appjet/config: %s", project.Name, errs)
+			}
+		}
+		configs[project.Name] = config
+	}
+
+	configMutex.Lock()
+	projectConfigs = configs
+	configMutex.Unlock()
+
+	return nil
+}
+
+// loadProjectConfig reads the stored configuration of a project, nil when there is none
+func loadProjectConfig(project models.Project) (*models.Configuration, error) {
+	if project.Name == models.DefaultProject {
 		config, err := readConfigFile()
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to load config.json"})
+			return nil, fmt.Errorf("failed to load %s: %w", configFile, err)
 		}
-		return config
+		return config, nil
+	}
+
+	if project.Config == "" {
+		return nil, nil
+	}
+
+	var config models.Configuration
+	if err := json.Unmarshal([]byte(project.Config), &config); err != nil {
+		return nil, fmt.Errorf("failed to load the configuration of project %s: %w", project.Name, err)
+	}
+
+	return &config, nil
+}
+
+// CurrentConfig returns the configuration in use by a project. Callers must not modify it.
+func CurrentConfig(project string) (*models.Configuration, error) {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	config, ok := projectConfigs[project]
+	if !ok {
+		return nil, ErrProjectNotFound
+	}
+	if config == nil {
+		return nil, ErrNoConfig
 	}
+
+	return config, nil
 }
 
-func shouldBindJSON(c *gin.Context, config *models.Configuration) {
-	if err := c.ShouldBindJSON(&config); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
+// projectConfigsSnapshot returns the configuration in use by every project, nil for the projects without one
+func projectConfigsSnapshot() map[string]*models.Configuration {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	configs := make(map[string]*models.Configuration, len(projectConfigs))
+	for project, config := range projectConfigs {
+		configs[project] = config
 	}
+
+	return configs
 }
 
-func generateConfigFile(config *models.Configuration, c *gin.Context) {
-	configJSON, err := json.MarshalIndent(config, "", "  ")
+// ReplaceConfig validates the configuration of a project, records it as a new revision, stores it (in
+// config.json for the default project) and reloads it in memory, without restarting the decision manager.
+func ReplaceConfig(project string, config *models.Configuration, author string, cluster string, server string, comment string) (*models.ConfigRevision, error) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	if _, ok := projectConfigs[project]; !ok {
+		return nil, ErrProjectNotFound
+	}
+
+	return replaceConfigLocked(project, config, author, cluster, server, comment)
+}
+
+// PatchClusterConfig merges patch (a partial cluster JSON object) into a cluster of the configuration of a project.
+func PatchClusterConfig(project string, cluster string, patch []byte, author string) (*models.ConfigRevision, error) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	config, err := copyCurrentConfigLocked(project)
+	if err != nil {
+		return nil, err
+	}
+
+	for cIndex := range config.Clusters {
+		if config.Clusters[cIndex].Name == cluster {
+			if err := json.Unmarshal(patch, &config.Clusters[cIndex]); err != nil {
+				return nil, patchError(fmt.Sprintf("clusters[%d]", cIndex), err)
+			}
+			return replaceConfigLocked(project, config, author, cluster, "", "patch cluster "+cluster)
+		}
+	}
+
+	return nil, ErrConfigNotFound
+}
+
+// PatchServerConfig merges patch (a partial server JSON object) into a server of a cluster of a project.
+func PatchServerConfig(project string, cluster string, server string, patch []byte, author string) (*models.ConfigRevision, error) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	config, err := copyCurrentConfigLocked(project)
+	if err != nil {
+		return nil, err
+	}
+
+	for cIndex := range config.Clusters {
+		if config.Clusters[cIndex].Name != cluster {
+			continue
+		}
+		for sIndex := range config.Clusters[cIndex].Servers {
+			if config.Clusters[cIndex].Servers[sIndex].Name == server {
+				if err := json.Unmarshal(patch, &config.Clusters[cIndex].Servers[sIndex]); err != nil {
+					return nil, patchError(fmt.Sprintf("clusters[%d].servers[%d]", cIndex, sIndex), err)
+				}
+				return replaceConfigLocked(project, config, author, cluster, server, "patch server "+cluster+"/"+server)
+			}
+		}
+	}
+
+	return nil, ErrConfigNotFound
+}
+
+func replaceConfigLocked(project string, config *models.Configuration, author string, cluster string, server string, comment string) (*models.ConfigRevision, error) {
+	if errs := config.Validate(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	revision, err := RecordConfigRevision(project, config, author, cluster, server, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeProjectConfig(project, config); err != nil {
+		return nil, err
+	}
+
+	projectConfigs[project] = config
+
+	return revision, nil
+}
+
+func storeProjectConfig(project string, config *models.Configuration) error {
+	if project == models.DefaultProject {
+		if err := writeConfigFile(config); err != nil {
+			return fmt.Errorf("failed to write %s: %w", configFile, err)
+		}
+		return nil
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	err = GetDBConnection().Model(&models.Project{}).Where("name = ?", project).Update("config", string(configJSON)).Error
+	if err != nil {
+		return fmt.Errorf("failed to store the configuration of project %s: %w", project, err)
+	}
+
+	return nil
+}
+
+// patchError reports a patch that does not match the configuration schema
+func patchError(field string, err error) models.ValidationErrors {
+	return models.ValidationErrors{{Field: field, Message: err.Error()}}
+}
+
+// copyCurrentConfigLocked returns a deep copy of the configuration in use by a project
+func copyCurrentConfigLocked(project string) (*models.Configuration, error) {
+	currentConfig, ok := projectConfigs[project]
+	if !ok {
+		return nil, ErrProjectNotFound
+	}
+	if currentConfig == nil {
+		return nil, ErrNoConfig
+	}
+
+	configJSON, err := json.Marshal(currentConfig)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal JSON"})
-		return
+		return nil, err
+	}
+
+	var config models.Configuration
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, err
 	}
 
-	err = ioutil.WriteFile("config.json", configJSON, 0644)
+	return &config, nil
+}
+
+func writeConfigFile(config *models.Configuration) error {
+	configJSON, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to write JSON to file"})
-		return
+		return err
 	}
 
+	return ioutil.WriteFile(configFile, configJSON, 0644)
 }
 
 func readConfigFile() (*models.Configuration, error) {
-	file, err := os.Open("config.json")
+	file, err := os.Open(configFile)
 	if err != nil {
 		return nil, err
 	}
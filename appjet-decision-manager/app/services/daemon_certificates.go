@@ -0,0 +1,215 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"net"
+	"time"
+)
+
+var (
+	// ErrDaemonCertificateNotFound is returned for servers that were not enrolled
+	ErrDaemonCertificateNotFound = errors.New("daemon certificate not found")
+	// ErrInvalidCSR is returned for certificate requests that cannot be signed
+	ErrInvalidCSR = errors.New("invalid certificate request")
+	// ErrServerNotFound is returned when the server is not in the configuration of any project
+	ErrServerNotFound = errors.New("server not found in the configuration")
+)
+
+// EnrolledDaemon is what a daemon needs to serve TLS once enrolled
+type EnrolledDaemon struct {
+	Certificate string    `json:"certificate"`
+	CA          string    `json:"ca"`
+	Fingerprint string    `json:"fingerprint"`
+	NotAfter    time.Time `json:"not-after"`
+}
+
+// EnrollDaemon signs the CSR of a daemon and pins the issued certificate. The address of the server
+// in the configuration is always part of the certificate, next to the names requested in the CSR.
+// A daemon serves every project deploying to it, the server can be in the configuration of any of them.
+func EnrollDaemon(cluster string, server string, csrPEM string) (*EnrolledDaemon, error) {
+	target, ok := findServerTarget(cluster, server)
+	if !ok {
+		return nil, ErrServerNotFound
+	}
+
+	return issueDaemonCertificate(cluster, server, target.IP, csrPEM)
+}
+
+// issueDaemonCertificate signs the CSR of the daemon of a server reached at host and pins the certificate
+func issueDaemonCertificate(cluster string, server string, host string, csrPEM string) (*EnrolledDaemon, error) {
+	certificate, certificatePEM, err := signDaemonCertificate(cluster, server, host, csrPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return pinDaemonCertificate(GetDBConnection(), cluster, server, certificate, certificatePEM)
+}
+
+// signDaemonCertificate signs the CSR of the daemon of a server reached at host, without pinning it
+func signDaemonCertificate(cluster string, server string, host string, csrPEM string) (*x509.Certificate, []byte, error) {
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, nil, fmt.Errorf("%w: expected a PEM \"CERTIFICATE REQUEST\"", ErrInvalidCSR)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidCSR, err)
+	}
+
+	addHostToCSR(csr, host)
+
+	authority, err := GetCertificateAuthority()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return authority.IssueServerCertificate(csr, cluster+"/"+server)
+}
+
+// pinDaemonCertificate stores the certificate of a server with tx, a transaction or the connection,
+// so only this certificate is accepted from its daemon
+func pinDaemonCertificate(tx *gorm.DB, cluster string, server string, certificate *x509.Certificate, certificatePEM []byte) (*EnrolledDaemon, error) {
+	authority, err := GetCertificateAuthority()
+	if err != nil {
+		return nil, err
+	}
+
+	var pinned models.DaemonCertificate
+	result := tx.Where("cluster = ? AND server = ?", cluster, server).Limit(1).Find(&pinned)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	pinned.Cluster = cluster
+	pinned.Server = server
+	pinned.Fingerprint = certificateFingerprint(certificate.Raw)
+	pinned.SerialNumber = certificate.SerialNumber.Text(16)
+	pinned.NotAfter = certificate.NotAfter
+	pinned.Certificate = string(certificatePEM)
+	if err := tx.Save(&pinned).Error; err != nil {
+		return nil, fmt.Errorf("error persisting daemon certificate: %w", err)
+	}
+
+	// connections opened with the previous certificate are not reused
+	daemonTransport.CloseIdleConnections()
+
+	return &EnrolledDaemon{
+		Certificate: string(certificatePEM),
+		CA:          string(authority.CertPEM),
+		Fingerprint: pinned.Fingerprint,
+		NotAfter:    pinned.NotAfter,
+	}, nil
+}
+
+// ListDaemonCertificates returns the pinned daemon certificates
+func ListDaemonCertificates() ([]models.DaemonCertificate, error) {
+	var certificates []models.DaemonCertificate
+	if err := GetDBConnection().Order("cluster, server").Find(&certificates).Error; err != nil {
+		return nil, err
+	}
+
+	return certificates, nil
+}
+
+// DeleteDaemonCertificate unpins the certificate of a server, calls to it fail until it is enrolled again
+func DeleteDaemonCertificate(cluster string, server string) error {
+	result := GetDBConnection().Where("cluster = ? AND server = ?", cluster, server).Delete(&models.DaemonCertificate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDaemonCertificateNotFound
+	}
+
+	daemonTransport.CloseIdleConnections()
+
+	return nil
+}
+
+// dialDaemonTLS opens the TLS connection of a daemon call: the daemon must present the certificate
+// pinned for the server of the call, and the decision manager presents its client certificate
+func dialDaemonTLS(ctx context.Context, network string, addr string) (net.Conn, error) {
+	target, ok := ctx.Value(daemonTargetKey{}).(DaemonTarget)
+	if !ok {
+		return nil, errors.New("daemon call without a target, its certificate cannot be checked")
+	}
+
+	fingerprint, err := pinnedFingerprint(target)
+	if err != nil {
+		return nil, err
+	}
+
+	authority, err := GetCertificateAuthority()
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      authority.CertPool(),
+		ServerName:   host,
+		Certificates: []tls.Certificate{authority.client},
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 || certificateFingerprint(state.PeerCertificates[0].Raw) != fingerprint {
+				return fmt.Errorf("the daemon of server %s of cluster %s does not present its enrolled certificate", target.Server, target.Cluster)
+			}
+			return nil
+		},
+	}}
+
+	return dialer.DialContext(ctx, network, addr)
+}
+
+func pinnedFingerprint(target DaemonTarget) (string, error) {
+	var pinned models.DaemonCertificate
+	result := GetDBConnection().Where("cluster = ? AND server = ?", target.Cluster, target.Server).Limit(1).Find(&pinned)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", fmt.Errorf("%w for server %s of cluster %s, enroll it with ./appjet certs enroll %s %s", ErrDaemonCertificateNotFound, target.Server, target.Cluster, target.Cluster, target.Server)
+	}
+
+	return pinned.Fingerprint, nil
+}
+
+// addHostToCSR adds the address of the server to the names of the CSR
+func addHostToCSR(csr *x509.CertificateRequest, host string) {
+	if ip := net.ParseIP(host); ip != nil {
+		for _, existing := range csr.IPAddresses {
+			if existing.Equal(ip) {
+				return
+			}
+		}
+		csr.IPAddresses = append(csr.IPAddresses, ip)
+		return
+	}
+
+	for _, existing := range csr.DNSNames {
+		if existing == host {
+			return
+		}
+	}
+	csr.DNSNames = append(csr.DNSNames, host)
+}
+
+func certificateFingerprint(der []byte) string {
+	hash := sha256.Sum256(der)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of a signed daemon call. The signature is an HMAC-SHA256, keyed by the secret of the
+// server, over the method, the path with its query, the timestamp, the nonce and the body hash.
+const (
+	signatureHeader     = "X-Appjet-Signature"
+	timestampHeader     = "X-Appjet-Timestamp"
+	nonceHeader         = "X-Appjet-Nonce"
+	contentSHA256Header = "X-Appjet-Content-SHA256"
+	// calls made by a daemon name its cluster and server, whose secret signs the call
+	clusterHeader = "X-Appjet-Cluster"
+	serverHeader  = "X-Appjet-Server"
+)
+
+// maxClockSkew is how far the timestamp of a call signed by a daemon can be from the clock of the decision
+// manager. Nonces are remembered for twice as long, so a captured call cannot be replayed.
+const maxClockSkew = 5 * time.Minute
+
+// minDaemonSecretLength is the length of the secrets generated by the daemons (32 random bytes in hex)
+const minDaemonSecretLength = 64
+
+var (
+	// ErrDaemonSecretNotFound is returned for servers without a registered secret
+	ErrDaemonSecretNotFound = errors.New("daemon secret not found")
+	// ErrInvalidDaemonSignature is returned for daemon calls that are not signed with the secret of the server
+	ErrInvalidDaemonSignature = errors.New("invalid daemon signature")
+	// ErrInvalidDaemonSecret is returned for secrets that were not generated by a daemon
+	ErrInvalidDaemonSecret = fmt.Errorf("invalid daemon secret, expected the %d characters printed by \"appjet-server-daemon secret generate\"", minDaemonSecretLength)
+)
+
+var (
+	nonceMutex sync.Mutex
+	seenNonces = map[string]time.Time{}
+)
+
+// daemonTargetKey stores the DaemonTarget of a call in its context, so the forwarder knows which
+// secret to sign the call with
+type daemonTargetKey struct{}
+
+func withDaemonTarget(ctx context.Context, target DaemonTarget) context.Context {
+	return context.WithValue(ctx, daemonTargetKey{}, target)
+}
+
+// SetDaemonSecret registers the secret of a server, replacing the previous one
+func SetDaemonSecret(cluster string, server string, secret string) error {
+	return saveDaemonSecret(GetDBConnection(), cluster, server, secret)
+}
+
+// saveDaemonSecret stores the secret of a server with tx, a transaction or the connection
+func saveDaemonSecret(tx *gorm.DB, cluster string, server string, secret string) error {
+	if len(secret) < minDaemonSecretLength {
+		return ErrInvalidDaemonSecret
+	}
+	if _, err := hex.DecodeString(secret); err != nil {
+		return ErrInvalidDaemonSecret
+	}
+
+	var existing models.DaemonSecret
+	result := tx.Where("cluster = ? AND server = ?", cluster, server).Limit(1).Find(&existing)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	existing.Cluster = cluster
+	existing.Server = server
+	existing.Secret = secret
+	if err := tx.Save(&existing).Error; err != nil {
+		return fmt.Errorf("error persisting daemon secret: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDaemonSecret removes the secret of a server, calls to it fail until a new one is registered
+func DeleteDaemonSecret(cluster string, server string) error {
+	result := GetDBConnection().Where("cluster = ? AND server = ?", cluster, server).Delete(&models.DaemonSecret{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDaemonSecretNotFound
+	}
+
+	return nil
+}
+
+// ListDaemonSecrets returns the servers with a registered secret, without the secrets
+func ListDaemonSecrets() ([]models.DaemonSecret, error) {
+	var secrets []models.DaemonSecret
+	if err := GetDBConnection().Order("cluster, server").Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func daemonSecret(target DaemonTarget) (string, error) {
+	var secret models.DaemonSecret
+	result := GetDBConnection().Where("cluster = ? AND server = ?", target.Cluster, target.Server).Limit(1).Find(&secret)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", fmt.Errorf("%w for server %s of cluster %s, register it with ./appjet secrets set %s %s", ErrDaemonSecretNotFound, target.Server, target.Cluster, target.Cluster, target.Server)
+	}
+
+	return secret.Secret, nil
+}
+
+// signDaemonRequest signs a request with the secret of the server it is sent to
+func signDaemonRequest(request *http.Request) error {
+	target, ok := request.Context().Value(daemonTargetKey{}).(DaemonTarget)
+	if !ok {
+		return errors.New("daemon call without a target, it cannot be signed")
+	}
+
+	secret, err := daemonSecret(target)
+	if err != nil {
+		return err
+	}
+
+	body := []byte{}
+	if request.GetBody != nil {
+		reader, err := request.GetBody()
+		if err != nil {
+			return err
+		}
+		if body, err = io.ReadAll(reader); err != nil {
+			return err
+		}
+	} else if request.Body != nil && request.Body != http.NoBody {
+		if body, err = io.ReadAll(request.Body); err != nil {
+			return err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	// the daemon works in the workspace of the project, the parameter is covered by the signature
+	if target.Project != "" && target.Project != models.DefaultProject {
+		query := request.URL.Query()
+		query.Set("project", target.Project)
+		request.URL.RawQuery = query.Encode()
+	}
+
+	request.Header.Set(timestampHeader, timestamp)
+	request.Header.Set(nonceHeader, hex.EncodeToString(nonce))
+	request.Header.Set(contentSHA256Header, hashBody(body))
+	// the daemon checks the path it receives, after a reverse proxy removed the base path
+	path := strings.TrimPrefix(request.URL.RequestURI(), target.BasePath)
+	request.Header.Set(signatureHeader, requestSignature(secret, request.Method, path, timestamp, request.Header.Get(nonceHeader), request.Header.Get(contentSHA256Header)))
+
+	return nil
+}
+
+// VerifyDaemonRequest checks a call made by the daemon of a server, signed like the calls the decision
+// manager makes to the daemons, and returns the cluster and server of the daemon. body must be the body
+// actually received.
+func VerifyDaemonRequest(request *http.Request, body []byte) (string, string, error) {
+	cluster, server := request.Header.Get(clusterHeader), request.Header.Get(serverHeader)
+	timestamp, nonce := request.Header.Get(timestampHeader), request.Header.Get(nonceHeader)
+
+	bodyHash := hashBody(body)
+	if !hmac.Equal([]byte(bodyHash), []byte(request.Header.Get(contentSHA256Header))) {
+		return "", "", fmt.Errorf("%w: the body does not match the signed hash", ErrInvalidDaemonSignature)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: missing or invalid timestamp", ErrInvalidDaemonSignature)
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return "", "", fmt.Errorf("%w: timestamp is more than %s away from the decision manager clock", ErrInvalidDaemonSignature, maxClockSkew)
+	}
+	if nonce == "" {
+		return "", "", fmt.Errorf("%w: missing nonce", ErrInvalidDaemonSignature)
+	}
+
+	secret, err := daemonSecret(DaemonTarget{Cluster: cluster, Server: server})
+	if errors.Is(err, ErrDaemonSecretNotFound) {
+		return "", "", fmt.Errorf("%w: unknown server", ErrInvalidDaemonSignature)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	expected := requestSignature(secret, request.Method, request.URL.RequestURI(), timestamp, nonce, bodyHash)
+	if !hmac.Equal([]byte(request.Header.Get(signatureHeader)), []byte(expected)) {
+		return "", "", ErrInvalidDaemonSignature
+	}
+
+	if err := useNonce(cluster + "/" + server + "/" + nonce); err != nil {
+		return "", "", err
+	}
+
+	return cluster, server, nil
+}
+
+// hashBody returns the hex sha256 of a request body, as sent in X-Appjet-Content-SHA256
+func hashBody(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+// generateDaemonSecret returns a secret like the ones generated by the daemons
+func generateDaemonSecret() (string, error) {
+	random := make([]byte, minDaemonSecretLength/2)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("error generating daemon secret: %w", err)
+	}
+
+	return hex.EncodeToString(random), nil
+}
+
+// useNonce rejects nonces seen within the replay window and forgets the older ones
+func useNonce(nonce string) error {
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	now := time.Now()
+	for seen, at := range seenNonces {
+		if now.Sub(at) > 2*maxClockSkew {
+			delete(seenNonces, seen)
+		}
+	}
+
+	if _, seen := seenNonces[nonce]; seen {
+		return fmt.Errorf("%w: nonce already used", ErrInvalidDaemonSignature)
+	}
+	seenNonces[nonce] = now
+
+	return nil
+}
+
+// requestSignature must match the signature computed by the daemons
+func requestSignature(secret string, method string, path string, timestamp string, nonce string, bodyHash string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n" + bodyHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"appjet-decision-manager/app/models"
 	"fmt"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -74,6 +75,57 @@ func CreateDbConnection() (*gorm.DB, error) {
 	return db, nil
 }
 
+// MigrateDb creates or updates the tables owned by gorm models (users are seeded by init.sql)
+func MigrateDb() error {
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	err := db.AutoMigrate(&models.Job{}, &models.JobTask{}, &models.ConfigRevision{}, &models.APIToken{}, &models.AuditEvent{}, &models.DaemonSecret{}, &models.DaemonCertificate{},
+		&models.Server{}, &models.JoinToken{}, &models.HealthTransition{},
+		&models.Webhook{}, &models.WebhookDelivery{}, &models.Project{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate the database: %w", err)
+	}
+
+	// databases seeded by an older init.sql lack the column, and the seeded root still has its default password
+	if !db.Migrator().HasColumn(&models.User{}, "MustChangePassword") {
+		if err := db.Migrator().AddColumn(&models.User{}, "MustChangePassword"); err != nil {
+			return fmt.Errorf("failed to migrate the users table: %w", err)
+		}
+
+		err = db.Model(&models.User{}).Unscoped().
+			Where("username = ? AND password = ?", "root", "root").
+			UpdateColumn("must_change_password", true).Error
+		if err != nil {
+			return fmt.Errorf("failed to migrate the users table: %w", err)
+		}
+	}
+
+	// sessions of databases seeded by an older init.sql never expire, they are dropped so users log in again
+	if !db.Migrator().HasColumn(&models.UserSession{}, "ExpiresAt") {
+		if err := db.Exec("DELETE FROM user_sessions").Error; err != nil {
+			return fmt.Errorf("failed to migrate the user_sessions table: %w", err)
+		}
+	}
+	for _, column := range []string{"LastSeenAt", "ExpiresAt", "ClientIP", "UserAgent"} {
+		if db.Migrator().HasColumn(&models.UserSession{}, column) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.UserSession{}, column); err != nil {
+			return fmt.Errorf("failed to migrate the user_sessions table: %w", err)
+		}
+	}
+
+	// the routes without /projects/:project work on the default project, it always exists
+	defaultProject := models.Project{Name: models.DefaultProject, CreatedBy: "root"}
+	if err := db.Where("name = ?", models.DefaultProject).FirstOrCreate(&defaultProject).Error; err != nil {
+		return fmt.Errorf("failed to create the default project: %w", err)
+	}
+
+	return nil
+}
+
 // CloseDbConnection closes the database connection
 func CloseDbConnection() error {
 	if db == nil {
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+)
+
+// useTestDatabase replaces the database of the package with an in-memory SQLite database holding the
+// tables of the given models, for the duration of the test
+func useTestDatabase(t *testing.T, tables ...interface{}) {
+	t.Helper()
+
+	testDB, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("opening the test database: %s", err)
+	}
+	// every connection to :memory: is a new database, the test keeps to one
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := testDB.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrating the test database: %s", err)
+	}
+
+	previous := db
+	db = testDB
+	t.Cleanup(func() {
+		db = previous
+		sqlDB.Close()
+	})
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDaemonPort is the port of the servers of the configuration without one
+const defaultDaemonPort = 8080
+
+// DaemonTarget identifies one server of one cluster that a command is forwarded to.
+type DaemonTarget struct {
+	Project  string // the daemon works in the workspace of the project
+	Cluster  string
+	Server   string
+	Scheme   string
+	IP       string // IPv4, IPv6 (without brackets) or host name
+	Port     int
+	BasePath string // without trailing slash
+}
+
+// URL builds the daemon endpoint for the given api path (e.g. "/api/start"), from the scheme, address,
+// port and base path of the server.
+func (t DaemonTarget) URL(path string) string {
+	return t.Scheme + "://" + net.JoinHostPort(t.IP, strconv.Itoa(t.Port)) + t.BasePath + path
+}
+
+// ConfigureURL builds the configure endpoint of the daemon. The daemon renders the deployment in the
+// deploy-details folder of the server, so the call names the server it is for.
+func (t DaemonTarget) ConfigureURL() string {
+	return t.URL("/api/configure") + "?" + url.Values{"cluster": {t.Cluster}, "server": {t.Server}}.Encode()
+}
+
+// newDaemonTarget fills in the defaults of the daemon address of a server
+func newDaemonTarget(cluster string, server string, scheme string, ip string, port int, basePath string) DaemonTarget {
+	if scheme == "" {
+		scheme = defaultDaemonScheme(cluster, server)
+	}
+	if port == 0 {
+		port = defaultDaemonPort
+	}
+
+	return DaemonTarget{
+		Cluster:  cluster,
+		Server:   server,
+		Scheme:   scheme,
+		IP:       strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]"),
+		Port:     port,
+		BasePath: strings.TrimSuffix(basePath, "/"),
+	}
+}
+
+// defaultDaemonScheme returns the scheme of a server without one in the configuration. APPJET_DAEMON_TLS
+// chooses, as on the daemons: "on" for https, "off" for http, and "auto" (the default) for https once the
+// daemon of the server is enrolled and http until then.
+func defaultDaemonScheme(cluster string, server string) string {
+	switch os.Getenv("APPJET_DAEMON_TLS") {
+	case "on":
+		return "https"
+	case "off":
+		return "http"
+	}
+
+	if GetDBConnection() == nil {
+		return "http"
+	}
+	if _, err := pinnedFingerprint(DaemonTarget{Cluster: cluster, Server: server}); err != nil {
+		return "http"
+	}
+	return "https"
+}
+
+// DaemonCall performs a single forwarder call against one daemon.
+type DaemonCall func(ctx context.Context, target DaemonTarget) (*http.Response, []byte, error)
+
+// DispatchResult holds the outcome of one DaemonCall.
+type DispatchResult struct {
+	Target   DaemonTarget
+	Response *http.Response
+	Body     []byte
+	Err      error
+	Duration time.Duration
+}
+
+// DispatchSettings controls how many daemons are called at once and how long we wait for them.
+type DispatchSettings struct {
+	Concurrency   int
+	ServerTimeout time.Duration
+	Deadline      time.Duration
+}
+
+// GetDispatchSettings reads the dispatcher settings from the environment, falling back to defaults:
+//
+//	APPJET_DISPATCH_CONCURRENCY  max daemons called in parallel (default 10)
+//	APPJET_SERVER_TIMEOUT        timeout for a single daemon call (default 60s)
+//	APPJET_DISPATCH_DEADLINE     global deadline for the whole fan-out (default 5m)
+func GetDispatchSettings() DispatchSettings {
+	settings := DispatchSettings{
+		Concurrency:   10,
+		ServerTimeout: 60 * time.Second,
+		Deadline:      5 * time.Minute,
+	}
+
+	if value, err := strconv.Atoi(os.Getenv("APPJET_DISPATCH_CONCURRENCY")); err == nil && value > 0 {
+		settings.Concurrency = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("APPJET_SERVER_TIMEOUT")); err == nil && value > 0 {
+		settings.ServerTimeout = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("APPJET_DISPATCH_DEADLINE")); err == nil && value > 0 {
+		settings.Deadline = value
+	}
+
+	return settings
+}
+
+// ResolveTargets returns the servers of a project addressed by a command, ordered by cluster and server as
+// they appear in its configuration. The default project also gets the joined servers of the inventory
+// that are not in its configuration; other projects list the joined servers they deploy to in their
+// configuration. An empty cluster (or server) name selects all of them.
+func ResolveTargets(project string, config *models.Configuration, cluster string, server string) []DaemonTarget {
+	var targets []DaemonTarget
+
+	for cIndex := range config.Clusters {
+		if cluster != "" && config.Clusters[cIndex].Name != cluster {
+			continue
+		}
+
+		for sIndex := range config.Clusters[cIndex].Servers {
+			if server != "" && config.Clusters[cIndex].Servers[sIndex].Name != server {
+				continue
+			}
+
+			entry := &config.Clusters[cIndex].Servers[sIndex]
+			target := newDaemonTarget(config.Clusters[cIndex].Name, entry.Name, entry.Scheme, entry.IP, entry.Port, entry.BasePath)
+			target.Project = project
+			targets = append(targets, target)
+		}
+	}
+	if project != models.DefaultProject {
+		return targets
+	}
+
+	configured := len(targets)
+	for _, joined := range inventoryTargets(cluster, server) {
+		duplicate := false
+		for _, target := range targets[:configured] {
+			if target.Cluster == joined.Cluster && target.Server == joined.Server {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			joined.Project = project
+			targets = append(targets, joined)
+		}
+	}
+
+	return targets
+}
+
+// DispatchHooks lets callers follow the progress of a dispatch, e.g. to persist job tasks.
+// Hooks are called from the dispatching goroutines and must be safe for concurrent use.
+type DispatchHooks struct {
+	OnStart func(index int, target DaemonTarget)
+	OnDone  func(index int, result DispatchResult)
+}
+
+// Dispatch runs call against every target in parallel, bounded by the configured concurrency.
+// Each call gets its own timeout and the whole fan-out is bounded by the global deadline, so a
+// hung daemon only costs its own slot. Results keep the order of targets.
+func Dispatch(ctx context.Context, targets []DaemonTarget, call DaemonCall) []DispatchResult {
+	return DispatchWithHooks(ctx, targets, call, DispatchHooks{})
+}
+
+// DispatchWithHooks is Dispatch with progress hooks.
+func DispatchWithHooks(ctx context.Context, targets []DaemonTarget, call DaemonCall, hooks DispatchHooks) []DispatchResult {
+	settings := GetDispatchSettings()
+
+	ctx, cancel := context.WithTimeout(ctx, settings.Deadline)
+	defer cancel()
+
+	results := make([]DispatchResult, len(targets))
+	slots := make(chan struct{}, settings.Concurrency)
+
+	var wg sync.WaitGroup
+	for index, target := range targets {
+		wg.Add(1)
+		go func(index int, target DaemonTarget) {
+			defer wg.Done()
+			results[index].Target = target
+			if hooks.OnDone != nil {
+				defer func() { hooks.OnDone(index, results[index]) }()
+			}
+
+			// Wait for a free slot, unless the global deadline expires first
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				results[index].Err = ctx.Err()
+				return
+			}
+
+			if hooks.OnStart != nil {
+				hooks.OnStart(index, target)
+			}
+
+			serverCtx, serverCancel := context.WithTimeout(withDaemonTarget(ctx, target), settings.ServerTimeout)
+			defer serverCancel()
+
+			start := time.Now()
+			results[index].Response, results[index].Body, results[index].Err = call(serverCtx, target)
+			results[index].Duration = time.Since(start)
+		}(index, target)
+	}
+	wg.Wait()
+
+	return results
+}
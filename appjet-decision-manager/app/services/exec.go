@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/net/websocket"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// defaultExecTimeout is the timeout of a one-shot command that does not set one, as on the daemons
+const defaultExecTimeout = "30s"
+
+// execKillGrace is how long after its timeout a daemon may answer, the time it takes to kill the command
+const execKillGrace = 5 * time.Second
+
+// ErrInvalidExecRequest is returned for commands without arguments or with an invalid timeout
+var ErrInvalidExecRequest = errors.New("invalid exec request")
+
+// ExecRequest is a one-shot command to run in a container
+type ExecRequest struct {
+	Command []string `json:"command"`
+	Timeout string   `json:"timeout"` // 30s when empty
+}
+
+// execControl is a control message of an interactive session, sent as a text frame. The TTY itself
+// goes through binary frames.
+type execControl struct {
+	Type     string `json:"type"` // "resize" from the client, "exit" or "error" from the daemon
+	ExitCode int    `json:"exit-code,omitempty"`
+}
+
+// execFrame is a websocket frame with its payload type, so control messages go through untouched
+type execFrame struct {
+	payloadType byte
+	data        []byte
+}
+
+var execFrameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		frame := v.(execFrame)
+		return frame.data, frame.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*execFrame)
+		frame.data, frame.payloadType = data, payloadType
+		return nil
+	},
+}
+
+// ValidateExecRequest checks the command and fills in the default timeout. The command, and killing it
+// when it times out, must end before APPJET_SERVER_TIMEOUT, the longest the decision manager waits for a
+// daemon.
+func ValidateExecRequest(request *ExecRequest) error {
+	if len(request.Command) == 0 {
+		return fmt.Errorf("%w: the command is required", ErrInvalidExecRequest)
+	}
+	if request.Timeout == "" {
+		request.Timeout = defaultExecTimeout
+	}
+
+	serverTimeout := GetDispatchSettings().ServerTimeout
+	timeout, err := time.ParseDuration(request.Timeout)
+	if err != nil || timeout <= 0 || timeout+execKillGrace >= serverTimeout {
+		return fmt.Errorf("%w: timeout must be a duration shorter than the server timeout (%s) minus %s", ErrInvalidExecRequest, serverTimeout, execKillGrace)
+	}
+
+	return nil
+}
+
+// DialDaemonExec opens an interactive session with the daemon of the target: command runs in the
+// container attached to a TTY of rows x cols (the daemon default when 0), over a websocket.
+func DialDaemonExec(ctx context.Context, target DaemonTarget, container string, command []string, rows uint, cols uint) (*websocket.Conn, error) {
+	ctx = withDaemonTarget(ctx, target)
+
+	query := url.Values{"command": command}
+	if rows > 0 && cols > 0 {
+		query.Set("rows", strconv.FormatUint(uint64(rows), 10))
+		query.Set("cols", strconv.FormatUint(uint64(cols), 10))
+	}
+	execURL := target.URL("/api/exec/"+url.PathEscape(container)+"/tty") + "?" + query.Encode()
+
+	// the handshake is signed like any other daemon call
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, execURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := signDaemonRequest(request); err != nil {
+		return nil, err
+	}
+
+	// signing adds the project to the query, the session is opened on the signed url
+	config, err := websocket.NewConfig(request.URL.String(), target.URL(""))
+	if err != nil {
+		return nil, err
+	}
+	config.Header = request.Header
+
+	address := net.JoinHostPort(target.IP, strconv.Itoa(target.Port))
+	var conn net.Conn
+	if target.Scheme == "https" {
+		conn, err = dialDaemonTLS(ctx, "tcp", address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(GetDispatchSettings().ServerTimeout))
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("the daemon refused the session: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ws, nil
+}
+
+// ProxyExecSession relays the frames of an interactive session between the client and the daemon until
+// either side closes it, and returns the exit code of the command, -1 when it did not exit.
+func ProxyExecSession(client *websocket.Conn, daemon *websocket.Conn) int {
+	exitCode := -1
+	done := make(chan struct{}, 2)
+
+	go func() {
+		relayExecFrames(daemon, client, nil)
+		done <- struct{}{}
+	}()
+	go func() {
+		relayExecFrames(client, daemon, func(control execControl) {
+			if control.Type == "exit" {
+				exitCode = control.ExitCode
+			}
+		})
+		done <- struct{}{}
+	}()
+
+	// once one side is gone the other one is closed, which ends the second relay
+	<-done
+	client.Close()
+	daemon.Close()
+	<-done
+
+	return exitCode
+}
+
+// relayExecFrames copies the frames of from to to, handing the control messages over to onControl
+func relayExecFrames(to *websocket.Conn, from *websocket.Conn, onControl func(control execControl)) {
+	for {
+		var frame execFrame
+		if err := execFrameCodec.Receive(from, &frame); err != nil {
+			return
+		}
+
+		if onControl != nil && frame.payloadType == websocket.TextFrame {
+			var control execControl
+			if json.Unmarshal(frame.data, &control) == nil {
+				onControl(control)
+			}
+		}
+
+		if err := execFrameCodec.Send(to, frame); err != nil {
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"appjet-decision-manager/app/models"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"mime/multipart"
@@ -11,142 +12,133 @@ import (
 	"path/filepath"
 )
 
-func ForwardConfigToDaemon(config *models.Configuration, url string) (resp *http.Response, err error) {
-	// Convert config to JSON
-	configJSON, err := json.Marshal(config)
-	if err != nil {
-		return nil, err
+// daemonTransport talks mutual TLS to the daemons, see dialDaemonTLS
+var daemonTransport = &http.Transport{DialTLSContext: dialDaemonTLS}
+
+// daemonClient is shared by all forwarder calls. It has no timeout of its own, the
+// deadline of every call comes from the context handed over by the dispatcher.
+var daemonClient = &http.Client{Transport: daemonTransport}
+
+// doDaemonRequest signs and sends the request and reads the whole response body, so callers
+// never have to deal with a closed or half-read body.
+func doDaemonRequest(request *http.Request) (*http.Response, []byte, error) {
+	if err := signDaemonRequest(request); err != nil {
+		return nil, nil, err
 	}
 
-	// Make HTTP POST request to the daemon
-	response, err := http.Post(url, "application/json", bytes.NewBuffer(configJSON))
+	response, err := daemonClient.Do(request)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer response.Body.Close()
 
-	return response, err
-}
-
-func ForwardStartToDaemon(url string) (resp *http.Response, err error) {
-
-	// Make HTTP POST request to the daemon
-	response, err := http.Get(url)
+	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		return nil, err
+		return response, nil, err
 	}
-	defer response.Body.Close()
 
-	return response, err
+	return response, body, nil
 }
 
-func ForwardRestartToDaemon(url string) (resp *http.Response, err error) {
-
-	// Make HTTP POST request to the daemon
-	response, err := http.Get(url)
+func forwardGetToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer response.Body.Close()
 
-	return response, err
+	return doDaemonRequest(request)
 }
 
-func ForwardStopToDaemon(url string) (resp *http.Response, err error) {
-
-	// Make HTTP POST request to the daemon
-	response, err := http.Get(url)
+func ForwardConfigToDaemon(ctx context.Context, config *models.Configuration, url string) (*http.Response, []byte, error) {
+	// Convert config to JSON
+	configJSON, err := json.Marshal(config)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer response.Body.Close()
-
-	return response, err
-}
-
-func ForwardCheckAliveToDaemon(url string) (resp *http.Response, err error) {
 
 	// Make HTTP POST request to the daemon
-	response, err := http.Get(url)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(configJSON))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	request.Header.Set("Content-Type", "application/json")
 
-	return response, err
+	return doDaemonRequest(request)
 }
 
-func ForwardInspectToDaemon(url string) (resp *http.Response, err error) {
-
-	// Make HTTP POST request to the daemon
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
+func ForwardStartToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
+}
 
-	return response, err
+func ForwardRestartToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
 }
 
-func ForwardCleanToDaemon(url string) (resp *http.Response, err error) {
+func ForwardStopToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
+}
 
-	// Make HTTP POST request to the daemon
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
+func ForwardCheckAliveToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
+}
 
-	return response, err
+func ForwardInspectToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
 }
 
-func ForwardSCPRunToDaemon(url string) (resp *http.Response, err error) {
+func ForwardTemplatesToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
+}
 
-	// Make HTTP POST request to the daemon
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
+func ForwardCleanToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
+}
 
-	return response, err
+func ForwardSCPRunToDaemon(ctx context.Context, url string) (*http.Response, []byte, error) {
+	return forwardGetToDaemon(ctx, url)
 }
 
-func ForwardSCPToDaemon(scriptBytes multipart.File, filename string, url string) (resp *http.Response, err error) {
+// ForwardSCPToDaemon uploads a script to the daemon. The script is passed as bytes so the
+// same upload can be sent to several daemons concurrently.
+func ForwardSCPToDaemon(ctx context.Context, script []byte, filename string, url string) (*http.Response, []byte, error) {
 	// Create a new buffer to store the multipart request body
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
-	// Create a form file field for the scriptBytes
-	part, err := writer.CreateFormFile("file", filename) // Change "filename" to an appropriate name
+	// Create a form file field for the script
+	part, err := writer.CreateFormFile("file", filename)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Copy the content of scriptBytes to the form file field
-	_, err = io.Copy(part, scriptBytes)
+	_, err = part.Write(script)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Close the multipart writer before making the request
-	writer.Close()
+	err = writer.Close()
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Make HTTP POST request to the daemon
-	response, err := http.Post(url, writer.FormDataContentType(), body)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer response.Body.Close()
+	request.Header.Set("Content-Type", writer.FormDataContentType())
 
-	return response, err
+	return doDaemonRequest(request)
 }
 
-func ForwardSCPCodeToDaemon(codeDirectory string, url string) (resp *http.Response, err error) {
+func ForwardSCPCodeToDaemon(ctx context.Context, codeDirectory string, url string) (*http.Response, []byte, error) {
 	// Create a new buffer to store the multipart request body
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	// Walk through the specified directory and its subdirectories
-	err = filepath.Walk(codeDirectory, func(filePath string, info os.FileInfo, err error) error {
+	err := filepath.Walk(codeDirectory, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -178,28 +170,37 @@ func ForwardSCPCodeToDaemon(codeDirectory string, url string) (resp *http.Respon
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Close the multipart writer before making the request
 	err = writer.Close()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Make HTTP POST request to the SCPCodeHandler endpoint
-	request, err := http.NewRequest("POST", url, body)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	request.Header.Set("Content-Type", writer.FormDataContentType())
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	return doDaemonRequest(request)
+}
+
+// ForwardExecToDaemon runs a one-shot command in a container of the daemon
+func ForwardExecToDaemon(ctx context.Context, request ExecRequest, url string) (*http.Response, []byte, error) {
+	requestJSON, err := json.Marshal(request)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer response.Body.Close()
 
-	return response, nil
+	daemonRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	daemonRequest.Header.Set("Content-Type", "application/json")
+
+	return doDaemonRequest(daemonRequest)
 }
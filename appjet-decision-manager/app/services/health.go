@@ -0,0 +1,333 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidHealthWindow is returned for uptime windows that are not a positive duration
+var ErrInvalidHealthWindow = errors.New("invalid window")
+
+// HealthSettings controls the health monitor
+type HealthSettings struct {
+	Interval      time.Duration
+	FlapChanges   int
+	FlapWindow    time.Duration
+	HistoryLength int
+}
+
+// GetHealthSettings reads the health monitor settings from the environment, falling back to defaults:
+//
+//	APPJET_HEALTH_INTERVAL      how often every daemon is checked, "off" disables the monitor (default 30s)
+//	APPJET_HEALTH_FLAP_CHANGES  up/down changes within the flap window that make a server flapping (default 4)
+//	APPJET_HEALTH_FLAP_WINDOW   window the changes are counted in (default 10m)
+//	APPJET_HEALTH_HISTORY       transitions returned per server by GET /appjet/status (default 20)
+func GetHealthSettings() HealthSettings {
+	settings := HealthSettings{
+		Interval:      30 * time.Second,
+		FlapChanges:   4,
+		FlapWindow:    10 * time.Minute,
+		HistoryLength: 20,
+	}
+
+	if os.Getenv("APPJET_HEALTH_INTERVAL") == "off" {
+		settings.Interval = 0
+	} else if value, err := time.ParseDuration(os.Getenv("APPJET_HEALTH_INTERVAL")); err == nil && value > 0 {
+		settings.Interval = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("APPJET_HEALTH_FLAP_CHANGES")); err == nil && value > 1 {
+		settings.FlapChanges = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("APPJET_HEALTH_FLAP_WINDOW")); err == nil && value > 0 {
+		settings.FlapWindow = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("APPJET_HEALTH_HISTORY")); err == nil && value > 0 {
+		settings.HistoryLength = value
+	}
+
+	return settings
+}
+
+// healthState is what the monitor knows about a server between two checks
+type healthState struct {
+	state     string      // reported state: up, down or flapping
+	observed  string      // result of the last check: up or down
+	reason    string      // why the last check was down
+	since     time.Time   // when the reported state started
+	checkedAt time.Time   // when the last check finished
+	changes   []time.Time // up/down changes within the flap window
+}
+
+var (
+	healthMutex  sync.Mutex
+	healthStates = map[string]*healthState{}
+)
+
+// MonitorHealth checks every daemon at the configured interval and records the changes of their
+// health state. It never returns, unless the monitor is disabled.
+func MonitorHealth() {
+	settings := GetHealthSettings()
+	if settings.Interval == 0 {
+		log.Printf("Health monitor disabled")
+		return
+	}
+
+	ticker := time.NewTicker(settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		checkHealth(settings)
+		<-ticker.C
+	}
+}
+
+// checkHealth calls check-alive on every daemon of every project and records the servers whose state changed
+func checkHealth(settings HealthSettings) {
+	var targets []DaemonTarget
+	for project, config := range projectConfigsSnapshot() {
+		if config == nil {
+			// the joined servers are checked even without a configuration
+			config = &models.Configuration{}
+		}
+		targets = append(targets, ResolveTargets(project, config, "", "")...)
+	}
+
+	results := Dispatch(context.Background(), targets, func(ctx context.Context, target DaemonTarget) (*http.Response, []byte, error) {
+		return ForwardCheckAliveToDaemon(ctx, target.URL("/api/check-alive"))
+	})
+
+	for _, result := range results {
+		observed, reason := observedHealth(NewServerResult(result))
+		if err := observeHealth(settings, result.Target, observed, reason, time.Now()); err != nil {
+			log.Printf("Error recording the health of server %s of cluster %s (project %s): %s", result.Target.Server, result.Target.Cluster, result.Target.Project, err)
+		}
+	}
+}
+
+// observedHealth tells whether a check-alive result is up: the daemon answered and every container
+// it reports is running
+func observedHealth(result models.ServerResult) (string, string) {
+	if result.Status != models.ServerStatusOK {
+		return models.HealthDown, result.Status + ": " + result.Error
+	}
+
+	body, _ := result.Body.(map[string]interface{})
+	containers, _ := body["docker-containers-status"].(map[string]interface{})
+
+	var stopped []string
+	for name, value := range containers {
+		container, _ := value.(map[string]interface{})
+		if running, _ := container["status"].(bool); !running {
+			stopped = append(stopped, name)
+		}
+	}
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		return models.HealthDown, "containers not running: " + strings.Join(stopped, ", ")
+	}
+
+	return models.HealthUp, ""
+}
+
+// observeHealth updates the state of a server with the result of a check. A server whose result changed
+// FlapChanges times within FlapWindow is flapping until its result is stable for a whole window.
+func observeHealth(settings HealthSettings, target DaemonTarget, observed string, reason string, now time.Time) error {
+	healthMutex.Lock()
+	defer healthMutex.Unlock()
+
+	key := healthKey(target.Project, target.Cluster, target.Server)
+	current, ok := healthStates[key]
+	if !ok {
+		current = &healthState{state: models.HealthUnknown}
+		if last, err := lastHealthTransition(target.Project, target.Cluster, target.Server); err == nil && last != nil {
+			// carry on from the state recorded before the decision manager restarted
+			current.state, current.since, current.reason = last.State, last.CreatedAt, last.Reason
+			if last.State != models.HealthFlapping {
+				current.observed = last.State
+			}
+		}
+		healthStates[key] = current
+	}
+
+	if current.observed != "" && current.observed != observed {
+		current.changes = append(current.changes, now)
+	}
+	for len(current.changes) > 0 && now.Sub(current.changes[0]) > settings.FlapWindow {
+		current.changes = current.changes[1:]
+	}
+	current.observed, current.reason, current.checkedAt = observed, reason, now
+
+	state := observed
+	if len(current.changes) >= settings.FlapChanges || (current.state == models.HealthFlapping && len(current.changes) > 0) {
+		state = models.HealthFlapping
+	}
+	if state == current.state {
+		return nil
+	}
+
+	transition := models.HealthTransition{
+		Project:   target.Project,
+		Cluster:   target.Cluster,
+		Server:    target.Server,
+		State:     state,
+		Reason:    reason,
+		CreatedAt: now,
+	}
+	if current.state != models.HealthUnknown {
+		transition.PreviousState = current.state
+	}
+	log.Printf("Server %s of cluster %s is %s for project %s", target.Server, target.Cluster, state, target.Project)
+
+	current.state, current.since = state, now
+	if err := GetDBConnection().Create(&transition).Error; err != nil {
+		return err
+	}
+
+	switch state {
+	case models.HealthDown:
+		EmitWebhookEvent(models.EventServerDown, serverEventData(transition))
+	case models.HealthUp:
+		// the first check of a server is not news, unless it was down before a restart
+		if transition.PreviousState != "" {
+			EmitWebhookEvent(models.EventServerUp, serverEventData(transition))
+		}
+	}
+
+	return nil
+}
+
+func serverEventData(transition models.HealthTransition) ServerEventData {
+	return ServerEventData{
+		Project:       transition.Project,
+		Cluster:       transition.Cluster,
+		Server:        transition.Server,
+		State:         transition.State,
+		PreviousState: transition.PreviousState,
+		Reason:        transition.Reason,
+	}
+}
+
+// healthKey identifies the health state of the containers of a project on a server
+func healthKey(project string, cluster string, server string) string {
+	return project + "/" + cluster + "/" + server
+}
+
+// ServerHealthStatus returns the health of the servers of a project selected by cluster/server (empty means
+// all), with their last transitions and their uptime over the window ("24h", "7d")
+func ServerHealthStatus(project string, config *models.Configuration, cluster string, server string, windowValue string) ([]models.ServerHealth, error) {
+	window, ok := parseDuration(windowValue)
+	if !ok {
+		return nil, fmt.Errorf("%w %q, use a duration such as \"24h\" or a number of days such as \"7d\"", ErrInvalidHealthWindow, windowValue)
+	}
+
+	settings := GetHealthSettings()
+	now := time.Now()
+
+	targets := ResolveTargets(project, config, cluster, server)
+	statuses := make([]models.ServerHealth, 0, len(targets))
+	for _, target := range targets {
+		status := models.ServerHealth{Cluster: target.Cluster, Server: target.Server, State: models.HealthUnknown}
+
+		history, err := healthHistory(project, target.Cluster, target.Server, settings.HistoryLength)
+		if err != nil {
+			return nil, err
+		}
+		status.History = history
+		if len(history) > 0 {
+			status.State, status.Since, status.Reason = history[0].State, &history[0].CreatedAt, history[0].Reason
+		}
+
+		healthMutex.Lock()
+		if current, ok := healthStates[healthKey(project, target.Cluster, target.Server)]; ok && !current.checkedAt.IsZero() {
+			checkedAt := current.checkedAt
+			status.CheckedAt = &checkedAt
+			if current.observed == models.HealthDown {
+				status.Reason = current.reason
+			}
+		}
+		healthMutex.Unlock()
+
+		if status.UptimePercent, err = uptimePercent(project, target.Cluster, target.Server, now.Add(-window), now); err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// uptimePercent returns the share of the window the server was up. The time before its first recorded
+// state does not count, and flapping does not count as up.
+func uptimePercent(project string, cluster string, server string, from time.Time, to time.Time) (*float64, error) {
+	var transitions []models.HealthTransition
+	err := GetDBConnection().Where("project = ? AND cluster = ? AND server = ? AND created_at > ? AND created_at <= ?", project, cluster, server, from, to).
+		Order("created_at").Find(&transitions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// the state the server was in when the window started
+	var before models.HealthTransition
+	result := GetDBConnection().Where("project = ? AND cluster = ? AND server = ? AND created_at <= ?", project, cluster, server, from).
+		Order("created_at desc").Limit(1).Find(&before)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		before.CreatedAt = from
+		transitions = append([]models.HealthTransition{before}, transitions...)
+	}
+	if len(transitions) == 0 {
+		return nil, nil
+	}
+
+	var up, total time.Duration
+	for index, transition := range transitions {
+		end := to
+		if index+1 < len(transitions) {
+			end = transitions[index+1].CreatedAt
+		}
+		total += end.Sub(transition.CreatedAt)
+		if transition.State == models.HealthUp {
+			up += end.Sub(transition.CreatedAt)
+		}
+	}
+	if total <= 0 {
+		return nil, nil
+	}
+
+	percent := float64(up) / float64(total) * 100
+	return &percent, nil
+}
+
+// healthHistory returns the last transitions of a server for a project, most recent first
+func healthHistory(project string, cluster string, server string, limit int) ([]models.HealthTransition, error) {
+	transitions := []models.HealthTransition{}
+	err := GetDBConnection().Where("project = ? AND cluster = ? AND server = ?", project, cluster, server).
+		Order("created_at desc, id desc").Limit(limit).Find(&transitions).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading the health history: %w", err)
+	}
+
+	return transitions, nil
+}
+
+func lastHealthTransition(project string, cluster string, server string) (*models.HealthTransition, error) {
+	transitions, err := healthHistory(project, cluster, server, 1)
+	if err != nil || len(transitions) == 0 {
+		return nil, err
+	}
+
+	return &transitions[0], nil
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrServerExists is returned when a daemon joins with the name of a known server
+	ErrServerExists = errors.New("a server with this name already exists in the cluster")
+	// ErrInventoryServerNotFound is returned for servers that are not in the inventory
+	ErrInventoryServerNotFound = errors.New("server not found in the inventory")
+)
+
+// HeartbeatSettings controls how often the daemons report and when they are considered offline.
+type HeartbeatSettings struct {
+	Interval time.Duration
+	Missed   int
+}
+
+// GetHeartbeatSettings reads the heartbeat settings from the environment, falling back to defaults:
+//
+//	APPJET_HEARTBEAT_INTERVAL  how often joined daemons send a heartbeat (default 30s)
+//	APPJET_HEARTBEAT_MISSED    missed heartbeats before a server is marked offline (default 3)
+func GetHeartbeatSettings() HeartbeatSettings {
+	settings := HeartbeatSettings{
+		Interval: 30 * time.Second,
+		Missed:   3,
+	}
+
+	if value, err := time.ParseDuration(os.Getenv("APPJET_HEARTBEAT_INTERVAL")); err == nil && value > 0 {
+		settings.Interval = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("APPJET_HEARTBEAT_MISSED")); err == nil && value > 0 {
+		settings.Missed = value
+	}
+
+	return settings
+}
+
+// JoinRequest is sent by "appjet-server-daemon join"
+type JoinRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Cluster  string `json:"cluster" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Scheme   string `json:"scheme"`
+	Address  string `json:"address"` // the address the join request came from when empty
+	Port     int    `json:"port"`
+	BasePath string `json:"base-path"`
+	CSR      string `json:"csr" binding:"required"`
+}
+
+// JoinResponse is everything a daemon needs once it joined: the shared secret and its certificate
+type JoinResponse struct {
+	Cluster           string `json:"cluster"`
+	Server            string `json:"server"`
+	Secret            string `json:"secret"`
+	Certificate       string `json:"certificate"`
+	CA                string `json:"ca"`
+	HeartbeatInterval string `json:"heartbeat-interval"`
+}
+
+// Heartbeat is sent periodically by the joined daemons
+type Heartbeat struct {
+	Version       string                 `json:"version"`
+	Hostname      string                 `json:"hostname"`
+	FreeDiskBytes uint64                 `json:"free-disk-bytes"`
+	Containers    map[string]interface{} `json:"containers"`
+}
+
+// JoinServer adds the daemon of a server to the inventory with a one-time join token, and issues the
+// secret and the certificate it is called with from then on
+func JoinServer(request JoinRequest, remoteAddress string) (*JoinResponse, error) {
+	if config, err := CurrentConfig(models.DefaultProject); err == nil && len(ResolveTargets(models.DefaultProject, config, request.Cluster, request.Name)) > 0 {
+		return nil, ErrServerExists
+	}
+
+	if request.Address == "" {
+		request.Address = remoteAddress
+	}
+	// a joined daemon is enrolled, it serves https unless the join says otherwise
+	if request.Scheme == "" {
+		request.Scheme = "https"
+	}
+	target := newDaemonTarget(request.Cluster, request.Name, request.Scheme, request.Address, request.Port, request.BasePath)
+
+	now := time.Now()
+	server := models.Server{
+		Cluster:    target.Cluster,
+		Name:       target.Server,
+		Scheme:     target.Scheme,
+		Address:    target.IP,
+		Port:       target.Port,
+		BasePath:   target.BasePath,
+		Status:     models.ServerOnline,
+		JoinedAt:   now,
+		LastSeenAt: &now,
+	}
+
+	// the credentials are issued before the token is used, a CSR that cannot be signed leaves it valid
+	secret, err := generateDaemonSecret()
+	if err != nil {
+		return nil, err
+	}
+	certificate, certificatePEM, err := signDaemonCertificate(target.Cluster, target.Server, target.IP, request.CSR)
+	if err != nil {
+		return nil, err
+	}
+
+	// the token is consumed with the enrolment: the server, its secret and its certificate
+	tx := GetDBConnection().Begin()
+	if err := consumeJoinToken(tx, request.Token, request.Cluster, request.Name); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	var existing int64
+	if err := tx.Model(&models.Server{}).Where("cluster = ? AND name = ?", server.Cluster, server.Name).Count(&existing).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if existing > 0 {
+		tx.Rollback()
+		return nil, ErrServerExists
+	}
+	if err := tx.Create(&server).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("error persisting server: %w", err)
+	}
+	if err := saveDaemonSecret(tx, target.Cluster, target.Server, secret); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	enrolled, err := pinDaemonCertificate(tx, target.Cluster, target.Server, certificate, certificatePEM)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("Server %s of cluster %s joined from %s", server.Name, server.Cluster, remoteAddress)
+	return &JoinResponse{
+		Cluster:           target.Cluster,
+		Server:            target.Server,
+		Secret:            secret,
+		Certificate:       enrolled.Certificate,
+		CA:                enrolled.CA,
+		HeartbeatInterval: GetHeartbeatSettings().Interval.String(),
+	}, nil
+}
+
+// RecordHeartbeat stores the state reported by the daemon of a server and marks it online
+func RecordHeartbeat(cluster string, name string, heartbeat Heartbeat) error {
+	var server models.Server
+	result := GetDBConnection().Where("cluster = ? AND name = ?", cluster, name).Limit(1).Find(&server)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInventoryServerNotFound
+	}
+
+	if server.Status != models.ServerOnline {
+		log.Printf("Server %s of cluster %s is back online", name, cluster)
+	}
+
+	now := time.Now()
+	server.Status = models.ServerOnline
+	server.LastSeenAt = &now
+	server.Version = heartbeat.Version
+	server.Hostname = heartbeat.Hostname
+	server.FreeDiskBytes = heartbeat.FreeDiskBytes
+	server.Containers = heartbeat.Containers
+
+	return GetDBConnection().Save(&server).Error
+}
+
+// ListServers returns the inventory, ordered by cluster and name
+func ListServers() ([]models.Server, error) {
+	var servers []models.Server
+	if err := GetDBConnection().Order("cluster, name").Find(&servers).Error; err != nil {
+		return nil, err
+	}
+
+	return servers, nil
+}
+
+// DeleteServer removes a server from the inventory with its secret and certificate
+func DeleteServer(cluster string, name string) error {
+	result := GetDBConnection().Where("cluster = ? AND name = ?", cluster, name).Delete(&models.Server{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInventoryServerNotFound
+	}
+
+	if err := DeleteDaemonSecret(cluster, name); err != nil && !errors.Is(err, ErrDaemonSecretNotFound) {
+		return err
+	}
+	if err := DeleteDaemonCertificate(cluster, name); err != nil && !errors.Is(err, ErrDaemonCertificateNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// MonitorHeartbeats marks the servers offline once they missed too many heartbeats. It never returns.
+func MonitorHeartbeats() {
+	settings := GetHeartbeatSettings()
+	ticker := time.NewTicker(settings.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := markOfflineServers(settings); err != nil {
+			log.Printf("Error checking the server heartbeats: %s", err)
+		}
+	}
+}
+
+func markOfflineServers(settings HeartbeatSettings) error {
+	deadline := time.Now().Add(-time.Duration(settings.Missed) * settings.Interval)
+
+	var servers []models.Server
+	err := GetDBConnection().Where("status = ? AND last_seen_at < ?", models.ServerOnline, deadline).Find(&servers).Error
+	if err != nil {
+		return err
+	}
+
+	for _, server := range servers {
+		log.Printf("Server %s of cluster %s is offline, no heartbeat since %s", server.Name, server.Cluster, server.LastSeenAt.Format(time.RFC3339))
+		err := GetDBConnection().Model(&models.Server{}).Where("id = ?", server.ID).UpdateColumn("status", models.ServerOffline).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inventoryTargets returns the joined servers selected by cluster/server (empty means all)
+func inventoryTargets(cluster string, server string) []DaemonTarget {
+	if GetDBConnection() == nil {
+		return nil
+	}
+
+	query := GetDBConnection().Order("cluster, name")
+	if cluster != "" {
+		query = query.Where("cluster = ?", cluster)
+	}
+	if server != "" {
+		query = query.Where("name = ?", server)
+	}
+
+	var servers []models.Server
+	if err := query.Find(&servers).Error; err != nil {
+		log.Printf("Error loading the server inventory: %s", err)
+		return nil
+	}
+
+	targets := make([]DaemonTarget, 0, len(servers))
+	for _, entry := range servers {
+		targets = append(targets, newDaemonTarget(entry.Cluster, entry.Name, entry.Scheme, entry.Address, entry.Port, entry.BasePath))
+	}
+
+	return targets
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"errors"
+	"testing"
+)
+
+func TestJoinServer(t *testing.T) {
+	useTestDatabase(t, &models.JoinToken{}, &models.Server{}, &models.DaemonSecret{}, &models.DaemonCertificate{})
+	useTestCertificateAuthority(t)
+
+	_, token, err := CreateJoinToken("prod", "1h", "root")
+	if err != nil {
+		t.Fatalf("creating the join token: %s", err)
+	}
+	request := JoinRequest{Token: token, Cluster: "prod", Name: "server-1", Address: "10.0.0.5", Port: 8080, CSR: "not a csr"}
+
+	// a failed enrolment leaves the token valid and the server out of the inventory
+	if _, err := JoinServer(request, "10.0.0.5"); !errors.Is(err, ErrInvalidCSR) {
+		t.Fatalf("joined with an invalid CSR: %v", err)
+	}
+	if servers, _ := ListServers(); len(servers) != 0 {
+		t.Fatalf("%d servers in the inventory after a failed join", len(servers))
+	}
+
+	_, request.CSR = newDaemonCSR(t)
+	response, err := JoinServer(request, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("joining with the unused token: %s", err)
+	}
+	if response.Secret == "" || response.Certificate == "" {
+		t.Errorf("joined without credentials: %+v", response)
+	}
+	if _, err := pinnedFingerprint(newDaemonTarget("prod", "server-1", "https", "10.0.0.5", 8080, "")); err != nil {
+		t.Errorf("the certificate of the joined server is not pinned: %s", err)
+	}
+
+	// the token was consumed by the enrolment
+	request.Name = "server-2"
+	if _, err := JoinServer(request, "10.0.0.6"); !errors.Is(err, ErrInvalidJoinToken) {
+		t.Errorf("joined twice with the same token: %v", err)
+	}
+}
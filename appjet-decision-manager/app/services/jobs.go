@@ -0,0 +1,196 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// JobRequest describes a mutating command to run asynchronously.
+type JobRequest struct {
+	Project   string
+	Command   string
+	Argument  string
+	Cluster   string
+	Server    string
+	CreatedBy string
+}
+
+// StartJob persists a job with one pending task per target and runs it in the background.
+// The returned job is the pending snapshot; its progress is read back with GetJob.
+func StartJob(request JobRequest, targets []DaemonTarget, call DaemonCall) (*models.Job, error) {
+	job := newJob(request, targets)
+
+	// Creating the job also creates its tasks
+	if err := GetDBConnection().Create(&job).Error; err != nil {
+		return nil, fmt.Errorf("error persisting job: %w", err)
+	}
+
+	go runJob(job, targets, call)
+
+	return &job, nil
+}
+
+// newJob builds a pending job with one pending task per target.
+func newJob(request JobRequest, targets []DaemonTarget) models.Job {
+	job := models.Job{
+		ID:        uuid.New().String(),
+		Project:   request.Project,
+		Command:   request.Command,
+		Argument:  request.Argument,
+		Cluster:   request.Cluster,
+		Server:    request.Server,
+		Status:    models.JobStatusPending,
+		CreatedBy: request.CreatedBy,
+		CreatedAt: time.Now(),
+	}
+
+	for index, target := range targets {
+		job.Tasks = append(job.Tasks, models.JobTask{
+			Position: index,
+			Cluster:  target.Cluster,
+			Server:   target.Server,
+			Status:   models.JobStatusPending,
+		})
+	}
+
+	return job
+}
+
+// GetJob returns a job with its tasks ordered by cluster and server.
+func GetJob(id string) (*models.Job, error) {
+	var job models.Job
+	err := GetDBConnection().
+		Preload("Tasks", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Where("id = ?", id).
+		First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ListJobs returns the most recent jobs of a project, optionally filtered by status.
+func ListJobs(project string, status string, limit int) ([]models.Job, error) {
+	query := GetDBConnection().Where("project = ?", project).Order("created_at desc").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var jobs []models.Job
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// FailInterruptedJobs marks jobs left pending or running by a previous process as failed.
+func FailInterruptedJobs() error {
+	now := time.Now()
+	interrupted := []string{models.JobStatusPending, models.JobStatusRunning}
+
+	err := GetDBConnection().Model(&models.JobTask{}).
+		Where("status IN ?", interrupted).
+		Updates(map[string]interface{}{"status": models.JobStatusFailed, "error": "interrupted by a decision manager restart", "finished_at": now}).Error
+	if err != nil {
+		return err
+	}
+
+	return GetDBConnection().Model(&models.Job{}).
+		Where("status IN ?", interrupted).
+		Updates(map[string]interface{}{"status": models.JobStatusFailed, "error": "interrupted by a decision manager restart", "finished_at": now}).Error
+}
+
+// runJobTasks dispatches call to the targets and keeps the job tasks up to date as servers finish.
+// Tasks are matched to targets by position.
+func runJobTasks(jobID string, targets []DaemonTarget, call DaemonCall) models.FanOutResponse {
+	hooks := DispatchHooks{
+		OnStart: func(index int, target DaemonTarget) {
+			updateTask(jobID, index, map[string]interface{}{"status": models.JobStatusRunning, "started_at": time.Now()})
+		},
+		OnDone: func(index int, result DispatchResult) {
+			updateTask(jobID, index, taskUpdates(NewServerResult(result)))
+		},
+	}
+
+	return NewFanOutResponse(DispatchWithHooks(context.Background(), targets, call, hooks))
+}
+
+// updateTask applies updates to the task at the given position of a job.
+func updateTask(jobID string, position int, updates map[string]interface{}) {
+	err := GetDBConnection().Model(&models.JobTask{}).
+		Where("job_id = ? AND position = ?", jobID, position).
+		Updates(updates).Error
+	if err != nil {
+		log.Printf("Error updating task %d of job %s: %s", position, jobID, err)
+	}
+}
+
+// finishJob stores the final status and outcome of a job and completes its audit events.
+func finishJob(jobID string, outcome string, jobErr error) {
+	status := models.JobStatusSucceeded
+	if outcome != models.OutcomeSuccess || jobErr != nil {
+		status = models.JobStatusFailed
+	}
+
+	updates := map[string]interface{}{"status": status, "outcome": outcome, "finished_at": time.Now()}
+	if jobErr != nil {
+		updates["error"] = jobErr.Error()
+	}
+
+	err := GetDBConnection().Model(&models.Job{}).Where("id = ?", jobID).Updates(updates).Error
+	if err != nil {
+		log.Printf("Error finishing job %s: %s", jobID, err)
+		return
+	}
+
+	completeJobAuditEvents(jobID)
+	emitJobEvent(jobID)
+}
+
+func runJob(job models.Job, targets []DaemonTarget, call DaemonCall) {
+	markJobRunning(job.ID)
+
+	response := runJobTasks(job.ID, targets, call)
+
+	finishJob(job.ID, response.Outcome, nil)
+}
+
+func markJobRunning(jobID string) {
+	err := GetDBConnection().Model(&models.Job{}).Where("id = ?", jobID).
+		Updates(map[string]interface{}{"status": models.JobStatusRunning, "started_at": time.Now()}).Error
+	if err != nil {
+		log.Printf("Error starting job %s: %s", jobID, err)
+	}
+}
+
+func taskUpdates(result models.ServerResult) map[string]interface{} {
+	status := models.JobStatusSucceeded
+	if result.Status != models.ServerStatusOK {
+		status = models.JobStatusFailed
+	}
+
+	body := ""
+	if result.Body != nil {
+		if encoded, err := json.Marshal(result.Body); err == nil {
+			body = string(encoded)
+		}
+	}
+
+	return map[string]interface{}{
+		"status":      status,
+		"result":      result.Status,
+		"http_code":   result.HTTPCode,
+		"body":        body,
+		"error":       result.Error,
+		"duration_ms": result.DurationMs,
+		"finished_at": time.Now(),
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// joinTokenPrefix starts every join token secret
+const joinTokenPrefix = "ajj_"
+
+// defaultJoinTokenLifetime is used when a join token is created without an expiry
+const defaultJoinTokenLifetime = 24 * time.Hour
+
+var (
+	// ErrJoinTokenNotFound is returned for unknown join tokens
+	ErrJoinTokenNotFound = errors.New("join token not found")
+	// ErrInvalidJoinToken is returned for join tokens that are expired, used or for another cluster
+	ErrInvalidJoinToken = errors.New("invalid join token")
+)
+
+// CreateJoinToken creates a token letting one daemon join the cluster and returns it with its secret.
+// The secret is not stored and cannot be read again.
+func CreateJoinToken(cluster string, expires string, createdBy string) (*models.JoinToken, string, error) {
+	lifetime := defaultJoinTokenLifetime
+	if expires != "" {
+		var ok bool
+		if lifetime, ok = parseDuration(expires); !ok {
+			return nil, "", fmt.Errorf("invalid expiry %q, use a duration such as \"1h\" or a number of days such as \"2d\"", expires)
+		}
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("error generating join token: %w", err)
+	}
+	secret := joinTokenPrefix + hex.EncodeToString(random)
+
+	token := models.JoinToken{
+		Prefix:    secret[:12],
+		Hash:      hashSecret(secret),
+		Cluster:   cluster,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(lifetime),
+	}
+	if err := GetDBConnection().Create(&token).Error; err != nil {
+		return nil, "", fmt.Errorf("error persisting join token: %w", err)
+	}
+
+	return &token, secret, nil
+}
+
+// ListJoinTokens returns the join tokens, most recent first
+func ListJoinTokens() ([]models.JoinToken, error) {
+	var tokens []models.JoinToken
+	if err := GetDBConnection().Order("created_at desc").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RevokeJoinToken deletes a join token
+func RevokeJoinToken(id uint) error {
+	result := GetDBConnection().Delete(&models.JoinToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJoinTokenNotFound
+	}
+
+	return nil
+}
+
+// consumeJoinToken marks the join token as used by the server, in the transaction of the join. The
+// token must be unused, unexpired and for the cluster.
+func consumeJoinToken(tx *gorm.DB, secret string, cluster string, server string) error {
+	now := time.Now()
+	result := tx.Model(&models.JoinToken{}).
+		Where("hash = ? AND cluster = ? AND used_at IS NULL AND expires_at > ?", hashSecret(secret), cluster, now).
+		UpdateColumns(map[string]interface{}{"used_at": now, "used_by": server})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidJoinToken
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// maxLogLineLength is the longest log line read from a daemon, longer lines end the stream of that server
+const maxLogLineLength = 1024 * 1024
+
+// LogLine is a line of the logs of a container on one server, or the error that ended its stream
+type LogLine struct {
+	Cluster string `json:"cluster"`
+	Server  string `json:"server"`
+	Line    string `json:"line,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// StreamDaemonLogs streams the logs of the container from every target into lines, interleaved as they
+// arrive, and closes lines once every stream ended. query holds the follow, tail, since and timestamps
+// options of the daemons. Cancel ctx to stop following the logs.
+func StreamDaemonLogs(ctx context.Context, targets []DaemonTarget, container string, query url.Values, lines chan<- LogLine) {
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target DaemonTarget) {
+			defer wg.Done()
+
+			err := streamDaemonLogs(ctx, target, container, query, func(line string) {
+				lines <- LogLine{Cluster: target.Cluster, Server: target.Server, Line: line}
+			})
+			if err != nil && ctx.Err() == nil {
+				lines <- LogLine{Cluster: target.Cluster, Server: target.Server, Error: err.Error()}
+			}
+		}(target)
+	}
+
+	wg.Wait()
+	close(lines)
+}
+
+// streamDaemonLogs calls the logs endpoint of one daemon and hands over every line it sends
+func streamDaemonLogs(ctx context.Context, target DaemonTarget, container string, query url.Values, send func(line string)) error {
+	ctx = withDaemonTarget(ctx, target)
+	logsURL := target.URL("/api/logs/" + url.PathEscape(container))
+	if len(query) > 0 {
+		logsURL += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, logsURL, nil)
+	if err != nil {
+		return err
+	}
+	if err := signDaemonRequest(request); err != nil {
+		return err
+	}
+
+	// the response body is streamed, unlike the other daemon calls which read it whole
+	response, err := daemonClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+		return errors.New(daemonErrorMessage(response, parseDaemonBody(body)))
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineLength)
+	for scanner.Scan() {
+		send(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading the logs: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Validity of the certificates issued by the internal CA
+const (
+	caCertificateLifetime     = 10 * 365 * 24 * time.Hour
+	clientCertificateLifetime = 365 * 24 * time.Hour
+	daemonCertificateLifetime = 365 * 24 * time.Hour
+	// the client certificate is renewed at startup when it expires within this window
+	certificateRenewalWindow = 30 * 24 * time.Hour
+)
+
+// clientCommonName is the subject of the client certificate the decision manager presents to the daemons
+const clientCommonName = "appjet-decision-manager"
+
+// CertificateAuthority is the internal CA of the decision manager. It issues the certificates of the
+// daemons and the client certificate the decision manager authenticates to them with.
+type CertificateAuthority struct {
+	Certificate *x509.Certificate
+	CertPEM     []byte
+	key         *ecdsa.PrivateKey
+	client      tls.Certificate
+}
+
+var (
+	pkiMutex sync.RWMutex
+	pki      *CertificateAuthority
+)
+
+// PKIDir returns the directory of the CA and client key pairs, APPJET_PKI_DIR or "pki"
+func PKIDir() string {
+	if dir := os.Getenv("APPJET_PKI_DIR"); dir != "" {
+		return dir
+	}
+
+	return "pki"
+}
+
+// LoadCertificateAuthority loads the internal CA and the client certificate from PKIDir, and creates
+// them the first time the decision manager starts
+func LoadCertificateAuthority() error {
+	dir := PKIDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating the pki directory: %w", err)
+	}
+
+	caCertFile, caKeyFile := filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key")
+	if _, err := os.Stat(caCertFile); errors.Is(err, os.ErrNotExist) {
+		if err := createCertificateAuthority(caCertFile, caKeyFile); err != nil {
+			return err
+		}
+	}
+
+	caCertPEM, caCert, caKey, err := readKeyPair(caCertFile, caKeyFile)
+	if err != nil {
+		return fmt.Errorf("error loading the internal CA: %w", err)
+	}
+	authority := &CertificateAuthority{Certificate: caCert, CertPEM: caCertPEM, key: caKey}
+
+	clientCertFile, clientKeyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	client, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	if err == nil {
+		client.Leaf, err = x509.ParseCertificate(client.Certificate[0])
+	}
+	if err != nil || time.Until(client.Leaf.NotAfter) < certificateRenewalWindow {
+		if err := authority.createClientCertificate(clientCertFile, clientKeyFile); err != nil {
+			return err
+		}
+		if client, err = tls.LoadX509KeyPair(clientCertFile, clientKeyFile); err != nil {
+			return fmt.Errorf("error loading the client certificate: %w", err)
+		}
+	}
+	authority.client = client
+
+	pkiMutex.Lock()
+	pki = authority
+	pkiMutex.Unlock()
+
+	return nil
+}
+
+// GetCertificateAuthority returns the internal CA loaded by LoadCertificateAuthority
+func GetCertificateAuthority() (*CertificateAuthority, error) {
+	pkiMutex.RLock()
+	defer pkiMutex.RUnlock()
+
+	if pki == nil {
+		return nil, errors.New("the internal CA is not loaded")
+	}
+
+	return pki, nil
+}
+
+// CertPool returns a pool trusting only the internal CA
+func (authority *CertificateAuthority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(authority.Certificate)
+	return pool
+}
+
+// IssueServerCertificate signs a daemon CSR. The certificate can only be used to serve TLS.
+func (authority *CertificateAuthority) IssueServerCertificate(csr *x509.CertificateRequest, commonName string) (*x509.Certificate, []byte, error) {
+	template, err := certificateTemplate(commonName, daemonCertificateLifetime)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	template.DNSNames = csr.DNSNames
+	template.IPAddresses = csr.IPAddresses
+
+	der, err := x509.CreateCertificate(rand.Reader, template, authority.Certificate, csr.PublicKey, authority.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error signing the certificate: %w", err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func createCertificateAuthority(certFile string, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating the CA key: %w", err)
+	}
+
+	template, err := certificateTemplate("appjet internal CA", caCertificateLifetime)
+	if err != nil {
+		return err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLenZero = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("error creating the CA certificate: %w", err)
+	}
+
+	return writeKeyPair(certFile, keyFile, der, key)
+}
+
+func (authority *CertificateAuthority) createClientCertificate(certFile string, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("error generating the client key: %w", err)
+	}
+
+	template, err := certificateTemplate(clientCommonName, clientCertificateLifetime)
+	if err != nil {
+		return err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, authority.Certificate, &key.PublicKey, authority.key)
+	if err != nil {
+		return fmt.Errorf("error creating the client certificate: %w", err)
+	}
+
+	return writeKeyPair(certFile, keyFile, der, key)
+}
+
+func certificateTemplate(commonName string, lifetime time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating a serial number: %w", err)
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"appjet"}},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(lifetime),
+	}, nil
+}
+
+func writeKeyPair(certFile string, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", keyFile, err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", certFile, err)
+	}
+
+	return nil
+}
+
+func readKeyPair(certFile string, keyFile string) ([]byte, *x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, nil, fmt.Errorf("%s is not a PEM certificate", certFile)
+	}
+	certificate, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, nil, fmt.Errorf("%s is not a PEM key", keyFile)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return certPEM, certificate, key, nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// useTestCertificateAuthority creates an internal CA in a temporary directory
+func useTestCertificateAuthority(t *testing.T) *CertificateAuthority {
+	t.Helper()
+
+	t.Setenv("APPJET_PKI_DIR", t.TempDir())
+	if err := LoadCertificateAuthority(); err != nil {
+		t.Fatalf("creating the CA: %s", err)
+	}
+	t.Cleanup(func() {
+		pkiMutex.Lock()
+		pki = nil
+		pkiMutex.Unlock()
+	})
+
+	authority, err := GetCertificateAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authority
+}
+
+// newDaemonCSR returns the key and the PEM CSR of a daemon, as "appjet-server-daemon tls request" makes them
+func newDaemonCSR(t *testing.T, hosts ...string) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "daemon"}}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+// startEnrolledDaemon serves TLS with the certificate issued for a CSR, and only accepts clients with a
+// certificate of the CA, as an enrolled daemon does
+func startEnrolledDaemon(t *testing.T, authority *CertificateAuthority, enrolled *EnrolledDaemon, key *ecdsa.PrivateKey) (*httptest.Server, DaemonTarget) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := tls.X509KeyPair([]byte(enrolled.Certificate), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		t.Fatalf("loading the issued certificate: %s", err)
+	}
+
+	daemon := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	daemon.TLS = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    authority.CertPool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	daemon.StartTLS()
+	t.Cleanup(daemon.Close)
+
+	address, err := url.Parse(daemon.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(address.Port())
+	return daemon, newDaemonTarget("prod", "server-1", "https", address.Hostname(), port, "")
+}
+
+func callDaemon(target DaemonTarget) (string, error) {
+	request, err := http.NewRequestWithContext(withDaemonTarget(context.Background(), target), http.MethodGet, target.URL("/api/check-alive"), nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := daemonClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	return string(body), err
+}
+
+func TestIssueDaemonCertificate(t *testing.T) {
+	useTestDatabase(t, &models.DaemonCertificate{})
+	authority := useTestCertificateAuthority(t)
+
+	_, csrPEM := newDaemonCSR(t, "daemon.internal")
+	enrolled, err := issueDaemonCertificate("prod", "server-1", "10.0.0.5", csrPEM)
+	if err != nil {
+		t.Fatalf("issuing the certificate: %s", err)
+	}
+
+	block, _ := pem.Decode([]byte(enrolled.Certificate))
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := certificate.Verify(x509.VerifyOptions{Roots: authority.CertPool(), DNSName: "daemon.internal"}); err != nil {
+		t.Errorf("the certificate is not valid for the CSR name: %s", err)
+	}
+	if _, err := certificate.Verify(x509.VerifyOptions{Roots: authority.CertPool(), DNSName: "10.0.0.5"}); err != nil {
+		t.Errorf("the certificate is not valid for the address of the server: %s", err)
+	}
+	if len(certificate.ExtKeyUsage) != 1 || certificate.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+		t.Errorf("the certificate must only serve TLS, got %v", certificate.ExtKeyUsage)
+	}
+
+	fingerprint, err := pinnedFingerprint(DaemonTarget{Cluster: "prod", Server: "server-1"})
+	if err != nil || fingerprint != enrolled.Fingerprint {
+		t.Errorf("pinned fingerprint %q (%v), want %q", fingerprint, err, enrolled.Fingerprint)
+	}
+}
+
+func TestIssueDaemonCertificateRejectsInvalidCSR(t *testing.T) {
+	useTestDatabase(t, &models.DaemonCertificate{})
+	useTestCertificateAuthority(t)
+
+	_, csrPEM := newDaemonCSR(t, "daemon.internal")
+	for name, csr := range map[string]string{
+		"not pem":     "not a csr",
+		"certificate": strings.Replace(csrPEM, "CERTIFICATE REQUEST", "CERTIFICATE", -1),
+	} {
+		if _, err := issueDaemonCertificate("prod", "server-1", "10.0.0.5", csr); !errors.Is(err, ErrInvalidCSR) {
+			t.Errorf("%s: got %v, want ErrInvalidCSR", name, err)
+		}
+	}
+}
+
+func TestDialDaemonTLS(t *testing.T) {
+	useTestDatabase(t, &models.DaemonCertificate{})
+	authority := useTestCertificateAuthority(t)
+	t.Cleanup(daemonTransport.CloseIdleConnections)
+
+	key, csrPEM := newDaemonCSR(t)
+	enrolled, err := issueDaemonCertificate("prod", "server-1", "127.0.0.1", csrPEM)
+	if err != nil {
+		t.Fatalf("issuing the certificate: %s", err)
+	}
+	_, target := startEnrolledDaemon(t, authority, enrolled, key)
+
+	// mutual TLS: the daemon sees the client certificate of the decision manager
+	commonName, err := callDaemon(target)
+	if err != nil {
+		t.Fatalf("calling the enrolled daemon: %s", err)
+	}
+	if commonName != clientCommonName {
+		t.Errorf("the daemon saw client %q, want %q", commonName, clientCommonName)
+	}
+
+	// a certificate of the same CA that is not the pinned one is refused
+	err = GetDBConnection().Model(&models.DaemonCertificate{}).Where("cluster = ? AND server = ?", "prod", "server-1").
+		Update("fingerprint", strings.Repeat("0", 64)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonTransport.CloseIdleConnections()
+	if _, err := callDaemon(target); err == nil || !strings.Contains(err.Error(), "does not present its enrolled certificate") {
+		t.Errorf("calling a daemon with another fingerprint: got %v, want the enrolled certificate error", err)
+	}
+
+	// and a server that was never enrolled is not called at all
+	target.Server = "server-2"
+	if _, err := callDaemon(target); !errors.Is(err, ErrDaemonCertificateNotFound) {
+		t.Errorf("calling a server that is not enrolled: got %v, want ErrDaemonCertificateNotFound", err)
+	}
+}
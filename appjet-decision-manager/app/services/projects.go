@@ -0,0 +1,153 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrProjectNotFound is returned for unknown projects
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrProjectExists is returned when a project is created with the name of another one
+	ErrProjectExists = errors.New("a project with this name already exists")
+	// ErrInvalidProjectRequest wraps the problems found in a ProjectRequest
+	ErrInvalidProjectRequest = errors.New("invalid project request")
+	// ErrProjectBusy is returned when a project with unfinished jobs is deleted
+	ErrProjectBusy = errors.New("the project has jobs that did not finish")
+)
+
+// ProjectRequest describes a project to create
+type ProjectRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// ProjectsDir returns the directory of the files of the projects, APPJET_PROJECTS_DIR or "projects"
+func ProjectsDir() string {
+	if dir := os.Getenv("APPJET_PROJECTS_DIR"); dir != "" {
+		return dir
+	}
+
+	return "projects"
+}
+
+// ProjectCodeDir returns the directory of the code sent to the servers of a project, :project/code in ProjectsDir
+func ProjectCodeDir(project string) string {
+	return filepath.Join(ProjectsDir(), project, "code")
+}
+
+// ListProjects returns the projects ordered by name
+func ListProjects() ([]models.Project, error) {
+	projects := []models.Project{}
+	if err := GetDBConnection().Order("name").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// ProjectExists reports whether a project was created and not deleted since
+func ProjectExists(name string) bool {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	_, ok := projectConfigs[name]
+	return ok
+}
+
+// CreateProject creates a project without configuration, the first PUT /appjet/projects/:project/config
+// or configure stores one
+func CreateProject(request ProjectRequest, createdBy string) (*models.Project, error) {
+	request.Name = strings.TrimSpace(request.Name)
+	if !models.ValidProjectName(request.Name) {
+		return nil, fmt.Errorf("%w: the name must be 1 to 63 lowercase letters, digits, - and _, starting with a letter or a digit", ErrInvalidProjectRequest)
+	}
+	if len(request.Description) > 255 {
+		return nil, fmt.Errorf("%w: the description is longer than 255 characters", ErrInvalidProjectRequest)
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	if _, ok := projectConfigs[request.Name]; ok {
+		return nil, ErrProjectExists
+	}
+
+	project := models.Project{
+		Name:        request.Name,
+		Description: request.Description,
+		CreatedBy:   createdBy,
+	}
+	if err := GetDBConnection().Create(&project).Error; err != nil {
+		return nil, fmt.Errorf("error persisting project: %w", err)
+	}
+	projectConfigs[project.Name] = nil
+
+	return &project, nil
+}
+
+// DeleteProject deletes a project and forgets its configuration. Its revisions, jobs and health history
+// are kept, and its containers keep running on the servers: clean them before deleting the project.
+func DeleteProject(name string) error {
+	if name == models.DefaultProject {
+		return fmt.Errorf("%w: the default project cannot be deleted", ErrInvalidProjectRequest)
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	if _, ok := projectConfigs[name]; !ok {
+		return ErrProjectNotFound
+	}
+
+	var unfinished int64
+	err := GetDBConnection().Model(&models.Job{}).
+		Where("project = ? AND status IN ?", name, []string{models.JobStatusPending, models.JobStatusRunning}).
+		Count(&unfinished).Error
+	if err != nil {
+		return err
+	}
+	if unfinished > 0 {
+		return ErrProjectBusy
+	}
+
+	if err := GetDBConnection().Where("name = ?", name).Delete(&models.Project{}).Error; err != nil {
+		return fmt.Errorf("error deleting project: %w", err)
+	}
+	delete(projectConfigs, name)
+
+	return nil
+}
+
+// findServerTarget returns the daemon of a server in the configuration of the first project that has it
+func findServerTarget(cluster string, server string) (DaemonTarget, bool) {
+	if cluster == "" || server == "" {
+		return DaemonTarget{}, false
+	}
+
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	projects := make([]string, 0, len(projectConfigs))
+	for project := range projectConfigs {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+
+	for _, project := range projects {
+		config := projectConfigs[project]
+		if config == nil {
+			config = &models.Configuration{}
+		}
+		if targets := ResolveTargets(project, config, cluster, server); len(targets) == 1 {
+			return targets[0], true
+		}
+	}
+
+	return DaemonTarget{}, false
+}
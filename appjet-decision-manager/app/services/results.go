@@ -0,0 +1,118 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// NewServerResult classifies a dispatch result into the shared per-server result model.
+func NewServerResult(result DispatchResult) models.ServerResult {
+	serverResult := models.ServerResult{
+		Cluster:    result.Target.Cluster,
+		Server:     result.Target.Server,
+		Body:       parseDaemonBody(result.Body),
+		DurationMs: result.Duration.Milliseconds(),
+	}
+
+	if result.Response != nil {
+		serverResult.HTTPCode = result.Response.StatusCode
+	}
+
+	switch {
+	case result.Err != nil && isTimeout(result.Err):
+		serverResult.Status = models.ServerStatusTimeout
+		serverResult.Error = result.Err.Error()
+	case result.Err != nil && result.Response == nil:
+		serverResult.Status = models.ServerStatusUnreachable
+		serverResult.Error = result.Err.Error()
+	case result.Err != nil:
+		serverResult.Status = models.ServerStatusFailed
+		serverResult.Error = result.Err.Error()
+	case result.Response.StatusCode >= http.StatusMultipleChoices:
+		serverResult.Status = models.ServerStatusFailed
+		serverResult.Error = daemonErrorMessage(result.Response, serverResult.Body)
+	default:
+		serverResult.Status = models.ServerStatusOK
+	}
+
+	return serverResult
+}
+
+// NewFanOutResponse builds the shared response for a set of dispatch results, keeping their order.
+func NewFanOutResponse(results []DispatchResult) models.FanOutResponse {
+	response := models.FanOutResponse{
+		Total:   len(results),
+		Results: make([]models.ServerResult, 0, len(results)),
+	}
+
+	for _, result := range results {
+		serverResult := NewServerResult(result)
+		if serverResult.Status == models.ServerStatusOK {
+			response.Ok++
+		} else {
+			response.Failed++
+		}
+		response.Results = append(response.Results, serverResult)
+	}
+
+	switch {
+	case response.Total > 0 && response.Failed == 0:
+		response.Outcome = models.OutcomeSuccess
+	case response.Ok > 0:
+		response.Outcome = models.OutcomePartial
+	default:
+		response.Outcome = models.OutcomeFailed
+	}
+
+	return response
+}
+
+// FanOutStatusCode maps an overall outcome to the HTTP status code returned to the client.
+func FanOutStatusCode(outcome string) int {
+	switch outcome {
+	case models.OutcomeSuccess:
+		return http.StatusOK
+	case models.OutcomePartial:
+		return http.StatusMultiStatus
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// parseDaemonBody returns the daemon body as JSON when possible, as plain text otherwise.
+func parseDaemonBody(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var jsonBody interface{}
+	if err := json.Unmarshal(body, &jsonBody); err != nil {
+		return string(body)
+	}
+
+	return jsonBody
+}
+
+// daemonErrorMessage prefers the "error" field the daemons put in their JSON errors.
+func daemonErrorMessage(response *http.Response, body interface{}) string {
+	if jsonBody, ok := body.(map[string]interface{}); ok {
+		if message, ok := jsonBody["error"].(string); ok && message != "" {
+			return message
+		}
+	}
+
+	return response.Status
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// RecordConfigRevision stores the configuration of a project as a new immutable revision.
+func RecordConfigRevision(project string, config *models.Configuration, author string, cluster string, server string, comment string) (*models.ConfigRevision, error) {
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling configuration: %w", err)
+	}
+
+	hash := sha256.Sum256(configJSON)
+
+	revision := models.ConfigRevision{
+		Project: project,
+		Hash:    hex.EncodeToString(hash[:]),
+		Config:  string(configJSON),
+		Author:  author,
+		Cluster: cluster,
+		Server:  server,
+		Comment: comment,
+	}
+
+	if err := GetDBConnection().Create(&revision).Error; err != nil {
+		return nil, fmt.Errorf("error persisting configuration revision: %w", err)
+	}
+
+	return &revision, nil
+}
+
+// ListConfigRevisions returns the most recent revisions of a project, without their configuration.
+func ListConfigRevisions(project string, limit int) ([]models.ConfigRevision, error) {
+	var revisions []models.ConfigRevision
+	err := GetDBConnection().Omit("config").Where("project = ?", project).Order("id desc").Limit(limit).Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetConfigRevision returns a revision of a project and its parsed configuration.
+func GetConfigRevision(project string, id uint) (*models.ConfigRevision, *models.Configuration, error) {
+	var revision models.ConfigRevision
+	if err := GetDBConnection().Where("id = ? AND project = ?", id, project).First(&revision).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var config models.Configuration
+	if err := json.Unmarshal([]byte(revision.Config), &config); err != nil {
+		return nil, nil, fmt.Errorf("error parsing revision %d: %w", id, err)
+	}
+
+	return &revision, &config, nil
+}
+
+// RollbackCall re-pushes the configuration of an older revision to a daemon and starts it
+// again; the daemon start runs "docker compose up --build", which recreates the containers.
+func RollbackCall(config *models.Configuration) DaemonCall {
+	return func(ctx context.Context, target DaemonTarget) (*http.Response, []byte, error) {
+		response, body, err := ForwardConfigToDaemon(ctx, config, target.ConfigureURL())
+		if err != nil || response.StatusCode >= http.StatusMultipleChoices {
+			return response, body, err
+		}
+
+		return ForwardStartToDaemon(ctx, target.URL("/api/start"))
+	}
+}
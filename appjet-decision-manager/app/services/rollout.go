@@ -0,0 +1,241 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// healthCheckInterval is how often a rolled out batch is polled through check-alive
+const healthCheckInterval = 5 * time.Second
+
+// RolloutOptions controls a rolling deployment
+type RolloutOptions struct {
+	// MaxUnavailable is the batch size: how many servers are configured and restarted at once
+	MaxUnavailable int `json:"maxUnavailable"`
+	// HealthTimeout is how long a batch may take to report all containers running (e.g. "5m")
+	HealthTimeout string `json:"healthTimeout"`
+}
+
+// Validate fills the defaults and checks the options, returning the parsed health timeout.
+func (o *RolloutOptions) Validate() (time.Duration, error) {
+	if o.MaxUnavailable == 0 {
+		o.MaxUnavailable = 1
+	}
+	if o.MaxUnavailable < 0 {
+		return 0, fmt.Errorf("maxUnavailable must be a positive number")
+	}
+
+	if o.HealthTimeout == "" {
+		o.HealthTimeout = "5m"
+	}
+	healthTimeout, err := time.ParseDuration(o.HealthTimeout)
+	if err != nil || healthTimeout <= 0 {
+		return 0, fmt.Errorf("healthTimeout must be a positive duration such as \"5m\"")
+	}
+
+	return healthTimeout, nil
+}
+
+// StartRollout persists a rollout job over the given targets and runs it in the background, one
+// batch of MaxUnavailable servers at a time. Each batch is configured, started and must pass the
+// check-alive health gate before the next one begins; the rollout halts on the first failed batch.
+func StartRollout(request JobRequest, config *models.Configuration, targets []DaemonTarget, options RolloutOptions) (*models.Job, error) {
+	healthTimeout, err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	batches := splitIntoBatches(targets, options.MaxUnavailable)
+
+	job := newJob(request, targets)
+	for index := range job.Tasks {
+		job.Tasks[index].Batch = index/options.MaxUnavailable + 1
+	}
+
+	// Creating the job also creates its tasks
+	if err := GetDBConnection().Create(&job).Error; err != nil {
+		return nil, fmt.Errorf("error persisting job: %w", err)
+	}
+
+	go runRollout(job.ID, config, batches, healthTimeout)
+
+	return &job, nil
+}
+
+func splitIntoBatches(targets []DaemonTarget, size int) [][]DaemonTarget {
+	var batches [][]DaemonTarget
+	for start := 0; start < len(targets); start += size {
+		end := start + size
+		if end > len(targets) {
+			end = len(targets)
+		}
+		batches = append(batches, targets[start:end])
+	}
+
+	return batches
+}
+
+func runRollout(jobID string, config *models.Configuration, batches [][]DaemonTarget, healthTimeout time.Duration) {
+	markJobRunning(jobID)
+
+	position := 0
+	for batchIndex, batch := range batches {
+		err := rollOutBatch(jobID, position, batch, config, healthTimeout)
+		if err != nil {
+			outcome := models.OutcomeFailed
+			if batchIndex > 0 {
+				outcome = models.OutcomePartial
+			}
+			// the tasks of the batches that never started are not left pending
+			skipped := position + len(batch)
+			for _, remaining := range batches[batchIndex+1:] {
+				for range remaining {
+					updateTask(jobID, skipped, skippedTaskUpdates(batchIndex+1))
+					skipped++
+				}
+			}
+			finishJob(jobID, outcome, fmt.Errorf("rollout halted at batch %d of %d: %w", batchIndex+1, len(batches), err))
+			return
+		}
+		position += len(batch)
+	}
+
+	finishJob(jobID, models.OutcomeSuccess, nil)
+}
+
+// rollOutBatch configures, starts and health-checks one batch. Tasks are matched by position,
+// starting at firstPosition.
+func rollOutBatch(jobID string, firstPosition int, batch []DaemonTarget, config *models.Configuration, healthTimeout time.Duration) error {
+	for index := range batch {
+		updateTask(jobID, firstPosition+index, map[string]interface{}{"status": models.JobStatusRunning, "started_at": time.Now()})
+	}
+
+	configure := func(ctx context.Context, target DaemonTarget) (*http.Response, []byte, error) {
+		return ForwardConfigToDaemon(ctx, config, target.ConfigureURL())
+	}
+	if err := runBatchPhase(jobID, firstPosition, batch, "configure", configure); err != nil {
+		return err
+	}
+
+	start := func(ctx context.Context, target DaemonTarget) (*http.Response, []byte, error) {
+		return ForwardStartToDaemon(ctx, target.URL("/api/start"))
+	}
+	if err := runBatchPhase(jobID, firstPosition, batch, "start", start); err != nil {
+		return err
+	}
+
+	return waitForHealthyBatch(jobID, firstPosition, batch, healthTimeout)
+}
+
+// runBatchPhase forwards one step of the rollout to every server of the batch. When a server
+// fails, its task keeps the daemon result and the rest of the batch is marked as halted.
+func runBatchPhase(jobID string, firstPosition int, batch []DaemonTarget, phase string, call DaemonCall) error {
+	for index := range batch {
+		updateTask(jobID, firstPosition+index, map[string]interface{}{"phase": phase})
+	}
+
+	response := NewFanOutResponse(Dispatch(context.Background(), batch, call))
+	if response.Outcome == models.OutcomeSuccess {
+		return nil
+	}
+
+	for index, result := range response.Results {
+		if result.Status != models.ServerStatusOK {
+			updateTask(jobID, firstPosition+index, taskUpdates(result))
+		} else {
+			updateTask(jobID, firstPosition+index, haltedTaskUpdates(phase))
+		}
+	}
+
+	return fmt.Errorf("%s failed on %d of %d servers", phase, response.Failed, response.Total)
+}
+
+// waitForHealthyBatch polls check-alive until every container of every server in the batch is
+// running, or the health timeout expires.
+func waitForHealthyBatch(jobID string, firstPosition int, batch []DaemonTarget, healthTimeout time.Duration) error {
+	for index := range batch {
+		updateTask(jobID, firstPosition+index, map[string]interface{}{"phase": "health-check"})
+	}
+
+	checkAlive := func(ctx context.Context, target DaemonTarget) (*http.Response, []byte, error) {
+		return ForwardCheckAliveToDaemon(ctx, target.URL("/api/check-alive"))
+	}
+
+	deadline := time.Now().Add(healthTimeout)
+	for {
+		response := NewFanOutResponse(Dispatch(context.Background(), batch, checkAlive))
+
+		unhealthy := 0
+		for _, result := range response.Results {
+			if result.Status != models.ServerStatusOK || !allContainersRunning(result.Body) {
+				unhealthy++
+			}
+		}
+
+		if unhealthy == 0 {
+			for index, result := range response.Results {
+				updateTask(jobID, firstPosition+index, taskUpdates(result))
+			}
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			for index, result := range response.Results {
+				updates := taskUpdates(result)
+				if result.Status == models.ServerStatusOK && !allContainersRunning(result.Body) {
+					updates["status"] = models.JobStatusFailed
+					updates["error"] = fmt.Sprintf("containers not running after %s", healthTimeout)
+				}
+				updateTask(jobID, firstPosition+index, updates)
+			}
+			return fmt.Errorf("%d of %d servers not healthy after %s", unhealthy, len(batch), healthTimeout)
+		}
+
+		time.Sleep(healthCheckInterval)
+	}
+}
+
+// allContainersRunning reads the daemon check-alive body:
+// {"docker-containers-status": {"app": {"status": true}, ...}}
+func allContainersRunning(body interface{}) bool {
+	jsonBody, ok := body.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	containers, ok := jsonBody["docker-containers-status"].(map[string]interface{})
+	if !ok || len(containers) == 0 {
+		return false
+	}
+
+	for _, state := range containers {
+		containerState, ok := state.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if running, _ := containerState["status"].(bool); !running {
+			return false
+		}
+	}
+
+	return true
+}
+
+func haltedTaskUpdates(phase string) map[string]interface{} {
+	return map[string]interface{}{
+		"status":      models.JobStatusFailed,
+		"error":       fmt.Sprintf("halted: %s failed on another server of the batch", phase),
+		"finished_at": time.Now(),
+	}
+}
+
+func skippedTaskUpdates(haltedBatch int) map[string]interface{} {
+	return map[string]interface{}{
+		"status":      models.JobStatusSkipped,
+		"error":       fmt.Sprintf("skipped: the rollout halted at batch %d", haltedBatch),
+		"finished_at": time.Now(),
+	}
+}
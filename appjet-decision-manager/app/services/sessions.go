@@ -0,0 +1,187 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"os"
+	"time"
+)
+
+// lastSeenResolution limits how often a session in use is written back to the database
+const lastSeenResolution = time.Minute
+
+var (
+	// ErrSessionNotFound is returned for unknown or revoked tokens
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExpired is returned for sessions past their idle or absolute expiry
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// SessionSettings controls how long sessions live.
+type SessionSettings struct {
+	IdleTimeout time.Duration
+	MaxAge      time.Duration
+}
+
+// GetSessionSettings reads the session settings from the environment, falling back to defaults:
+//
+//	APPJET_SESSION_IDLE_TIMEOUT  a session unused for this long expires (default 30m)
+//	APPJET_SESSION_MAX_AGE       a session expires this long after login, refreshing it does not extend it (default 12h)
+func GetSessionSettings() SessionSettings {
+	settings := SessionSettings{
+		IdleTimeout: 30 * time.Minute,
+		MaxAge:      12 * time.Hour,
+	}
+
+	if value, err := time.ParseDuration(os.Getenv("APPJET_SESSION_IDLE_TIMEOUT")); err == nil && value > 0 {
+		settings.IdleTimeout = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("APPJET_SESSION_MAX_AGE")); err == nil && value > 0 {
+		settings.MaxAge = value
+	}
+
+	return settings
+}
+
+// CreateSession starts a session for the user and returns it with its token
+func CreateSession(userID uint, clientIP string, userAgent string) (*models.UserSession, error) {
+	return createSession(userID, clientIP, userAgent, time.Now().Add(GetSessionSettings().MaxAge))
+}
+
+func createSession(userID uint, clientIP string, userAgent string, expiresAt time.Time) (*models.UserSession, error) {
+	session := models.UserSession{
+		UserID:     userID,
+		Token:      uuid.New().String(),
+		LastSeenAt: time.Now(),
+		ExpiresAt:  expiresAt,
+		ClientIP:   clientIP,
+		UserAgent:  truncate(userAgent, 255),
+	}
+
+	if err := GetDBConnection().Unscoped().Create(&session).Error; err != nil {
+		return nil, fmt.Errorf("error inserting token into the database: %w", err)
+	}
+
+	return &session, nil
+}
+
+// ValidateSession returns the session of the token and its user. Expired sessions are deleted.
+func ValidateSession(token string) (*models.UserSession, *models.User, error) {
+	var session models.UserSession
+	result := GetDBConnection().Unscoped().Where("token = ?", token).Limit(1).Find(&session)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrSessionNotFound
+	}
+
+	now := time.Now()
+	if sessionExpired(&session, now) {
+		if err := DeleteSession(token); err != nil {
+			log.Printf("Error deleting expired session %d: %s", session.ID, err)
+		}
+		return nil, nil, ErrSessionExpired
+	}
+
+	var user models.User
+	result = GetDBConnection().Unscoped().Where("id = ?", session.UserID).Limit(1).Find(&user)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrSessionNotFound
+	}
+
+	if now.Sub(session.LastSeenAt) > lastSeenResolution {
+		session.LastSeenAt = now
+		err := GetDBConnection().Model(&models.UserSession{}).Unscoped().Where("id = ?", session.ID).
+			UpdateColumn("last_seen_at", now).Error
+		if err != nil {
+			log.Printf("Error updating session %d: %s", session.ID, err)
+		}
+	}
+
+	return &session, &user, nil
+}
+
+// RefreshSession replaces a valid session by a new one with a new token and a reset idle timer. The
+// absolute expiry of the login is kept, a session cannot be refreshed past it.
+func RefreshSession(session *models.UserSession, clientIP string, userAgent string) (*models.UserSession, error) {
+	refreshed, err := createSession(session.UserID, clientIP, userAgent, session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := DeleteSession(session.Token); err != nil {
+		return nil, err
+	}
+
+	return refreshed, nil
+}
+
+// DeleteSession ends the session of the token
+func DeleteSession(token string) error {
+	return GetDBConnection().Unscoped().Where("token = ?", token).Delete(&models.UserSession{}).Error
+}
+
+// ListSessions returns the active sessions of a user, most recent first
+func ListSessions(userID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := GetDBConnection().Unscoped().Where("user_id = ?", userID).Order("created_at desc").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := sessions[:0]
+	for _, session := range sessions {
+		if !sessionExpired(&session, now) {
+			active = append(active, session)
+		}
+	}
+
+	return active, nil
+}
+
+// GetSession returns a session by id
+func GetSession(id uint) (*models.UserSession, error) {
+	var session models.UserSession
+	result := GetDBConnection().Unscoped().Where("id = ?", id).Limit(1).Find(&session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	return &session, nil
+}
+
+// RevokeSession ends a session by id
+func RevokeSession(id uint) error {
+	return GetDBConnection().Unscoped().Where("id = ?", id).Delete(&models.UserSession{}).Error
+}
+
+// PurgeExpiredSessions deletes the sessions past their absolute or idle expiry
+func PurgeExpiredSessions() error {
+	now := time.Now()
+	return GetDBConnection().Unscoped().
+		Where("expires_at < ? OR last_seen_at < ?", now, now.Add(-GetSessionSettings().IdleTimeout)).
+		Delete(&models.UserSession{}).Error
+}
+
+func sessionExpired(session *models.UserSession, now time.Time) bool {
+	return now.After(session.ExpiresAt) || now.Sub(session.LastSeenAt) > GetSessionSettings().IdleTimeout
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+
+	return value
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefreshSessionKeepsAbsoluteExpiry(t *testing.T) {
+	useTestDatabase(t, &models.UserSession{}, &models.User{})
+	user := models.User{Username: "alice", Password: "-", Role: models.RoleViewer, Name: "Alice"}
+	if err := GetDBConnection().Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := CreateSession(user.ID, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.LastSeenAt = session.LastSeenAt.Add(-time.Minute)
+
+	refreshed, err := RefreshSession(session, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Token == session.Token {
+		t.Error("the refresh did not rotate the token")
+	}
+	if !refreshed.ExpiresAt.Equal(session.ExpiresAt) {
+		t.Errorf("the refresh moved the absolute expiry from %s to %s", session.ExpiresAt, refreshed.ExpiresAt)
+	}
+	if !refreshed.LastSeenAt.After(session.LastSeenAt) {
+		t.Error("the refresh did not reset the idle timer")
+	}
+	if _, _, err := ValidateSession(session.Token); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("the previous token is still valid: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"strings"
+)
+
+// minPasswordLength is the shortest password accepted when creating users or changing passwords
+const minPasswordLength = 8
+
+var (
+	// ErrInvalidCredentials is returned when the username or the password is wrong
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrUserExists is returned when creating a user whose username is taken
+	ErrUserExists = errors.New("user already exists")
+	// ErrUserNotFound is returned when the user does not exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUnknownRole is returned for roles missing from models.RolePermissions
+	ErrUnknownRole = errors.New("unknown role, expected admin, operator or viewer")
+	// ErrWeakPassword is returned for passwords shorter than minPasswordLength
+	ErrWeakPassword = fmt.Errorf("password must have at least %d characters", minPasswordLength)
+	// ErrSamePassword is returned when the new password is the current one
+	ErrSamePassword = errors.New("new password must be different from the current one")
+)
+
+// HashPassword returns the bcrypt hash of password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// isPasswordHash tells bcrypt hashes apart from the plaintext passwords stored before hashing existed
+func isPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$")
+}
+
+func passwordMatches(stored string, password string) bool {
+	if isPasswordHash(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	}
+
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
+
+// AuthenticateUser checks the username and password. A user still stored with a plaintext
+// password gets it replaced by its hash.
+func AuthenticateUser(username string, password string) (*models.User, error) {
+	user, err := GetUser(username)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !passwordMatches(user.Password, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !isPasswordHash(user.Password) {
+		if hash, err := HashPassword(password); err == nil {
+			err = GetDBConnection().Model(&models.User{}).Unscoped().Where("id = ?", user.ID).UpdateColumn("password", hash).Error
+			if err != nil {
+				log.Printf("Error upgrading the password of %s: %s", username, err)
+			}
+		}
+	}
+
+	return user, nil
+}
+
+// GetUser returns the user with the given username
+func GetUser(username string) (*models.User, error) {
+	var user models.User
+	result := GetDBConnection().Unscoped().Where("username = ?", username).Limit(1).Find(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+// ListUsers returns every user ordered by username
+func ListUsers() ([]models.User, error) {
+	var users []models.User
+	if err := GetDBConnection().Unscoped().Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CreateUser creates a user with a hashed password. The user must change it on the first login.
+func CreateUser(username string, name string, role string, password string) (*models.User, error) {
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+	if _, ok := models.RolePermissions[role]; !ok {
+		return nil, ErrUnknownRole
+	}
+	if len(password) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	if _, err := GetUser(username); err == nil {
+		return nil, ErrUserExists
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Username:           username,
+		Password:           hash,
+		Role:               role,
+		Name:               name,
+		MustChangePassword: true,
+	}
+	// the users table created by init.sql has no timestamp columns
+	err = GetDBConnection().Unscoped().Omit("CreatedAt", "UpdatedAt", "DeletedAt").Create(&user).Error
+	if err != nil {
+		return nil, fmt.Errorf("error creating user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// DeleteUser deletes a user with its sessions and api tokens
+func DeleteUser(username string) error {
+	user, err := GetUser(username)
+	if err != nil {
+		return err
+	}
+
+	if err := GetDBConnection().Unscoped().Where("user_id = ?", user.ID).Delete(&models.UserSession{}).Error; err != nil {
+		return fmt.Errorf("error deleting user sessions: %w", err)
+	}
+	if err := GetDBConnection().Where("user_id = ?", user.ID).Delete(&models.APIToken{}).Error; err != nil {
+		return fmt.Errorf("error deleting user api tokens: %w", err)
+	}
+
+	return GetDBConnection().Unscoped().Delete(&models.User{}, user.ID).Error
+}
+
+// SetUserRole changes the role of a user
+func SetUserRole(username string, role string) error {
+	if _, ok := models.RolePermissions[role]; !ok {
+		return ErrUnknownRole
+	}
+
+	user, err := GetUser(username)
+	if err != nil {
+		return err
+	}
+
+	return GetDBConnection().Model(&models.User{}).Unscoped().Where("id = ?", user.ID).UpdateColumn("role", role).Error
+}
+
+// ResetPassword sets a temporary password chosen by an admin, the user must change it on the next login
+func ResetPassword(username string, password string) error {
+	user, err := GetUser(username)
+	if err != nil {
+		return err
+	}
+
+	return updatePassword(user, password, true)
+}
+
+// ChangePassword is the self-service password change, it requires the current password
+func ChangePassword(user *models.User, currentPassword string, newPassword string) error {
+	if !passwordMatches(user.Password, currentPassword) {
+		return ErrInvalidCredentials
+	}
+	if currentPassword == newPassword {
+		return ErrSamePassword
+	}
+
+	return updatePassword(user, newPassword, false)
+}
+
+func updatePassword(user *models.User, password string, mustChange bool) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return GetDBConnection().Model(&models.User{}).Unscoped().Where("id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{"password": hash, "must_change_password": mustChange}).Error
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Headers of the webhook deliveries
+const (
+	WebhookEventHeader     = "X-Appjet-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Appjet-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Appjet-Webhook-Timestamp"
+	// WebhookSignatureHeader is "sha256=" followed by the hex HMAC-SHA256 of "timestamp.body" with the webhook secret
+	WebhookSignatureHeader = "X-Appjet-Webhook-Signature"
+)
+
+var (
+	// ErrWebhookNotFound is returned for unknown webhooks
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrInvalidWebhookRequest wraps the problems found in a WebhookRequest
+	ErrInvalidWebhookRequest = errors.New("invalid webhook request")
+)
+
+// WebhookSettings controls the deliveries of the webhooks
+type WebhookSettings struct {
+	Attempts int
+	Backoff  time.Duration
+	Timeout  time.Duration
+}
+
+// GetWebhookSettings reads the webhook settings from the environment, falling back to defaults:
+//
+//	APPJET_WEBHOOK_ATTEMPTS  attempts per delivery before it is marked failed (default 5)
+//	APPJET_WEBHOOK_BACKOFF   wait before the first retry, doubled after every attempt (default 2s)
+//	APPJET_WEBHOOK_TIMEOUT   timeout of a single attempt (default 10s)
+func GetWebhookSettings() WebhookSettings {
+	settings := WebhookSettings{
+		Attempts: 5,
+		Backoff:  2 * time.Second,
+		Timeout:  10 * time.Second,
+	}
+
+	if value, err := strconv.Atoi(os.Getenv("APPJET_WEBHOOK_ATTEMPTS")); err == nil && value > 0 {
+		settings.Attempts = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("APPJET_WEBHOOK_BACKOFF")); err == nil && value > 0 {
+		settings.Backoff = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("APPJET_WEBHOOK_TIMEOUT")); err == nil && value > 0 {
+		settings.Timeout = value
+	}
+
+	return settings
+}
+
+// WebhookRequest describes a webhook to create
+type WebhookRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	Format string   `json:"format"` // json when empty
+	Secret string   `json:"secret"` // generated when empty
+}
+
+// ServerEventData is the data of the server.down and server.up events
+type ServerEventData struct {
+	Project       string `json:"project"`
+	Cluster       string `json:"cluster"`
+	Server        string `json:"server"`
+	State         string `json:"state"`
+	PreviousState string `json:"previous-state,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// JobEventData is the data of the events of finished jobs: deploys, scripts and cleans
+type JobEventData struct {
+	JobID     string                     `json:"job-id"`
+	Project   string                     `json:"project"`
+	Command   string                     `json:"command"`
+	Argument  string                     `json:"argument,omitempty"`
+	Cluster   string                     `json:"cluster,omitempty"`
+	Server    string                     `json:"server,omitempty"`
+	Outcome   string                     `json:"outcome"`
+	Error     string                     `json:"error,omitempty"`
+	CreatedBy string                     `json:"created-by"`
+	Results   []models.AuditServerResult `json:"results"`
+}
+
+// Label prefixes the chat messages of the server events with the project, outside the default one
+func (d ServerEventData) Label() string {
+	return projectLabel(d.Project)
+}
+
+// Label prefixes the chat messages of the job events with the project, outside the default one
+func (d JobEventData) Label() string {
+	return projectLabel(d.Project)
+}
+
+func projectLabel(project string) string {
+	if project == "" || project == models.DefaultProject {
+		return "appjet"
+	}
+
+	return "appjet/" + project
+}
+
+// Scope describes the servers the job ran on, for the chat messages
+func (d JobEventData) Scope() string {
+	switch {
+	case d.Server != "":
+		return "server " + d.Server + " of cluster " + d.Cluster
+	case d.Cluster != "":
+		return "cluster " + d.Cluster
+	default:
+		return "all clusters"
+	}
+}
+
+// webhookMessages are the messages sent to the chat webhooks, executed with the models.WebhookEvent
+var webhookMessages = template.Must(template.New("").Parse(`
+{{define "server.down"}}:red_circle: {{.Data.Label}}: server {{.Data.Server}} of cluster {{.Data.Cluster}} is down{{with .Data.Reason}}: {{.}}{{end}}{{end}}
+{{define "server.up"}}:large_green_circle: {{.Data.Label}}: server {{.Data.Server}} of cluster {{.Data.Cluster}} is up{{end}}
+{{define "deploy.succeeded"}}:rocket: {{.Data.Label}}: {{.Data.Command}} by {{.Data.CreatedBy}} on {{.Data.Scope}} succeeded{{end}}
+{{define "deploy.failed"}}:x: {{.Data.Label}}: {{.Data.Command}} by {{.Data.CreatedBy}} on {{.Data.Scope}} failed ({{.Data.Outcome}}){{with .Data.Error}}: {{.}}{{end}}{{end}}
+{{define "script.completed"}}:scroll: {{.Data.Label}}: script {{.Data.Argument}} run by {{.Data.CreatedBy}} on {{.Data.Scope}} completed: {{.Data.Outcome}}{{end}}
+{{define "clean.executed"}}:broom: {{.Data.Label}}: clean by {{.Data.CreatedBy}} on {{.Data.Scope}}: {{.Data.Outcome}}{{end}}
+{{define "webhook.test"}}:wave: appjet: test notification of webhook {{.Data.webhook}}{{end}}
+`))
+
+// CreateWebhook stores a webhook and returns it with its secret, which cannot be read again
+func CreateWebhook(request WebhookRequest, createdBy string) (*models.Webhook, string, error) {
+	if err := validateWebhookRequest(&request); err != nil {
+		return nil, "", err
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return nil, "", fmt.Errorf("error generating webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(random)
+	}
+
+	webhook := models.Webhook{
+		Name:      request.Name,
+		URL:       request.URL,
+		Events:    strings.Join(request.Events, ","),
+		Format:    request.Format,
+		Secret:    secret,
+		CreatedBy: createdBy,
+	}
+	if err := GetDBConnection().Create(&webhook).Error; err != nil {
+		return nil, "", fmt.Errorf("error persisting webhook: %w", err)
+	}
+
+	return &webhook, secret, nil
+}
+
+// ListWebhooks returns the webhooks, oldest first
+func ListWebhooks() ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := GetDBConnection().Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook by id
+func GetWebhook(id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	result := GetDBConnection().Where("id = ?", id).Limit(1).Find(&webhook)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return &webhook, nil
+}
+
+// DeleteWebhook deletes a webhook with its delivery log
+func DeleteWebhook(id uint) error {
+	result := GetDBConnection().Delete(&models.Webhook{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return GetDBConnection().Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+}
+
+// ListWebhookDeliveries returns the last deliveries of a webhook, most recent first
+func ListWebhookDeliveries(webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := GetDBConnection().Where("webhook_id = ?", webhookID).Order("created_at desc, id desc").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// TestWebhook sends a webhook.test event to the webhook and returns its delivery, which completes in
+// the background like any other
+func TestWebhook(webhook *models.Webhook) (*models.WebhookDelivery, error) {
+	return startWebhookDelivery(*webhook, models.EventWebhookTest, map[string]string{"webhook": webhook.Name})
+}
+
+// FailInterruptedWebhookDeliveries marks failed the deliveries that were being retried when the decision
+// manager stopped
+func FailInterruptedWebhookDeliveries() error {
+	return GetDBConnection().Model(&models.WebhookDelivery{}).
+		Where("status = ?", models.DeliveryPending).
+		Updates(map[string]interface{}{"status": models.DeliveryFailed, "error": "interrupted by a decision manager restart"}).Error
+}
+
+// EmitWebhookEvent delivers the event to every webhook subscribed to it. It returns right away, the
+// deliveries and their retries run in the background.
+func EmitWebhookEvent(event string, data interface{}) {
+	go func() {
+		webhooks, err := ListWebhooks()
+		if err != nil {
+			log.Printf("Error loading the webhooks for event %s: %s", event, err)
+			return
+		}
+
+		for _, webhook := range webhooks {
+			if !webhook.Subscribes(event) {
+				continue
+			}
+			if _, err := startWebhookDelivery(webhook, event, data); err != nil {
+				log.Printf("Error delivering event %s to webhook %s: %s", event, webhook.Name, err)
+			}
+		}
+	}()
+}
+
+// emitJobEvent notifies the webhooks of a finished job, for the commands that have an event
+func emitJobEvent(jobID string) {
+	job, err := GetJob(jobID)
+	if err != nil {
+		log.Printf("Error loading job %s for the webhooks: %s", jobID, err)
+		return
+	}
+
+	var event string
+	switch job.Command {
+	case "configure", "rollout", "rollback", "code":
+		event = models.EventDeploySucceeded
+		if job.Status != models.JobStatusSucceeded {
+			event = models.EventDeployFailed
+		}
+	case "scp-run":
+		event = models.EventScriptCompleted
+	case "clean":
+		event = models.EventCleanExecuted
+	default:
+		return
+	}
+
+	EmitWebhookEvent(event, JobEventData{
+		JobID:     job.ID,
+		Project:   job.Project,
+		Command:   job.Command,
+		Argument:  job.Argument,
+		Cluster:   job.Cluster,
+		Server:    job.Server,
+		Outcome:   job.Outcome,
+		Error:     job.Error,
+		CreatedBy: job.CreatedBy,
+		Results:   jobServerResults(job),
+	})
+}
+
+// startWebhookDelivery records the delivery and sends it in the background
+func startWebhookDelivery(webhook models.Webhook, event string, data interface{}) (*models.WebhookDelivery, error) {
+	payload := models.WebhookEvent{
+		ID:        uuid.New().String(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+
+	body, err := webhookBody(webhook.Format, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:  webhook.ID,
+		DeliveryID: payload.ID,
+		Event:      event,
+		Payload:    string(body),
+		Status:     models.DeliveryPending,
+	}
+	if err := GetDBConnection().Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("error persisting webhook delivery: %w", err)
+	}
+
+	go deliverWebhook(webhook, delivery, body)
+
+	return &delivery, nil
+}
+
+// deliverWebhook sends the delivery until the webhook accepts it, waiting twice as long after every
+// failed attempt, and keeps the delivery log up to date
+func deliverWebhook(webhook models.Webhook, delivery models.WebhookDelivery, body []byte) {
+	settings := GetWebhookSettings()
+	client := &http.Client{Timeout: settings.Timeout}
+	backoff := settings.Backoff
+
+	for attempt := 1; attempt <= settings.Attempts; attempt++ {
+		statusCode, err := sendWebhook(client, webhook, delivery, body)
+
+		updates := map[string]interface{}{"attempts": attempt, "status_code": statusCode, "error": ""}
+		if err != nil {
+			updates["error"] = err.Error()
+			if attempt == settings.Attempts {
+				updates["status"] = models.DeliveryFailed
+			}
+		} else {
+			updates["status"] = models.DeliverySucceeded
+			updates["delivered_at"] = time.Now()
+		}
+		if err := GetDBConnection().Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+			log.Printf("Error updating webhook delivery %s: %s", delivery.DeliveryID, err)
+		}
+
+		if err == nil {
+			return
+		}
+		log.Printf("Attempt %d of %d to deliver %s to webhook %s failed: %s", attempt, settings.Attempts, delivery.Event, webhook.Name, err)
+		if attempt < settings.Attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+func sendWebhook(client *http.Client, webhook models.Webhook, delivery models.WebhookDelivery, body []byte) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "appjet-webhooks")
+	request.Header.Set(WebhookEventHeader, delivery.Event)
+	request.Header.Set(WebhookDeliveryHeader, delivery.DeliveryID)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, "sha256="+webhookSignature(webhook.Secret, timestamp, body))
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return response.StatusCode, fmt.Errorf("webhook answered %s", response.Status)
+	}
+
+	return response.StatusCode, nil
+}
+
+// webhookSignature signs "timestamp.body", receivers recompute it with their copy of the secret
+func webhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBody renders the payload in the format of the webhook
+func webhookBody(format string, payload models.WebhookEvent) ([]byte, error) {
+	if format == models.WebhookFormatJSON {
+		return json.Marshal(payload)
+	}
+
+	var message bytes.Buffer
+	if err := webhookMessages.ExecuteTemplate(&message, payload.Event, payload); err != nil {
+		return nil, fmt.Errorf("error rendering the %s message: %w", payload.Event, err)
+	}
+
+	switch format {
+	case models.WebhookFormatDiscord:
+		return json.Marshal(map[string]string{"content": message.String()})
+	default:
+		return json.Marshal(map[string]string{"text": message.String()})
+	}
+}
+
+func validateWebhookRequest(request *WebhookRequest) error {
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an http or https url", ErrInvalidWebhookRequest)
+	}
+
+	if len(request.Events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidWebhookRequest)
+	}
+	for _, event := range request.Events {
+		if !containsString(models.WebhookEvents, event) {
+			return fmt.Errorf("%w: unknown event %q, expected some of %s", ErrInvalidWebhookRequest, event, strings.Join(models.WebhookEvents, ", "))
+		}
+	}
+
+	if request.Format == "" {
+		request.Format = models.WebhookFormatJSON
+	}
+	if !containsString(models.WebhookFormats, request.Format) {
+		return fmt.Errorf("%w: unknown format %q, expected one of %s", ErrInvalidWebhookRequest, request.Format, strings.Join(models.WebhookFormats, ", "))
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
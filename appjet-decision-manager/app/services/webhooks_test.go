@@ -0,0 +1,206 @@
+package services
+
+import (
+	"appjet-decision-manager/app/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receivedWebhook is a call received by a webhookReceiver
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+// webhookReceiver is a local webhook endpoint answering the status codes in order, then 200
+type webhookReceiver struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	received []receivedWebhook
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	receiver := &webhookReceiver{statuses: statuses}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mutex.Lock()
+		receiver.received = append(receiver.received, receivedWebhook{header: r.Header.Clone(), body: body, at: time.Now()})
+		status := http.StatusOK
+		if len(receiver.statuses) > 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+		receiver.mutex.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.Close)
+
+	return receiver
+}
+
+func (receiver *webhookReceiver) calls() []receivedWebhook {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	return append([]receivedWebhook(nil), receiver.received...)
+}
+
+// useTestWebhooks sets up the webhook tables and short retries
+func useTestWebhooks(t *testing.T, attempts string) {
+	useTestDatabase(t, &models.Webhook{}, &models.WebhookDelivery{})
+	t.Setenv("APPJET_WEBHOOK_ATTEMPTS", attempts)
+	t.Setenv("APPJET_WEBHOOK_BACKOFF", "50ms")
+	t.Setenv("APPJET_WEBHOOK_TIMEOUT", "2s")
+}
+
+func createTestWebhook(t *testing.T, receiver *webhookReceiver, secret string, events ...string) *models.Webhook {
+	t.Helper()
+
+	webhook, _, err := CreateWebhook(WebhookRequest{Name: "test", URL: receiver.URL, Events: events, Secret: secret}, "root")
+	if err != nil {
+		t.Fatalf("creating the webhook: %s", err)
+	}
+	return webhook
+}
+
+// waitForDelivery waits until the only delivery of the webhook is no longer pending
+func waitForDelivery(t *testing.T, webhookID uint) models.WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, err := ListWebhookDeliveries(webhookID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Status != models.DeliveryPending {
+			return deliveries[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("the delivery of webhook %d did not finish", webhookID)
+	return models.WebhookDelivery{}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	useTestWebhooks(t, "1")
+	receiver := newWebhookReceiver(t)
+	webhook := createTestWebhook(t, receiver, "s3cret", models.EventServerDown)
+
+	EmitWebhookEvent(models.EventServerDown, ServerEventData{Project: "default", Cluster: "prod", Server: "server-1", State: "down"})
+	delivery := waitForDelivery(t, webhook.ID)
+	if delivery.Status != models.DeliverySucceeded {
+		t.Fatalf("delivery %s: %s", delivery.Status, delivery.Error)
+	}
+
+	calls := receiver.calls()
+	if len(calls) != 1 {
+		t.Fatalf("the receiver got %d calls, want 1", len(calls))
+	}
+	call := calls[0]
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(call.header.Get(WebhookTimestampHeader) + "."))
+	mac.Write(call.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); call.header.Get(WebhookSignatureHeader) != want {
+		t.Errorf("signature %q, want %q", call.header.Get(WebhookSignatureHeader), want)
+	}
+	if call.header.Get(WebhookEventHeader) != models.EventServerDown || call.header.Get(WebhookDeliveryHeader) != delivery.DeliveryID {
+		t.Errorf("event %q delivery %q, want %q %q", call.header.Get(WebhookEventHeader), call.header.Get(WebhookDeliveryHeader), models.EventServerDown, delivery.DeliveryID)
+	}
+
+	var payload struct {
+		ID    string          `json:"id"`
+		Event string          `json:"event"`
+		Data  ServerEventData `json:"data"`
+	}
+	if err := json.Unmarshal(call.body, &payload); err != nil {
+		t.Fatalf("the body is not a JSON event: %s", err)
+	}
+	if payload.ID != delivery.DeliveryID || payload.Event != models.EventServerDown || payload.Data.Server != "server-1" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	// another secret does not give the same signature
+	other := "sha256=" + webhookSignature("other", call.header.Get(WebhookTimestampHeader), call.body)
+	if other == call.header.Get(WebhookSignatureHeader) {
+		t.Error("the signature does not depend on the secret")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	useTestWebhooks(t, "3")
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	webhook := createTestWebhook(t, receiver, "s3cret", models.EventDeployFailed)
+
+	EmitWebhookEvent(models.EventDeployFailed, JobEventData{JobID: "job-1", Command: "configure", Outcome: "failed"})
+	delivery := waitForDelivery(t, webhook.ID)
+
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 2 || delivery.StatusCode != http.StatusOK || delivery.DeliveredAt == nil {
+		t.Errorf("delivery %s after %d attempts, status code %d, delivered at %v: want succeeded after 2 with 200",
+			delivery.Status, delivery.Attempts, delivery.StatusCode, delivery.DeliveredAt)
+	}
+
+	calls := receiver.calls()
+	if len(calls) != 2 {
+		t.Fatalf("the receiver got %d calls, want 2", len(calls))
+	}
+	if calls[0].header.Get(WebhookDeliveryHeader) != calls[1].header.Get(WebhookDeliveryHeader) {
+		t.Error("a retry must keep the delivery id")
+	}
+	if wait := calls[1].at.Sub(calls[0].at); wait < 50*time.Millisecond {
+		t.Errorf("retried after %s, want the 50ms backoff", wait)
+	}
+}
+
+func TestWebhookRetriesExhausted(t *testing.T) {
+	useTestWebhooks(t, "2")
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusInternalServerError)
+	webhook := createTestWebhook(t, receiver, "s3cret", models.EventCleanExecuted)
+
+	EmitWebhookEvent(models.EventCleanExecuted, JobEventData{JobID: "job-1", Command: "clean", Outcome: "succeeded"})
+	delivery := waitForDelivery(t, webhook.ID)
+
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 2 || delivery.StatusCode != http.StatusBadGateway || delivery.Error == "" {
+		t.Errorf("delivery %s after %d attempts, status code %d, error %q: want failed after 2 with 502",
+			delivery.Status, delivery.Attempts, delivery.StatusCode, delivery.Error)
+	}
+	if calls := receiver.calls(); len(calls) != 2 {
+		t.Errorf("the receiver got %d calls, want 2", len(calls))
+	}
+}
+
+func TestWebhookEventFiltering(t *testing.T) {
+	useTestWebhooks(t, "1")
+	subscribed := newWebhookReceiver(t)
+	other := newWebhookReceiver(t)
+	subscribedWebhook := createTestWebhook(t, subscribed, "s3cret", models.EventServerUp, models.EventServerDown)
+	otherWebhook := createTestWebhook(t, other, "s3cret", models.EventDeployFailed)
+
+	EmitWebhookEvent(models.EventServerUp, ServerEventData{Cluster: "prod", Server: "server-1", State: "up"})
+	waitForDelivery(t, subscribedWebhook.ID)
+
+	// the deliveries are recorded before they are sent, the other webhook has none
+	deliveries, err := ListWebhookDeliveries(otherWebhook.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 0 || len(other.calls()) != 0 {
+		t.Errorf("the webhook not subscribed to %s got %d deliveries and %d calls", models.EventServerUp, len(deliveries), len(other.calls()))
+	}
+	if calls := subscribed.calls(); len(calls) != 1 || calls[0].header.Get(WebhookEventHeader) != models.EventServerUp {
+		t.Errorf("the subscribed webhook got %d calls, want one %s", len(calls), models.EventServerUp)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	services "appjet-decision-manager/app/services"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"net/http"
 )
 
 func main() {
@@ -21,95 +22,278 @@ func main() {
 		return
 	}
 
-	apiGroup := r.Group("/appjet")
-	{
-		// open endpoints
-		apiGroup.POST("/login", handlers.LoginHandler)         //OK
-		apiGroup.GET("/logout/:token", handlers.LogoutHandler) //OK
-
-		apiGroup.GET("/help", handlers.HelpHandler) //OK
-
-		// protected endpoints
-		protectedGroup := apiGroup.Group("/")
-		protectedGroup.Use(handlers.AuthMiddlewareHandler)
-		{
-
-			//returns config and builds all dependencies - but don't start the process - in all servers in all clusters
-			protectedGroup.POST("/configure", handlers.ConfigureAllClustersAllServersHandler) //OK
-			//returns config and builds all dependencies - but don't start the process - in all servers in specific cluster
-			protectedGroup.POST("/configure/:cluster", handlers.ConfigureSpecificClusterAllServersHandler) //OK
-			//returns config and builds all dependencies - but don't start the process - in specific server in specific cluster
-			protectedGroup.POST("/configure/:cluster/:server", handlers.ConfigureSpecificClusterSpecificServerHandler) //OK
-
-			//start all infrastructure in all servers on the clusters
-			apiGroup.GET("/start", handlers.StartAllClustersAllServersHandler) //OK
-			//start all infrastructure in all servers on specific cluster
-			apiGroup.GET("/start/:cluster", handlers.StartSpecificClusterAllServersHandler) //OK
-			//start all infrastructure in a specific server on specific cluster
-			apiGroup.GET("/start/:cluster/:server", handlers.StartSpecificClusterSpecificServerHandler) //OK
-			//start a specific docker container inside a specific server on specific cluster
-			apiGroup.GET("/start/:cluster/:server/:container", handlers.StartContainerSpecificClusterSpecificServerHandler)
-
-			//restart all infrastructure in all servers in all clusters
-			apiGroup.GET("/restart", handlers.RestartAllClustersAllServersHandler)
-			//restart all infrastructure in all servers on specific cluster
-			apiGroup.GET("/restart/:cluster", handlers.RestartSpecificClusterAllServersHandler)
-			//restart all infrastructure in a specific server on specific cluster
-			apiGroup.GET("/restart/:cluster/:server", handlers.RestartSpecificClusterSpecificServerHandler)
-			//restart a specific docker container inside a specific server on specific cluster
-			apiGroup.GET("/restart/:cluster/:server/:container", handlers.RestartContainerSpecificClusterSpecificServerContainerHandler)
-
-			//stop all infrastructure in all servers on the clusters
-			apiGroup.GET("/stop", handlers.StopAllClustersAllServersHandler)
-			//stop all infrastructure in all servers on specific cluster
-			apiGroup.GET("/stop/:cluster", handlers.StopSpecificClusterAllServersHandler)
-			//stop all infrastructure in a specific server on specific cluster
-			apiGroup.GET("/stop/:cluster/:server", handlers.StopSpecificClusterSpecificServerHandler)
-			//stop a specific docker container inside a specific server on specific cluster
-			apiGroup.GET("/stop/:cluster/:server/:container", handlers.StopContainerSpecificClusterSpecificServerContainerHandler)
-
-			//Check if all containers are alive in all servers in all clusters
-			protectedGroup.GET("/check-alive", handlers.CheckAliveAllClustersAllServersHandler)
-			//Check if all containers are alive in all servers in specific cluster
-			protectedGroup.GET("/check-alive/:cluster", handlers.CheckAliveSpecificClusterAllServersHandler)
-			//Check if all containers are alive in specific server in specific cluster
-			protectedGroup.GET("/check-alive/:cluster/:server", handlers.CheckAliveSpecificClusterSpecificServerHandler)
-
-			//returns the config.json present in all servers on all clusters
-			apiGroup.GET("/inspect", handlers.InspectAllClustersAllServersHandler)
-			//returns the config.json present in all servers on a specific clusters
-			apiGroup.GET("/inspect/:cluster", handlers.InspectSpecificClusterAllServersHandler)
-			//returns the config.json present in specific server on a specific cluster
-			apiGroup.GET("/inspect/:cluster/:server", handlers.InspectSpecificClusterSpecificServerHandler)
-
-			//clean all docker images, containers and volumes in all servers in all clusters
-			apiGroup.GET("/clean", handlers.CleanAllClustersAllServersHandler)
-			//clean all docker images, containers and volumes in all servers in specific clusters
-			apiGroup.GET("/clean/:cluster", handlers.CleanSpecificClusterAllServersHandler)
-			//clean all docker images, containers and volumes in specific server in specific clusters
-			apiGroup.GET("/clean/:cluster/:server", handlers.CleanSpecificClusterSpecificServerHandler)
-
-			//endpoint to load scrips files throught SCP in all servers in all clusters
-			apiGroup.POST("/scripts", handlers.SCPAllClustersAllServersHandler)
-			//endpoint to load scrips files throught SCP in all servers in specific cluster
-			apiGroup.POST("/scripts/:cluster", handlers.SCPSpecificClusterAllServersHandler)
-			//endpoint to load scrips files throught SCP in specific server in specific cluster
-			apiGroup.POST("/scripts/:cluster/:server", handlers.SCPSpecificClusterSpecificServerHandler)
-
-			//endpoint to load project files throught SCP in all servers in all clusters
-			apiGroup.POST("/code", handlers.SCPCodeAllClustersAllServersHandler)
-			//endpoint to load project files throught SCP in all servers in specific cluster
-			apiGroup.POST("/code/:cluster", handlers.SCPCodeSpecificClusterAllServersHandler)
-			//endpoint to load project files throught SCP in specific server in specific cluster
-			apiGroup.POST("/code/:cluster/:server", handlers.SCPCodeSpecificClusterSpecificServerHandler)
-
-			//endpoint to run a pre-loaded scp script in all servers in all clusters
-			apiGroup.GET("/scp/run/:script", handlers.SCPRunAllClustersAllServersHandler)
-			//endpoint to run a pre-loaded scp script in all servers in specific cluster
-			apiGroup.GET("/scp/run/:script/:cluster", handlers.SCPRunSpecificClusterAllServersHandler)
-			//endpoint to run a pre-loaded scp script in specific server in specific cluster
-			apiGroup.GET("/scp/run/:script/:cluster/:server", handlers.SCPRunSpecificClusterSpecificServerHandler)
-		}
+	err = services.MigrateDb()
+	if err != nil {
+		print(err)
+		return
+	}
+
+	// jobs that were running when the previous process stopped will never finish
+	err = services.FailInterruptedJobs()
+	if err != nil {
+		print(err)
+		return
+	}
+
+	// webhook deliveries that were being retried are not resumed
+	err = services.FailInterruptedWebhookDeliveries()
+	if err != nil {
+		print(err)
+		return
+	}
+
+	// sessions that expired while the decision manager was down
+	err = services.PurgeExpiredSessions()
+	if err != nil {
+		print(err)
+		return
+	}
+
+	// the internal CA issues the daemon certificates and the client certificate used to call them
+	err = services.LoadCertificateAuthority()
+	if err != nil {
+		print(err.Error())
+		return
+	}
+
+	// joined servers are marked offline when their heartbeats stop
+	go services.MonitorHeartbeats()
+
+	// the stored configuration is kept in memory and reloaded whenever it is updated through the api
+	err = services.LoadConfig()
+	if err != nil {
+		print(err)
+		return
+	}
+
+	// every daemon is checked in the background, GET /status returns what was recorded
+	go services.MonitorHealth()
+
+	routes := handlers.NewRouteRegistry(r, "/appjet")
+
+	// open endpoints, they must be in the public allowlist
+	routes.Public(http.MethodPost, "/login", handlers.LoginHandler)   //OK
+	routes.Public(http.MethodPost, "/logout", handlers.LogoutHandler) //OK
+
+	routes.Public(http.MethodGet, "/help", handlers.HelpHandler) //OK
+
+	//a daemon joins the inventory with a one-time join token
+	routes.Public(http.MethodPost, "/join", handlers.JoinHandler)
+	//a joined daemon reports its state, signed with its secret
+	routes.Public(http.MethodPost, "/heartbeat", handlers.HeartbeatHandler)
+
+	// protected endpoints, behind authentication and the permission matrix
+
+	//change the password of the authenticated user, the only route allowed until a required change is done
+	routes.Protected(http.MethodPut, "/password", handlers.ChangePasswordHandler)
+
+	//list the active sessions of the authenticated user
+	routes.Protected(http.MethodGet, "/sessions", handlers.ListSessionsHandler)
+	//replace the current session by a new one, with a new token and expiry
+	routes.Protected(http.MethodPost, "/sessions/refresh", handlers.RefreshSessionHandler)
+	//revoke a session, any session for admins
+	routes.Protected(http.MethodDelete, "/sessions/:id", handlers.RevokeSessionHandler)
+	//list the active sessions of a user
+	routes.Protected(http.MethodGet, "/users/:username/sessions", handlers.ListUserSessionsHandler)
+
+	//list the api tokens of the authenticated user
+	routes.Protected(http.MethodGet, "/tokens", handlers.ListAPITokensHandler)
+	//create a scoped api token for CI, the secret is only returned once
+	routes.Protected(http.MethodPost, "/tokens", handlers.CreateAPITokenHandler)
+	//revoke an api token, any token for admins
+	routes.Protected(http.MethodDelete, "/tokens/:id", handlers.RevokeAPITokenHandler)
+
+	//list the users
+	routes.Protected(http.MethodGet, "/users", handlers.ListUsersHandler)
+	//create a user with a temporary password
+	routes.Protected(http.MethodPost, "/users", handlers.CreateUserHandler)
+	//delete a user and its sessions
+	routes.Protected(http.MethodDelete, "/users/:username", handlers.DeleteUserHandler)
+	//change the role of a user
+	routes.Protected(http.MethodPut, "/users/:username/role", handlers.SetUserRoleHandler)
+	//set a temporary password, to be changed on the next login
+	routes.Protected(http.MethodPut, "/users/:username/password", handlers.ResetUserPasswordHandler)
+
+	//list the joined servers with their last heartbeat
+	routes.Protected(http.MethodGet, "/servers", handlers.ListServersHandler)
+	//remove a joined server with its secret and certificate
+	routes.Protected(http.MethodDelete, "/servers/:cluster/:server", handlers.DeleteServerHandler)
+	//list the join tokens
+	routes.Protected(http.MethodGet, "/join-tokens", handlers.ListJoinTokensHandler)
+	//create a one-time token for a daemon to join a cluster, the secret is only returned once
+	routes.Protected(http.MethodPost, "/join-tokens", handlers.CreateJoinTokenHandler)
+	//revoke a join token
+	routes.Protected(http.MethodDelete, "/join-tokens/:id", handlers.RevokeJoinTokenHandler)
+
+	//list the pinned daemon certificates
+	routes.Protected(http.MethodGet, "/certificates", handlers.ListDaemonCertificatesHandler)
+	//enroll the daemon of a server: sign its certificate request with the internal CA and pin the certificate
+	routes.Protected(http.MethodPost, "/certificates/:cluster/:server", handlers.EnrollDaemonHandler)
+	//unpin the certificate of a server
+	routes.Protected(http.MethodDelete, "/certificates/:cluster/:server", handlers.DeleteDaemonCertificateHandler)
+
+	//list the servers whose daemon secret is registered
+	routes.Protected(http.MethodGet, "/secrets", handlers.ListDaemonSecretsHandler)
+	//register the secret generated by the daemon of a server, every call to that daemon is signed with it
+	routes.Protected(http.MethodPut, "/secrets/:cluster/:server", handlers.SetDaemonSecretHandler)
+	//remove the secret of a server
+	routes.Protected(http.MethodDelete, "/secrets/:cluster/:server", handlers.DeleteDaemonSecretHandler)
+
+	//query the audit log of the protected routes, ?format=jsonl exports it as JSON lines
+	routes.Protected(http.MethodGet, "/audit", handlers.ListAuditHandler)
+
+	//list the outbound webhooks
+	routes.Protected(http.MethodGet, "/webhooks", handlers.ListWebhooksHandler)
+	//create a webhook notified of the fleet events it subscribes to, the signing secret is only returned once
+	routes.Protected(http.MethodPost, "/webhooks", handlers.CreateWebhookHandler)
+	//delete a webhook with its delivery log
+	routes.Protected(http.MethodDelete, "/webhooks/:id", handlers.DeleteWebhookHandler)
+	//send a test event to a webhook
+	routes.Protected(http.MethodPost, "/webhooks/:id/test", handlers.TestWebhookHandler)
+	//delivery log of a webhook, with the attempts and the last answer of each delivery
+	routes.Protected(http.MethodGet, "/webhooks/:id/deliveries", handlers.ListWebhookDeliveriesHandler)
+
+	//list the projects, every project has its own configuration, revisions, jobs and health
+	routes.Protected(http.MethodGet, "/projects", handlers.ListProjectsHandler)
+	//create a project, its routes are the routes below under /projects/:project
+	routes.Protected(http.MethodPost, "/projects", handlers.CreateProjectHandler)
+	//delete a project, its history is kept and its containers are left on the servers
+	routes.Protected(http.MethodDelete, "/projects/:project", handlers.DeleteProjectHandler)
+
+	// project endpoints: on the default project here, on any project under /projects/:project
+
+	//list the most recent asynchronous jobs
+	routes.Project(http.MethodGet, "/jobs", handlers.ListJobsHandler)
+	//returns a job with the progress on each server
+	routes.Project(http.MethodGet, "/jobs/:id", handlers.GetJobHandler)
+
+	//returns the configuration in use
+	routes.Project(http.MethodGet, "/config", handlers.GetConfigHandler)
+	//validates and replaces the configuration in use, without pushing it to the servers
+	routes.Project(http.MethodPut, "/config", handlers.PutConfigHandler)
+	//merges a partial cluster into the configuration in use
+	routes.Project(http.MethodPatch, "/config/:cluster", handlers.PatchClusterConfigHandler)
+	//merges a partial server into the configuration in use
+	routes.Project(http.MethodPatch, "/config/:cluster/:server", handlers.PatchServerConfigHandler)
+
+	//returns config and builds all dependencies - but don't start the process - in all servers in all clusters
+	routes.Project(http.MethodPost, "/configure", handlers.ConfigureAllClustersAllServersHandler) //OK
+	//returns config and builds all dependencies - but don't start the process - in all servers in specific cluster
+	routes.Project(http.MethodPost, "/configure/:cluster", handlers.ConfigureSpecificClusterAllServersHandler) //OK
+	//returns config and builds all dependencies - but don't start the process - in specific server in specific cluster
+	routes.Project(http.MethodPost, "/configure/:cluster/:server", handlers.ConfigureSpecificClusterSpecificServerHandler) //OK
+
+	//list the configuration revisions accepted by the decision manager
+	routes.Project(http.MethodGet, "/revisions", handlers.ListRevisionsHandler)
+	//returns a configuration revision
+	routes.Project(http.MethodGet, "/revisions/:id", handlers.GetRevisionHandler)
+
+	//re-push an older configuration revision (?to=) to all servers in all clusters and restart them
+	routes.Project(http.MethodPost, "/rollback", handlers.RollbackAllClustersAllServersHandler)
+	//re-push an older configuration revision (?to=) to all servers in specific cluster and restart them
+	routes.Project(http.MethodPost, "/rollback/:cluster", handlers.RollbackSpecificClusterAllServersHandler)
+	//re-push an older configuration revision (?to=) to specific server in specific cluster and restart it
+	routes.Project(http.MethodPost, "/rollback/:cluster/:server", handlers.RollbackSpecificClusterSpecificServerHandler)
+
+	//rolling deployment on a specific cluster: configure, start and health-check one batch of servers at a time
+	routes.Project(http.MethodPost, "/rollout/:cluster", handlers.RolloutSpecificClusterHandler)
+
+	//start all infrastructure in all servers on the clusters
+	routes.Project(http.MethodGet, "/start", handlers.StartAllClustersAllServersHandler) //OK
+	//start all infrastructure in all servers on specific cluster
+	routes.Project(http.MethodGet, "/start/:cluster", handlers.StartSpecificClusterAllServersHandler) //OK
+	//start all infrastructure in a specific server on specific cluster
+	routes.Project(http.MethodGet, "/start/:cluster/:server", handlers.StartSpecificClusterSpecificServerHandler) //OK
+	//start a specific docker container inside a specific server on specific cluster
+	routes.Project(http.MethodGet, "/start/:cluster/:server/:container", handlers.StartContainerSpecificClusterSpecificServerHandler)
+
+	//restart all infrastructure in all servers in all clusters
+	routes.Project(http.MethodGet, "/restart", handlers.RestartAllClustersAllServersHandler)
+	//restart all infrastructure in all servers on specific cluster
+	routes.Project(http.MethodGet, "/restart/:cluster", handlers.RestartSpecificClusterAllServersHandler)
+	//restart all infrastructure in a specific server on specific cluster
+	routes.Project(http.MethodGet, "/restart/:cluster/:server", handlers.RestartSpecificClusterSpecificServerHandler)
+	//restart a specific docker container inside a specific server on specific cluster
+	routes.Project(http.MethodGet, "/restart/:cluster/:server/:container", handlers.RestartContainerSpecificClusterSpecificServerContainerHandler)
+
+	//stop all infrastructure in all servers on the clusters
+	routes.Project(http.MethodGet, "/stop", handlers.StopAllClustersAllServersHandler)
+	//stop all infrastructure in all servers on specific cluster
+	routes.Project(http.MethodGet, "/stop/:cluster", handlers.StopSpecificClusterAllServersHandler)
+	//stop all infrastructure in a specific server on specific cluster
+	routes.Project(http.MethodGet, "/stop/:cluster/:server", handlers.StopSpecificClusterSpecificServerHandler)
+	//stop a specific docker container inside a specific server on specific cluster
+	routes.Project(http.MethodGet, "/stop/:cluster/:server/:container", handlers.StopContainerSpecificClusterSpecificServerContainerHandler)
+
+	//Check if all containers are alive in all servers in all clusters
+	routes.Project(http.MethodGet, "/check-alive", handlers.CheckAliveAllClustersAllServersHandler)
+	//Check if all containers are alive in all servers in specific cluster
+	routes.Project(http.MethodGet, "/check-alive/:cluster", handlers.CheckAliveSpecificClusterAllServersHandler)
+	//Check if all containers are alive in specific server in specific cluster
+	routes.Project(http.MethodGet, "/check-alive/:cluster/:server", handlers.CheckAliveSpecificClusterSpecificServerHandler)
+
+	//health recorded by the monitor: current state, recent transitions and uptime of all servers in all clusters
+	routes.Project(http.MethodGet, "/status", handlers.StatusAllClustersAllServersHandler)
+	//health of all servers in a specific cluster
+	routes.Project(http.MethodGet, "/status/:cluster", handlers.StatusSpecificClusterAllServersHandler)
+	//health of a specific server in a specific cluster
+	routes.Project(http.MethodGet, "/status/:cluster/:server", handlers.StatusSpecificClusterSpecificServerHandler)
+
+	//stream the logs of a container on all servers of a cluster (?container=), as server-sent events
+	routes.Project(http.MethodGet, "/logs/:cluster", handlers.LogsSpecificClusterHandler)
+	//stream the logs of a container on a specific server, ?follow=true keeps the stream open
+	routes.Project(http.MethodGet, "/logs/:cluster/:server/:container", handlers.LogsSpecificClusterSpecificServerHandler)
+
+	//run a one-shot command in a container of a specific server and return its output and exit code
+	routes.Project(http.MethodPost, "/exec/:cluster/:server/:container", handlers.ExecHandler)
+	//run an interactive command in a container of a specific server, attached to a TTY over a websocket
+	routes.Project(http.MethodGet, "/exec/:cluster/:server/:container/tty", handlers.ExecTTYHandler)
+
+	//returns the config.json present in all servers on all clusters
+	routes.Project(http.MethodGet, "/inspect", handlers.InspectAllClustersAllServersHandler)
+	//returns the config.json present in all servers on a specific clusters
+	routes.Project(http.MethodGet, "/inspect/:cluster", handlers.InspectSpecificClusterAllServersHandler)
+	//returns the config.json present in specific server on a specific cluster
+	routes.Project(http.MethodGet, "/inspect/:cluster/:server", handlers.InspectSpecificClusterSpecificServerHandler)
+
+	//list the runtime templates the Dockerfile can be built with, in all servers or in the servers of a cluster
+	routes.Project(http.MethodGet, "/templates", handlers.TemplatesHandler)
+	routes.Project(http.MethodGet, "/templates/:cluster", handlers.TemplatesHandler)
+	routes.Project(http.MethodGet, "/templates/:cluster/:server", handlers.TemplatesHandler)
+
+	//clean all docker images, containers and volumes in all servers in all clusters
+	routes.Project(http.MethodGet, "/clean", handlers.CleanAllClustersAllServersHandler)
+	//clean all docker images, containers and volumes in all servers in specific clusters
+	routes.Project(http.MethodGet, "/clean/:cluster", handlers.CleanSpecificClusterAllServersHandler)
+	//clean all docker images, containers and volumes in specific server in specific clusters
+	routes.Project(http.MethodGet, "/clean/:cluster/:server", handlers.CleanSpecificClusterSpecificServerHandler)
+
+	//endpoint to load scrips files throught SCP in all servers in all clusters
+	routes.Project(http.MethodPost, "/scripts", handlers.SCPAllClustersAllServersHandler)
+	//endpoint to load scrips files throught SCP in all servers in specific cluster
+	routes.Project(http.MethodPost, "/scripts/:cluster", handlers.SCPSpecificClusterAllServersHandler)
+	//endpoint to load scrips files throught SCP in specific server in specific cluster
+	routes.Project(http.MethodPost, "/scripts/:cluster/:server", handlers.SCPSpecificClusterSpecificServerHandler)
+
+	//endpoint to load project files throught SCP in all servers in all clusters
+	routes.Project(http.MethodPost, "/code", handlers.SCPCodeAllClustersAllServersHandler)
+	//endpoint to load project files throught SCP in all servers in specific cluster
+	routes.Project(http.MethodPost, "/code/:cluster", handlers.SCPCodeSpecificClusterAllServersHandler)
+	//endpoint to load project files throught SCP in specific server in specific cluster
+	routes.Project(http.MethodPost, "/code/:cluster/:server", handlers.SCPCodeSpecificClusterSpecificServerHandler)
+
+	//endpoint to run a pre-loaded scp script in all servers in all clusters
+	routes.Project(http.MethodGet, "/scp/run/:script", handlers.SCPRunAllClustersAllServersHandler)
+	//endpoint to run a pre-loaded scp script in all servers in specific cluster
+	routes.Project(http.MethodGet, "/scp/run/:script/:cluster", handlers.SCPRunSpecificClusterAllServersHandler)
+	//endpoint to run a pre-loaded scp script in specific server in specific cluster
+	routes.Project(http.MethodGet, "/scp/run/:script/:cluster/:server", handlers.SCPRunSpecificClusterSpecificServerHandler)
+
+	// refuse to serve if a route was registered without authentication
+	err = routes.Verify(r)
+	if err != nil {
+		print(err.Error())
+		return
 	}
 
 	err = r.Run(":8080")
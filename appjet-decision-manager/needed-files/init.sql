@@ -10,7 +10,8 @@ CREATE TABLE IF NOT EXISTS `users` (
     `username` VARCHAR(255) NOT NULL,
     `password` VARCHAR(255) NOT NULL,
     `role` VARCHAR(50) NOT NULL,
-    `name` VARCHAR(100) NOT NULL
+    `name` VARCHAR(100) NOT NULL,
+    `must_change_password` BOOLEAN NOT NULL DEFAULT FALSE
 );
 
 -- Create a table for user sessions
@@ -21,10 +22,15 @@ CREATE TABLE IF NOT EXISTS `user_sessions` (
     `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     `deleted_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `last_seen_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `expires_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    `client_ip` VARCHAR(64) NOT NULL DEFAULT '',
+    `user_agent` VARCHAR(255) NOT NULL DEFAULT '',
     FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
     );
 
 
--- Insert a new user if a user with the username "root" doesn't already exist
-INSERT IGNORE INTO `users` (`username`, `password`, `role`, `name`)
-VALUES ('root', 'root', 'admin', 'Mr Admin Administrador');
+-- Insert a new user if a user with the username "root" doesn't already exist.
+-- The plaintext password is hashed on the first login and must be changed before anything else.
+INSERT IGNORE INTO `users` (`username`, `password`, `role`, `name`, `must_change_password`)
+VALUES ('root', 'root', 'admin', 'Mr Admin Administrador', TRUE);
@@ -0,0 +1,67 @@
+// Command sharedmodels copies the model files shared by the three modules from the decision manager to
+// appjet-cli and appjet-server-daemon. It runs from app/models, through go generate ./app/models.
+// With -check it only reports the copies that differ, as the tests of app/models do.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// sharedFiles are the files of app/models copied to the other modules
+var sharedFiles = []string{"validation.go"}
+
+// copyDirs are the app/models directories of the other modules, from app/models
+var copyDirs = []string{
+	"../../../appjet-cli/app/models",
+	"../../../appjet-server-daemon/app/models",
+}
+
+func main() {
+	check := flag.Bool("check", false, "report the copies that differ instead of writing them")
+	flag.Parse()
+
+	stale := 0
+	for _, name := range sharedFiles {
+		source, err := ioutil.ReadFile(name)
+		if err != nil {
+			log.Fatalf("Error reading %s: %s", name, err)
+		}
+		generated := generatedCopy(name, source)
+
+		for _, dir := range copyDirs {
+			target := filepath.Join(dir, name)
+			current, err := ioutil.ReadFile(target)
+			if err == nil && bytes.Equal(current, generated) {
+				continue
+			}
+
+			if *check {
+				fmt.Fprintf(os.Stderr, "%s differs from appjet-decision-manager/app/models/%s\n", target, name)
+				stale++
+				continue
+			}
+			if err := ioutil.WriteFile(target, generated, 0644); err != nil {
+				log.Fatalf("Error writing %s: %s", target, err)
+			}
+			fmt.Println("Wrote", target)
+		}
+	}
+
+	if stale > 0 {
+		fmt.Fprintln(os.Stderr, "Run go generate ./app/models in appjet-decision-manager")
+		os.Exit(1)
+	}
+}
+
+// generatedCopy is the content of the copy of a shared file
+func generatedCopy(name string, source []byte) []byte {
+	header := fmt.Sprintf("// Code generated by tools/sharedmodels from appjet-decision-manager/app/models/%s. DO NOT EDIT.\n\n", name)
+
+	return append([]byte(header), source...)
+}
@@ -41,10 +41,13 @@ export const AuthWrapper = () => {
 
     const logout = async () => {
         return new Promise((resolve, reject) => {
-            let url = `${baseURL}/appjet/logout/${user.token}`;
+            let url = `${baseURL}/appjet/logout`;
 
             fetch(url, {
-                method: "GET"
+                method: "POST",
+                headers: {
+                    Authorization: `${user.token}`
+                }
             })
                 .then((response) => {
                     setUser({ name: "", token: "", isAuthenticated: false });
@@ -16,11 +16,11 @@ import (
 )
 
 func ConfigureHandler(c *gin.Context) {
-	configurationService.Configure(c)
+	configurationService.Configure(c, currentWorkspace(c))
 }
 
 func CheckAlive(c *gin.Context) {
-	containerStates, err := configurationService.GetDockerContainersState()
+	containerStates, err := configurationService.GetDockerContainersState(currentWorkspace(c))
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to get Docker container states"})
 		return
@@ -34,21 +34,23 @@ func CheckAlive(c *gin.Context) {
 }
 
 func InspectHandler(c *gin.Context) {
-	config, err := loadConfig("config.json")
+	workspace := currentWorkspace(c)
+	config, err := loadConfig(workspace.Path("config.json"))
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to load config.json"})
 		return
 	}
 
-	containerStates, err := configurationService.GetDockerContainersState()
+	containerStates, err := configurationService.GetDockerContainersState(workspace)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to get Docker container states"})
 		return
 	}
 
 	response := gin.H{
-		"docker": containerStates,
-		"config": config,
+		"docker":    containerStates,
+		"config":    config,
+		"workspace": workspace.Dir,
 	}
 
 	c.JSON(200, response)
@@ -78,9 +80,8 @@ func loadConfig(filename string) (*models.Configuration, error) {
 }
 
 func StartHandler(c *gin.Context) {
-	// Run "docker compose up -d" command in the current directory
-	cmd := exec.Command("docker", "compose", "up", "--build")
-	cmd.Dir = "." // Set the command's working directory to the current directory
+	// Run "docker compose up --build" in the workspace of the project
+	cmd := currentWorkspace(c).Compose("up", "--build")
 
 	// Start the command asynchronously
 	err := cmd.Start()
@@ -96,9 +97,9 @@ func StartHandler(c *gin.Context) {
 }
 
 func RestartHandler(c *gin.Context) {
-	containerNames := []string{"app", "database"}
-	for _, name := range containerNames {
-		err := restartContainer(name)
+	workspace := currentWorkspace(c)
+	for _, name := range configurationService.ConfiguredServiceNames(workspace) {
+		err := restartContainer(workspace, name)
 		if err != nil {
 			log.Printf("Error restarting container %s: %s", name, err)
 			c.JSON(500, gin.H{"error": "Failed to restart containers"})
@@ -109,9 +110,9 @@ func RestartHandler(c *gin.Context) {
 }
 
 func StopHandler(c *gin.Context) {
-	containerNames := []string{"app", "database"}
-	for _, name := range containerNames {
-		err := stopContainer(name)
+	workspace := currentWorkspace(c)
+	for _, name := range configurationService.ConfiguredServiceNames(workspace) {
+		err := stopContainer(workspace, name)
 		if err != nil {
 			log.Printf("Error stopping container %s: %s", name, err)
 			c.JSON(500, gin.H{"error": "Failed to stop containers"})
@@ -121,19 +122,36 @@ func StopHandler(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "Containers stopped successfully"})
 }
 
-func restartContainer(containerName string) error {
+// restartContainer restarts a service of the compose file, or any other container by name in the
+// default project
+func restartContainer(workspace configurationService.Workspace, containerName string) error {
+	if configurationService.IsComposeService(workspace, containerName) || !workspace.IsDefault() {
+		return workspace.Compose("restart", containerName).Run()
+	}
+
 	cmd := exec.Command("docker", "container", "restart", containerName)
 	err := cmd.Run()
 	return err
 }
 
-func stopContainer(containerName string) error {
+// stopContainer stops a service of the compose file, or any other container by name in the default project
+func stopContainer(workspace configurationService.Workspace, containerName string) error {
+	if configurationService.IsComposeService(workspace, containerName) || !workspace.IsDefault() {
+		return workspace.Compose("stop", containerName).Run()
+	}
+
 	cmd := exec.Command("docker", "container", "stop", containerName)
 	err := cmd.Run()
 	return err
 }
 
-func startContainer(containerName string) error {
+// startContainer starts a service of the compose file, creating its container and the services it
+// depends on when needed, or any other container by name in the default project
+func startContainer(workspace configurationService.Workspace, containerName string) error {
+	if configurationService.IsComposeService(workspace, containerName) || !workspace.IsDefault() {
+		return workspace.Compose("up", "-d", "--build", containerName).Run()
+	}
+
 	cmd := exec.Command("docker", "container", "start", containerName)
 	err := cmd.Run()
 	return err
@@ -142,7 +160,7 @@ func startContainer(containerName string) error {
 func StartContainerHandler(c *gin.Context) {
 	// Retrieve the container parameter from the URL path
 	container := c.Param("container")
-	err := startContainer(container)
+	err := startContainer(currentWorkspace(c), container)
 	if err != nil {
 		log.Printf("Error stopping container %s: %s", container, err)
 		c.JSON(500, gin.H{"error": "Failed to start containers"})
@@ -153,7 +171,7 @@ func StartContainerHandler(c *gin.Context) {
 
 func RestartContainerHandler(c *gin.Context) {
 	container := c.Param("container")
-	err := restartContainer(container)
+	err := restartContainer(currentWorkspace(c), container)
 	if err != nil {
 		log.Printf("Error restarting container %s: %s", container, err)
 		c.JSON(500, gin.H{"error": "Failed to restart containers"})
@@ -165,7 +183,7 @@ func RestartContainerHandler(c *gin.Context) {
 
 func StopContainerHandler(c *gin.Context) {
 	container := c.Param("container")
-	err := stopContainer(container)
+	err := stopContainer(currentWorkspace(c), container)
 	if err != nil {
 		log.Printf("Error stopping container %s: %s", container, err)
 		c.JSON(500, gin.H{"error": "Failed to stop containers"})
@@ -175,6 +193,18 @@ func StopContainerHandler(c *gin.Context) {
 }
 
 func CleanHandler(c *gin.Context) {
+	// a project only removes its own containers, images and volumes, the default project cleans the host
+	if workspace := currentWorkspace(c); !workspace.IsDefault() {
+		if output, err := workspace.Compose("down", "--rmi", "all", "--volumes").CombinedOutput(); err != nil {
+			log.Printf("Error cleaning the Docker resources of project %s: %s: %s", workspace.Project, err, output)
+			c.JSON(500, gin.H{"error": "Failed to clean Docker resources"})
+			return
+		}
+
+		c.JSON(200, gin.H{"message": "Docker resources of project " + workspace.Project + " cleaned successfully"})
+		return
+	}
+
 	if err := cleanDocker(); err != nil {
 		log.Printf("Error cleaning Docker resources: %s", err)
 		c.JSON(500, gin.H{"error": "Failed to clean Docker resources"})
@@ -209,8 +239,8 @@ func cleanDocker() error {
 func SCPRunHandler(c *gin.Context) {
 	script := c.Param("script")
 
-	// Define the folder where the scripts are stored
-	scriptsFolder := "./scp/loaded-scripts"
+	// Define the folder where the scripts of the project are stored
+	scriptsFolder := currentWorkspace(c).HomePath("scp/loaded-scripts")
 
 	// Construct the file path for the specified script
 	scriptPath := filepath.Join(scriptsFolder, script)
@@ -269,6 +299,10 @@ func SCPCodeHandler(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "Missing 'dir-name' parameter"})
 		return
 	}
+	if !configurationService.IsWorkspaceDir(dirName) {
+		c.JSON(400, gin.H{"error": "Invalid 'dir-name' parameter: expected a relative directory without '..'"})
+		return
+	}
 
 	// Get the files from the "file" field in the form
 	files, ok := c.Request.MultipartForm.File["file"]
@@ -277,8 +311,8 @@ func SCPCodeHandler(c *gin.Context) {
 		return
 	}
 
-	// Specify the destination folder based on the "dir-name" parameter
-	destination := dirName
+	// Specify the destination folder based on the "dir-name" parameter, in the workspace of the project
+	destination := currentWorkspace(c).Path(dirName)
 	err = os.MkdirAll(destination, os.ModePerm)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to create destination folder"})
@@ -294,7 +328,7 @@ func SCPCodeHandler(c *gin.Context) {
 		}
 		defer src.Close()
 
-		filePath := filepath.Join(destination, file.Filename)
+		filePath := filepath.Join(destination, filepath.Base(file.Filename))
 		dst, err := os.Create(filePath)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "Failed to create the file"})
@@ -322,7 +356,7 @@ func SCPHandler(c *gin.Context) {
 	defer file.Close()
 
 	// Change the destination folder according to your needs
-	destination := "./scp/loaded-scripts"
+	destination := currentWorkspace(c).HomePath("scp/loaded-scripts")
 	err = os.MkdirAll(destination, os.ModePerm)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to create destination folder"})
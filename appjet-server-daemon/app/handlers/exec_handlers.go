@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"appjet-server-daemon/app/services"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// execControl is a control message of an interactive session, sent as a text frame. The TTY itself
+// goes through binary frames: the output of the command to the client, its input from the client.
+type execControl struct {
+	Type     string `json:"type"` // "resize" from the client, "exit" or "error" to the client
+	Rows     uint   `json:"rows,omitempty"`
+	Cols     uint   `json:"cols,omitempty"`
+	ExitCode int    `json:"exit-code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// execFrame is a websocket frame with its payload type, so text and binary frames can be told apart
+type execFrame struct {
+	payloadType byte
+	data        []byte
+}
+
+var execFrameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		frame := v.(execFrame)
+		return frame.data, frame.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*execFrame)
+		frame.data, frame.payloadType = data, payloadType
+		return nil
+	},
+}
+
+// ExecHandler runs a one-shot command in a container and returns its stdout, stderr and exit code
+func ExecHandler(c *gin.Context) {
+	container := c.Param("container")
+	name, ok := currentWorkspace(c).ResolveContainer(container)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Container " + container + " not found"})
+		return
+	}
+
+	var request services.ExecRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exec request: " + err.Error()})
+		return
+	}
+
+	log.Printf("Running %q in container %s", request.Command, container)
+	result, err := services.RunExec(c.Request.Context(), name, request)
+	switch {
+	case errors.Is(err, services.ErrContainerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Container " + container + " not found"})
+		return
+	case errors.Is(err, services.ErrInvalidExecRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Error running %q in container %s: %s", request.Command, container, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run the command: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecTTYHandler runs an interactive command in a container, attached to a TTY over a websocket.
+// The command comes from ?command= (repeated for every argument), the initial size from ?rows= and ?cols=.
+func ExecTTYHandler(c *gin.Context) {
+	container := c.Param("container")
+	name, ok := currentWorkspace(c).ResolveContainer(container)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Container " + container + " not found"})
+		return
+	}
+	command := c.QueryArray("command")
+	rows, _ := strconv.ParseUint(c.Query("rows"), 10, 16)
+	cols, _ := strconv.ParseUint(c.Query("cols"), 10, 16)
+
+	// the session starts before the upgrade, so its errors are still plain HTTP errors
+	session, err := services.StartExecSession(c.Request.Context(), name, command, uint(rows), uint(cols))
+	switch {
+	case errors.Is(err, services.ErrContainerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Container " + container + " not found"})
+		return
+	case errors.Is(err, services.ErrInvalidExecRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Error starting %q in container %s: %s", command, container, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start the command: " + err.Error()})
+		return
+	}
+	defer session.Close()
+
+	log.Printf("Started an interactive %q in container %s", command, container)
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		// input and resizes from the client, until it goes away or the command exits
+		go func() {
+			for {
+				var frame execFrame
+				if err := execFrameCodec.Receive(ws, &frame); err != nil {
+					session.Close()
+					return
+				}
+
+				if frame.payloadType == websocket.BinaryFrame {
+					if _, err := session.Write(frame.data); err != nil {
+						return
+					}
+					continue
+				}
+
+				var control execControl
+				if json.Unmarshal(frame.data, &control) == nil && control.Type == "resize" {
+					if err := session.Resize(control.Rows, control.Cols); err != nil {
+						log.Printf("Error resizing the TTY of %q in container %s: %s", command, container, err)
+					}
+				}
+			}
+		}()
+
+		buffer := make([]byte, 32*1024)
+		for {
+			n, err := session.Output().Read(buffer)
+			if n > 0 {
+				if sendErr := execFrameCodec.Send(ws, execFrame{websocket.BinaryFrame, buffer[:n]}); sendErr != nil {
+					return
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				sendExecControl(ws, execControl{Type: "error", Error: err.Error()})
+				return
+			}
+		}
+
+		exitCode := session.ExitCode()
+		log.Printf("Interactive %q in container %s exited with %d", command, container, exitCode)
+		sendExecControl(ws, execControl{Type: "exit", ExitCode: exitCode})
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func sendExecControl(ws *websocket.Conn, control execControl) {
+	data, err := json.Marshal(control)
+	if err != nil {
+		return
+	}
+
+	if err := execFrameCodec.Send(ws, execFrame{websocket.TextFrame, data}); err != nil {
+		log.Printf("Error sending the %s message of an interactive exec: %s", control.Type, err)
+	}
+}
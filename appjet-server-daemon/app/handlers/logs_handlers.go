@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"appjet-server-daemon/app/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// flushWriter sends every write to the client right away, so followed logs are not buffered
+type flushWriter struct {
+	writer gin.ResponseWriter
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.writer.Flush()
+	return n, err
+}
+
+// LogsHandler streams the logs of a container as plain text, with ?follow=true, ?tail=N (default 100,
+// or "all"), ?since=10m and ?timestamps=true
+func LogsHandler(c *gin.Context) {
+	container := c.Param("container")
+	name, ok := currentWorkspace(c).ResolveContainer(container)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Container " + container + " not found"})
+		return
+	}
+	options := services.LogOptions{
+		Follow:     c.Query("follow") == "true",
+		Tail:       c.DefaultQuery("tail", "100"),
+		Since:      c.Query("since"),
+		Timestamps: c.Query("timestamps") == "true",
+	}
+
+	logs, err := services.OpenContainerLogs(c.Request.Context(), name, options)
+	switch {
+	case errors.Is(err, services.ErrContainerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Container " + container + " not found"})
+		return
+	case errors.Is(err, services.ErrInvalidLogOptions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Error reading the logs of container %s: %s", container, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read the container logs"})
+		return
+	}
+	defer logs.Close()
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// the stream ends with the logs, or when the client goes away while following them
+	if err := logs.CopyTo(flushWriter{c.Writer}); err != nil && c.Request.Context().Err() == nil {
+		log.Printf("Error streaming the logs of container %s: %s", container, err)
+	}
+}
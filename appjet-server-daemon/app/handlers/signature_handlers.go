@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"appjet-server-daemon/app/services"
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+)
+
+// SignatureMiddlewareHandler rejects every call that was not signed by the decision manager with the
+// daemon secret, or that replays a call already received. The headers are checked before the body is
+// read, and the body is read up to services.MaxBodySize.
+func SignatureMiddlewareHandler(c *gin.Context) {
+	err := services.CheckSignatureHeaders(
+		c.GetHeader(services.TimestampHeader),
+		c.GetHeader(services.NonceHeader),
+		c.GetHeader(services.SignatureHeader),
+	)
+	if err != nil {
+		log.Printf("Rejected unsigned call %s %s from %s: %s", c.Request.Method, c.Request.URL.Path, c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		c.Abort()
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxBodySize()))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("The request body is larger than %d bytes", tooLarge.Limit)})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the request body"})
+		c.Abort()
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	bodyHash := services.HashBody(body)
+	if subtle.ConstantTimeCompare([]byte(bodyHash), []byte(c.GetHeader(services.ContentSHA256Header))) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: the body does not match the signed hash"})
+		c.Abort()
+		return
+	}
+
+	err = services.VerifySignature(
+		c.Request.Method,
+		c.Request.URL.RequestURI(),
+		c.GetHeader(services.TimestampHeader),
+		c.GetHeader(services.NonceHeader),
+		bodyHash,
+		c.GetHeader(services.SignatureHeader),
+	)
+	if errors.Is(err, services.ErrInvalidSignature) {
+		log.Printf("Rejected unsigned call %s %s from %s: %s", c.Request.Method, c.Request.URL.Path, c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
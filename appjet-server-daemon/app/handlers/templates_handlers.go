@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"appjet-server-daemon/app/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// TemplatesHandler lists the runtime templates the Dockerfile of an application can be built with
+func TemplatesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"templates": services.RuntimeTemplates(), "templates-dir": services.TemplatesDir()})
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"appjet-server-daemon/app/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// WorkspaceMiddlewareHandler resolves the workspace of the project the call is for, ?project= (signed
+// with the rest of the call) or the default project. It must run after SignatureMiddlewareHandler.
+func WorkspaceMiddlewareHandler(c *gin.Context) {
+	workspace, err := services.ResolveWorkspace(c.Query("project"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+
+	c.Set("workspace", workspace)
+	c.Next()
+}
+
+// currentWorkspace returns the workspace resolved by WorkspaceMiddlewareHandler
+func currentWorkspace(c *gin.Context) services.Workspace {
+	return c.MustGet("workspace").(services.Workspace)
+}
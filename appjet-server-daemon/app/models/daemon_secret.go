@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// DaemonSecret is the secret shared with the decision manager, stored in the daemon secret file.
+// After a rotation the previous secret is still accepted until PreviousExpiresAt, so calls keep
+// working while the new secret is registered in the decision manager.
+type DaemonSecret struct {
+	Secret            string     `json:"secret"`
+	CreatedAt         time.Time  `json:"created-at"`
+	PreviousSecret    string     `json:"previous-secret,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous-expires-at,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// JoinState is written by "appjet-server-daemon join". A joined daemon sends its heartbeats to the
+// decision manager as this server of the cluster.
+type JoinState struct {
+	Manager           string    `json:"manager"`
+	Cluster           string    `json:"cluster"`
+	Server            string    `json:"server"`
+	HeartbeatInterval string    `json:"heartbeat-interval"`
+	JoinedAt          time.Time `json:"joined-at"`
+}
+
+// Heartbeat is what the daemon reports to the decision manager at every heartbeat
+type Heartbeat struct {
+	Version       string                 `json:"version"`
+	Hostname      string                 `json:"hostname"`
+	FreeDiskBytes uint64                 `json:"free-disk-bytes"`
+	Containers    map[string]interface{} `json:"containers"`
+}
@@ -3,23 +3,69 @@ package services
 import (
 	"appjet-server-daemon/app/models"
 	"github.com/gin-gonic/gin"
+	"log"
 )
 
-func Configure(c *gin.Context) {
+// Configure writes the configuration and the files generated from it to a new deployment of the
+// project, in the deploy-details folder of the server the call is for (?cluster= and ?server=). The
+// deployment becomes the current one once every file is written.
+func Configure(c *gin.Context, workspace Workspace) {
 
 	var config models.Configuration
 
-	ShouldBindJson(c, &config)
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 
-	GenerateConfigFile(config, c)
+	// reject the configuration before anything is written to disk, the runtime templates are the ones
+	// of this daemon
+	errs := config.Validate()
+	if len(errs) == 0 {
+		errs = ValidateTemplate(config)
+	}
+	if len(errs) > 0 {
+		c.JSON(400, gin.H{"error": "Invalid configuration", "errors": errs})
+		return
+	}
 
-	GenerateWaitForItScript(c)
+	deployment, err := workspace.NewDeployment(config.DeployFolder(c.Query("cluster"), c.Query("server")))
+	if err != nil {
+		log.Printf("Error creating a deployment of project %s: %s", workspace.Project, err)
+		c.JSON(500, gin.H{"error": "Failed to create the deployment workspace"})
+		return
+	}
 
-	GenerateDockerfile(config, c)
+	// the code uploaded before the configuration is built by the new deployment too
+	if config.Artifact.CodeCheckout.SCP.Enabled {
+		if err := workspace.CarryOver(deployment, config.Artifact.CodeCheckout.SCP.Configurations.Folder); err != nil {
+			log.Printf("Error copying the code of project %s to %s: %s", workspace.Project, deployment.Dir, err)
+			c.JSON(500, gin.H{"error": "Failed to copy the code to the deployment workspace"})
+			return
+		}
+	}
 
-	GenerateDockerCompose(config, c)
+	GenerateConfigFile(config, deployment, c)
 
-	GenerateInitSqlIfNeeded(config, c)
+	GenerateWaitForItScript(deployment, c)
 
-	c.JSON(200, gin.H{"message": "Configuration saved successfully"})
+	GenerateDockerfile(config, deployment, c)
+
+	GenerateDockerCompose(config, deployment, c)
+
+	GenerateInitSqlIfNeeded(config, deployment, c)
+
+	// a file could not be written, the error is answered and the current deployment stays as it was
+	if c.Writer.Written() {
+		return
+	}
+
+	if err := workspace.Activate(deployment); err != nil {
+		log.Printf("Error activating the deployment %s: %s", deployment.Dir, err)
+		c.JSON(500, gin.H{"error": "Failed to activate the deployment workspace"})
+		return
+	}
+
+	log.Printf("Project %s deployed to %s", workspace.Project, deployment.Dir)
+	c.JSON(200, gin.H{"message": "Configuration saved successfully", "workspace": deployment.Dir})
 }
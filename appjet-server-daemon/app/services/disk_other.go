@@ -0,0 +1,10 @@
+//go:build !unix
+
+package services
+
+import "errors"
+
+// freeDiskBytes is only implemented on unix systems, where the daemons run
+func freeDiskBytes(path string) (uint64, error) {
+	return 0, errors.New("free disk space is not available on this system")
+}
@@ -3,142 +3,227 @@ package services
 import (
 	"appjet-server-daemon/app/models"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/docker/docker/client"
 	"github.com/gin-gonic/gin"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 )
 
-func GenerateInitSqlIfNeeded(cfg models.Configuration, c *gin.Context) {
+func GenerateInitSqlIfNeeded(cfg models.Configuration, workspace Workspace, c *gin.Context) {
 
-	if cfg.Artifact.Application.Language == "python" {
+	// services bring their own database setup
+	if cfg.Artifact.Application.Language == "python" && len(cfg.Artifact.Services) == 0 {
 		dbName := cfg.Artifact.Database.Name
 
 		// Use fmt.Sprintf to format the SQL statement with the dynamic database name
 		initSql := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;", dbName)
 
-		if err := CreateFile("init.sql", initSql); err != nil {
+		if err := CreateFile(workspace.Path("init.sql"), initSql); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Init.sql file"})
 			return
 		}
 	}
 }
 
-func GenerateDockerCompose(cfg models.Configuration, c *gin.Context) {
+func GenerateDockerCompose(cfg models.Configuration, workspace Workspace, c *gin.Context) {
 
-	var volumesInit = ""
+	var dockerComposeContent strings.Builder
+	dockerComposeContent.WriteString("version: '3'\nservices:\n")
 
-	if cfg.Artifact.Application.Language == "python" {
-		volumesInit = "volumes:\n      - ./init.sql:/docker-entrypoint-initdb.d/init.sql"
+	for index, service := range ComposeServices(cfg) {
+		if index > 0 {
+			dockerComposeContent.WriteString("\n")
+		}
+		writeComposeService(&dockerComposeContent, service, workspace.ContainerName(service.Name))
 	}
 
-	dockerComposeContent := fmt.Sprintf(`version: '3'
-services:
-  app:
-    container_name: app
-    build:
-      context: .
-      dockerfile: Dockerfile
-    ports:
-      - "%d:%d"
-    depends_on:
-      - database
-
-  database:
-    container_name: database
-    image: %s
-    environment:
-      MYSQL_ROOT_PASSWORD: %s
-      MYSQL_DATABASE: %s
-      MYSQL_ALLOW_EMPTY_PASSWORD: 'yes'
-    ports:
-      - "%d:%d"
-    command: ["mysqld", "--character-set-server=utf8mb4", "--collation-server=utf8mb4_unicode_ci", "--bind-address=0.0.0.0"]
-    %s
-`,
-		cfg.Artifact.Application.Ports.ExternalDocker, cfg.Artifact.Application.Ports.InternalDocker,
-		cfg.Artifact.Database.Driver, cfg.Artifact.Database.RootPassword, cfg.Artifact.Database.Name,
-		cfg.Artifact.Database.Ports.ExternalDocker, cfg.Artifact.Database.Ports.InternalDocker, volumesInit)
-
-	if err := CreateFile("docker-compose.yml", dockerComposeContent); err != nil {
+	if err := CreateFile(workspace.Path("docker-compose.yml"), dockerComposeContent.String()); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Docker Compose file"})
 		return
 	}
 }
 
-func GenerateDockerfile(cfg models.Configuration, c *gin.Context) {
+// ComposeServices returns the services of the compose file: the services of the artifact, or the
+// application and database containers when it has none
+func ComposeServices(cfg models.Configuration) []models.Service {
+	if len(cfg.Artifact.Services) > 0 {
+		return cfg.Artifact.Services
+	}
 
-	dockerfileContent := ""
+	application := cfg.Artifact.Application
+	database := cfg.Artifact.Database
 
-	switch cfg.Artifact.Application.Language {
-	case "java":
-		dockerfileContent = generateJavaDockerFile(cfg)
-		if err := CreateFile("Dockerfile", dockerfileContent); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Dockerfile"})
-			return
-		}
-	case "python":
-		dockerfileContent = generatePythonDockerFile(cfg)
-		if err := CreateFile("Dockerfile", dockerfileContent); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Dockerfile"})
-			return
-		}
+	var volumes []string
+	if application.Language == "python" {
+		volumes = []string{"./init.sql:/docker-entrypoint-initdb.d/init.sql"}
+	}
+
+	return []models.Service{
+		{
+			Name:      "app",
+			Role:      models.ServiceRoleApplication,
+			Ports:     []models.ServicePort{{InternalDocker: application.Ports.InternalDocker, ExternalDocker: application.Ports.ExternalDocker}},
+			DependsOn: []string{"database"},
+		},
+		{
+			Name:  "database",
+			Role:  models.ServiceRoleDatabase,
+			Image: database.Driver,
+			Ports: []models.ServicePort{{InternalDocker: database.Ports.InternalDocker, ExternalDocker: database.Ports.ExternalDocker}},
+			Env: map[string]string{
+				"MYSQL_ROOT_PASSWORD":        database.RootPassword,
+				"MYSQL_DATABASE":             database.Name,
+				"MYSQL_ALLOW_EMPTY_PASSWORD": "yes",
+			},
+			Command: []string{"mysqld", "--character-set-server=utf8mb4", "--collation-server=utf8mb4_unicode_ci", "--bind-address=0.0.0.0"},
+			Volumes: volumes,
+		},
 	}
 }
 
-func generateJavaDockerFile(cfg models.Configuration) string {
-	dockerImage := cfg.Artifact.Application.DockerImage
-	target := cfg.Artifact.Application.Artifact.Target
-	internalPort := cfg.Artifact.Application.Ports.InternalDocker
-	builderName := cfg.Artifact.Application.Builder.Name
-	builderDockerImage := cfg.Artifact.Application.Builder.DockerImage
-
-	// Add code checkout logic based on configuration
-	var codeCheckoutCmd string
-	if cfg.Artifact.CodeCheckout.Git.Enabled {
-		codeCheckoutCmd += fmt.Sprintf("ARG GIT_USERNAME=%s\n", cfg.Artifact.CodeCheckout.Git.RepoUser)
-		codeCheckoutCmd += fmt.Sprintf("ARG GIT_PASSWORD=%s\n", cfg.Artifact.CodeCheckout.Git.RepoPassword)
-		codeCheckoutCmd += "RUN git config --global credential.helper '!f() { echo \"username=${GIT_USERNAME}\"; echo \"password=${GIT_PASSWORD}\"; }; f'\n"
-		codeCheckoutCmd += fmt.Sprintf("RUN git clone https://${GIT_USERNAME}:${GIT_PASSWORD}@%s /app || (echo \"Git clone failed\"; exit 1)\n", cfg.Artifact.CodeCheckout.Git.RepoURL)
+// ConfiguredServiceNames returns the services of the configuration applied to the workspace, or the
+// application and database containers before the first configure
+func ConfiguredServiceNames(workspace Workspace) []string {
+	content, err := ioutil.ReadFile(workspace.Path("config.json"))
+	if err != nil {
+		return []string{"app", "database"}
 	}
-	if cfg.Artifact.CodeCheckout.SCP.Enabled {
-		codeCheckoutCmd += PullCodeFromSCP(cfg.Artifact.CodeCheckout.SCP.Configurations.Folder)
+
+	var cfg models.Configuration
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		log.Printf("Error reading the services of %s: %s", workspace.Path("config.json"), err)
+		return []string{"app", "database"}
 	}
 
-	dockerfile := fmt.Sprintf(`FROM %s as builder
+	var names []string
+	for _, service := range ComposeServices(cfg) {
+		names = append(names, service.Name)
+	}
 
-# Install builder dependencies
-RUN apt-get update && apt-get install -y git
+	return names
+}
 
-WORKDIR /app
+// IsComposeService reports whether the name is a service of the compose file of the workspace
+func IsComposeService(workspace Workspace, name string) bool {
+	for _, service := range ConfiguredServiceNames(workspace) {
+		if service == name {
+			return true
+		}
+	}
 
-# Code checkout
-%s
+	return false
+}
 
-COPY wait-for-it.sh /wait-for-it.sh
-RUN chmod +x /wait-for-it.sh
+// writeComposeService writes one service of the compose file and the name of its container
+func writeComposeService(content *strings.Builder, service models.Service, containerName string) {
+	fmt.Fprintf(content, "  %s:\n    container_name: %s\n", service.Name, containerName)
 
-# Build the application
-RUN %s install -DskipTests
+	switch {
+	case service.Image != "":
+		fmt.Fprintf(content, "    image: %s\n", yamlString(service.Image))
+	case service.Build != "":
+		fmt.Fprintf(content, "    build:\n      context: %s\n", yamlString(service.Build))
+	default:
+		// application services are built from the generated Dockerfile
+		content.WriteString("    build:\n      context: .\n      dockerfile: Dockerfile\n")
+	}
 
-FROM %s
+	if len(service.Env) > 0 {
+		names := make([]string, 0, len(service.Env))
+		for name := range service.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
 
-WORKDIR /app
+		content.WriteString("    environment:\n")
+		for _, name := range names {
+			fmt.Fprintf(content, "      %s: %s\n", yamlString(name), yamlString(service.Env[name]))
+		}
+	}
+
+	// ports without an external port are only exposed to the other services
+	var published, exposed []string
+	for _, port := range service.Ports {
+		if port.ExternalDocker == 0 {
+			exposed = append(exposed, fmt.Sprintf("\"%d\"", port.InternalDocker))
+		} else {
+			published = append(published, fmt.Sprintf("\"%d:%d\"", port.ExternalDocker, port.InternalDocker))
+		}
+	}
+	writeComposeList(content, "ports", published)
+	writeComposeList(content, "expose", exposed)
+
+	writeComposeList(content, "depends_on", service.DependsOn)
+
+	if len(service.Command) > 0 {
+		command := make([]string, len(service.Command))
+		for index, argument := range service.Command {
+			command[index] = yamlString(argument)
+		}
+		fmt.Fprintf(content, "    command: [%s]\n", strings.Join(command, ", "))
+	}
 
-RUN apt-get update && apt-get install -y netcat
+	volumes := make([]string, len(service.Volumes))
+	for index, volume := range service.Volumes {
+		volumes[index] = yamlString(volume)
+	}
+	writeComposeList(content, "volumes", volumes)
+}
 
-COPY --from=builder /wait-for-it.sh /wait-for-it.sh
-RUN chmod +x /wait-for-it.sh
+func writeComposeList(content *strings.Builder, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
 
-COPY --from=builder %s app.jar
+	fmt.Fprintf(content, "    %s:\n", key)
+	for _, value := range values {
+		fmt.Fprintf(content, "      - %s\n", value)
+	}
+}
 
-EXPOSE %d
+// yamlString quotes a value for the compose file, a JSON string is a valid YAML double-quoted scalar
+func yamlString(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
 
-CMD ["/wait-for-it.sh", "database:%d", "--", "java", "-jar", "app.jar"]
-`, builderDockerImage, codeCheckoutCmd, getBuilderShortName(builderName), dockerImage, target, internalPort, cfg.Artifact.Database.Ports.InternalDocker)
+// databaseAddress returns the host:port the application waits for before it starts, the first port
+// of the first database service, or "" without one
+func databaseAddress(cfg models.Configuration) string {
+	for _, service := range ComposeServices(cfg) {
+		if service.Role == models.ServiceRoleDatabase && len(service.Ports) > 0 {
+			return fmt.Sprintf("%s:%d", service.Name, service.Ports[0].InternalDocker)
+		}
+	}
 
-	return dockerfile
+	return ""
+}
+
+// GenerateDockerfile renders the Dockerfile of the application with the runtime template of its
+// language and framework
+func GenerateDockerfile(cfg models.Configuration, workspace Workspace, c *gin.Context) {
+	runtimeTemplate, err := SelectTemplate(cfg.Artifact.Application.Language, cfg.Artifact.Application.Framework)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dockerfileContent, err := runtimeTemplate.Render(cfg)
+	if err != nil {
+		log.Printf("Error rendering the Dockerfile: %s", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render the Dockerfile: " + err.Error()})
+		return
+	}
+
+	if err := CreateFile(workspace.Path("Dockerfile"), dockerfileContent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Dockerfile"})
+		return
+	}
 }
 
 func GenerateWaitForItScriptContent() string {
@@ -172,7 +257,8 @@ func containerExists(cli *client.Client, name string) (bool, error) {
 	return true, nil
 }
 
-func GetDockerContainersState() (map[string]interface{}, error) {
+// GetDockerContainersState returns whether the container of every service of the workspace is running
+func GetDockerContainersState(workspace Workspace) (map[string]interface{}, error) {
 	cli, err := client.NewEnvClient()
 	if err != nil {
 		log.Fatal(err)
@@ -182,11 +268,9 @@ func GetDockerContainersState() (map[string]interface{}, error) {
 
 	containerStates := make(map[string]interface{})
 
-	containerNames := []string{"app", "database"}
-
-	for _, name := range containerNames {
+	for _, name := range ConfiguredServiceNames(workspace) {
 		// Check if the container exists
-		exists, err := containerExists(cli, name)
+		exists, err := containerExists(cli, workspace.ContainerName(name))
 		if err != nil {
 			log.Printf("Error checking if container %s exists: %s", name, err)
 			return nil, err
@@ -194,7 +278,7 @@ func GetDockerContainersState() (map[string]interface{}, error) {
 
 		if exists {
 			// Check the status using Docker SDK
-			containerInfo, err := cli.ContainerInspect(context.Background(), name)
+			containerInfo, err := cli.ContainerInspect(context.Background(), workspace.ContainerName(name))
 			if err != nil {
 				log.Printf("Error checking container status for %s: %s", name, err)
 				return nil, err
@@ -217,82 +301,6 @@ func GetDockerContainersState() (map[string]interface{}, error) {
 	return containerStates, nil
 }
 
-func generatePythonDockerFile(cfg models.Configuration) string {
-
-	var codeCheckoutCmd string
-	if cfg.Artifact.CodeCheckout.Git.Enabled {
-		codeCheckoutCmd += fmt.Sprintf("ARG GIT_USERNAME=%s\n", cfg.Artifact.CodeCheckout.Git.RepoUser)
-		codeCheckoutCmd += fmt.Sprintf("ARG GIT_PASSWORD=%s\n", cfg.Artifact.CodeCheckout.Git.RepoPassword)
-		codeCheckoutCmd += "RUN git config --global credential.helper '!f() { echo \"username=${GIT_USERNAME}\"; echo \"password=${GIT_PASSWORD}\"; }; f'\n"
-		codeCheckoutCmd += fmt.Sprintf("RUN git clone https://${GIT_USERNAME}:${GIT_PASSWORD}@%s /app_builder || (echo \"Git clone failed\"; exit 1)\n", cfg.Artifact.CodeCheckout.Git.RepoURL)
-	}
-	if cfg.Artifact.CodeCheckout.SCP.Enabled {
-		codeCheckoutCmd += PullCodeFromSCP(cfg.Artifact.CodeCheckout.SCP.Configurations.Folder)
-	}
-
-	return fmt.Sprintf(`# Use an official Python runtime as a parent image
-FROM %s AS builder
-
-# Set environment variables
-ENV PYTHONDONTWRITEBYTECODE 1
-ENV PYTHONUNBUFFERED 1
-
-# Install system dependencies
-RUN apt-get update \
-    && apt-get install -y --no-install-recommends git pkg-config libmariadb-dev-compat build-essential \
-    && rm -rf /var/lib/apt/lists/*
-
-# Set the working directory
-WORKDIR /app_builder
-
-# Code checkout
-%s
-
-COPY wait-for-it.sh /wait-for-it.sh
-RUN chmod +x /wait-for-it.sh
-
-# Print the contents of the cloned directory (for debugging)
-RUN ls -la /app_builder
-
-# Set the working directory again (just to be explicit)
-WORKDIR /app_builder
-
-# Create and activate a virtual environment
-RUN python -m venv venv
-ENV PATH="/app_builder/venv/bin:$PATH"
-
-# Install dependencies from requirements.txt within the virtual environment
-RUN . /app_builder/venv/bin/activate && pip install --upgrade pip \
-    && pip install -r /app_builder/requirements.txt
-
-# Second stage for the final image
-FROM %s
-
-# Set environment variables
-ENV PYTHONDONTWRITEBYTECODE 1
-ENV PYTHONUNBUFFERED 1
-
-# Set the working directory
-WORKDIR /app
-
-# Copy files from the builder stage
-COPY --from=builder /app_builder /app
-
-COPY --from=builder /wait-for-it.sh /wait-for-it.sh
-RUN chmod +x /wait-for-it.sh
-
-# Expose the port the app runs on
-EXPOSE %d
-
-# Set the working directory for the CMD
-WORKDIR /app
-
-# Create the database, run migrations, and start the server
-CMD /wait-for-it.sh database:%d -- /app/venv/bin/python manage.py makemigrations && /app/venv/bin/python manage.py migrate && /app/venv/bin/python manage.py runserver 0.0.0.0:%d
-`, cfg.Artifact.Application.DockerImage, codeCheckoutCmd,
-		cfg.Artifact.Application.DockerImage, cfg.Artifact.Application.Ports.InternalDocker, cfg.Artifact.Database.Ports.InternalDocker, cfg.Artifact.Application.Ports.InternalDocker)
-}
-
 func getBuilderShortName(builderName string) string {
 	switch builderName {
 	case "maven":
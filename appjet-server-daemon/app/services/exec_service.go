@@ -0,0 +1,347 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bounds of a one-shot command
+const (
+	defaultExecTimeout = 30 * time.Second
+	maxExecTimeout     = 10 * time.Minute
+	// maxExecOutput is the most stdout, and stderr, returned by a one-shot command, the rest is dropped
+	maxExecOutput = 1024 * 1024
+	// execKillGrace is how long killing a command may take, a one-shot command answers at most this long
+	// after its timeout
+	execKillGrace = 5 * time.Second
+)
+
+// ErrInvalidExecRequest is returned for commands without arguments or with an invalid timeout
+var ErrInvalidExecRequest = errors.New("invalid exec request")
+
+// ExecRequest is a one-shot command to run in a container
+type ExecRequest struct {
+	Command []string `json:"command"`
+	Timeout string   `json:"timeout"` // 30s when empty, at most 10m
+}
+
+// ExecResult is the outcome of a one-shot command
+type ExecResult struct {
+	ExitCode   int    `json:"exit-code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	TimedOut   bool   `json:"timed-out,omitempty"`
+	KillError  string `json:"kill-error,omitempty"` // why a timed out command could not be killed
+	DurationMs int64  `json:"duration-ms"`
+}
+
+// ExecSession is an interactive command attached to a TTY
+type ExecSession struct {
+	cli       *client.Client
+	container string
+	id        string
+	conn      types.HijackedResponse
+	closeOnce sync.Once
+}
+
+// RunExec runs a command in the container through the Docker exec API and returns its output and exit
+// code. A command still running after the timeout is killed and reported with TimedOut, and with
+// KillError when it could not be killed.
+func RunExec(ctx context.Context, container string, request ExecRequest) (*ExecResult, error) {
+	timeout, err := execTimeout(request)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := client.NewEnvClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	if err := checkContainer(ctx, cli, container); err != nil {
+		return nil, err
+	}
+
+	created, err := cli.ContainerExecCreate(ctx, container, types.ExecConfig{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          request.Command,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating the exec: %w", err)
+	}
+
+	start := time.Now()
+	attached, err := cli.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return nil, fmt.Errorf("error starting the exec: %w", err)
+	}
+	defer attached.Close()
+
+	stdout := &limitedBuffer{limit: maxExecOutput}
+	stderr := &limitedBuffer{limit: maxExecOutput}
+	done := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, attached.Reader)
+		done <- err
+	}()
+
+	result := &ExecResult{}
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, fmt.Errorf("error reading the exec output: %w", err)
+		}
+	case <-time.After(timeout):
+		result.TimedOut = true
+		if err := killExec(cli, container, created.ID); err != nil {
+			result.KillError = err.Error()
+			log.Printf("Error killing exec %s in container %s: %s", created.ID, container, err)
+		}
+		attached.Close()
+		<-done
+	case <-ctx.Done():
+		if err := killExec(cli, container, created.ID); err != nil {
+			log.Printf("Error killing exec %s in container %s: %s", created.ID, container, err)
+		}
+		return nil, ctx.Err()
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	inspect, err := cli.ContainerExecInspect(context.Background(), created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the exit code: %w", err)
+	}
+	result.ExitCode = inspect.ExitCode
+	if result.TimedOut {
+		result.ExitCode = -1
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+
+	return result, nil
+}
+
+// StartExecSession starts an interactive command in the container, attached to a TTY
+func StartExecSession(ctx context.Context, container string, command []string, rows uint, cols uint) (*ExecSession, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("%w: the command is required", ErrInvalidExecRequest)
+	}
+
+	cli, err := client.NewEnvClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkContainer(ctx, cli, container); err != nil {
+		cli.Close()
+		return nil, err
+	}
+
+	config := types.ExecConfig{
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          command,
+	}
+	if rows > 0 && cols > 0 {
+		config.ConsoleSize = &[2]uint{rows, cols}
+	}
+
+	created, err := cli.ContainerExecCreate(ctx, container, config)
+	if err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("error creating the exec: %w", err)
+	}
+
+	attached, err := cli.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{Tty: true, ConsoleSize: config.ConsoleSize})
+	if err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("error starting the exec: %w", err)
+	}
+
+	return &ExecSession{cli: cli, container: container, id: created.ID, conn: attached}, nil
+}
+
+// Output returns the TTY output of the command, it ends when the command exits
+func (s *ExecSession) Output() io.Reader {
+	return s.conn.Reader
+}
+
+// Write sends input to the command
+func (s *ExecSession) Write(p []byte) (int, error) {
+	return s.conn.Conn.Write(p)
+}
+
+// Resize changes the size of the TTY of the command
+func (s *ExecSession) Resize(rows uint, cols uint) error {
+	return s.cli.ContainerExecResize(context.Background(), s.id, container.ResizeOptions{Height: rows, Width: cols})
+}
+
+// ExitCode returns the exit code of the command, waiting a moment for it to exit once its output
+// ended. It is -1 while the command still runs.
+func (s *ExecSession) ExitCode() int {
+	for attempt := 0; attempt < 10; attempt++ {
+		inspect, err := s.cli.ContainerExecInspect(context.Background(), s.id)
+		if err != nil {
+			return -1
+		}
+		if !inspect.Running {
+			return inspect.ExitCode
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return -1
+}
+
+// Close detaches from the command, and kills it in the container if it still runs
+func (s *ExecSession) Close() {
+	s.closeOnce.Do(func() {
+		if err := killExec(s.cli, s.container, s.id); err != nil {
+			log.Printf("Error killing exec %s in container %s: %s", s.id, s.container, err)
+		}
+		s.conn.Close()
+		s.cli.Close()
+	})
+}
+
+func checkContainer(ctx context.Context, cli *client.Client, container string) error {
+	info, err := cli.ContainerInspect(ctx, container)
+	if client.IsErrNotFound(err) {
+		return ErrContainerNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if info.State == nil || !info.State.Running {
+		return fmt.Errorf("%w: container %s is not running", ErrInvalidExecRequest, container)
+	}
+
+	return nil
+}
+
+func execTimeout(request ExecRequest) (time.Duration, error) {
+	if len(request.Command) == 0 {
+		return 0, fmt.Errorf("%w: the command is required", ErrInvalidExecRequest)
+	}
+	if request.Timeout == "" {
+		return defaultExecTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(request.Timeout)
+	if err != nil || timeout <= 0 || timeout > maxExecTimeout {
+		return 0, fmt.Errorf("%w: timeout must be a duration of at most %s", ErrInvalidExecRequest, maxExecTimeout)
+	}
+
+	return timeout, nil
+}
+
+// killExec kills the process of an exec with a second exec running kill in the container. The Docker
+// API cannot stop an exec and reports its pid in the namespace of the host, the pid in the container is
+// read from /proc of the host. It fails when the daemon does not see the processes of the host, or the
+// image has no kill.
+func killExec(cli *client.Client, container string, execID string) error {
+	inspect, err := cli.ContainerExecInspect(context.Background(), execID)
+	if err != nil {
+		return err
+	}
+	if !inspect.Running || inspect.Pid <= 0 {
+		return nil
+	}
+
+	pid, err := containerPid(inspect.Pid)
+	if err != nil {
+		return err
+	}
+	exitCode, err := runContainerCommand(cli, container, []string{"kill", "-KILL", strconv.Itoa(pid)})
+	if err != nil {
+		return fmt.Errorf("error running kill in the container: %w", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("kill exited with code %d in the container", exitCode)
+	}
+
+	return nil
+}
+
+// containerPid returns the pid a process of the host has in the pid namespace of its container, the last
+// pid of the NSpid line of /proc/:pid/status
+func containerPid(hostPid int) (int, error) {
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", hostPid))
+	if err != nil {
+		return 0, fmt.Errorf("cannot read the pid of the exec in the container: %w", err)
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		if !strings.HasPrefix(line, "NSpid:") {
+			continue
+		}
+		if pids := strings.Fields(strings.TrimPrefix(line, "NSpid:")); len(pids) > 0 {
+			return strconv.Atoi(pids[len(pids)-1])
+		}
+	}
+
+	return 0, fmt.Errorf("cannot read the pid of the exec in the container: no NSpid in /proc/%d/status", hostPid)
+}
+
+// runContainerCommand runs a short command in the container, discards its output and returns its exit code
+func runContainerCommand(cli *client.Client, container string, command []string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), execKillGrace)
+	defer cancel()
+
+	created, err := cli.ContainerExecCreate(ctx, container, types.ExecConfig{AttachStdout: true, AttachStderr: true, Cmd: command})
+	if err != nil {
+		return 0, fmt.Errorf("error creating the exec: %w", err)
+	}
+	attached, err := cli.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return 0, fmt.Errorf("error starting the exec: %w", err)
+	}
+	defer attached.Close()
+
+	if _, err := io.Copy(io.Discard, attached.Reader); err != nil {
+		return 0, err
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return inspect.ExitCode, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and drops the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
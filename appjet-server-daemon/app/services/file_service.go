@@ -9,23 +9,23 @@ import (
 	"os"
 )
 
-func GenerateConfigFile(config models.Configuration, c *gin.Context) {
+func GenerateConfigFile(config models.Configuration, workspace Workspace, c *gin.Context) {
 	configJSON, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to marshal JSON"})
 		return
 	}
 
-	err = ioutil.WriteFile("config.json", configJSON, 0644)
+	err = ioutil.WriteFile(workspace.Path("config.json"), configJSON, 0644)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to write JSON to file"})
 		return
 	}
 }
 
-func GenerateWaitForItScript(c *gin.Context) {
+func GenerateWaitForItScript(workspace Workspace, c *gin.Context) {
 	waitForItScriptContent := GenerateWaitForItScriptContent()
-	err := CreateFile("wait-for-it.sh", waitForItScriptContent)
+	err := CreateFile(workspace.Path("wait-for-it.sh"), waitForItScriptContent)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create Docker Compose file"})
 		return
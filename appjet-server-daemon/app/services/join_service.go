@@ -0,0 +1,272 @@
+package services
+
+import (
+	"appjet-server-daemon/app/models"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers identifying the daemon in the calls it signs to the decision manager
+const (
+	ClusterHeader = "X-Appjet-Cluster"
+	ServerHeader  = "X-Appjet-Server"
+)
+
+// defaultHeartbeatInterval is used until the decision manager tells the daemon its interval
+const defaultHeartbeatInterval = 30 * time.Second
+
+// ErrNotJoined is returned when the daemon did not join a decision manager
+var ErrNotJoined = errors.New("the daemon did not join a decision manager")
+
+var managerClient = &http.Client{Timeout: 30 * time.Second}
+
+// JoinRequest describes how the decision manager reaches the daemon once it joined
+type JoinRequest struct {
+	Manager  string
+	Token    string
+	Cluster  string
+	Name     string
+	Scheme   string
+	Address  string // the address the join request comes from when empty
+	Port     int
+	BasePath string
+	Hosts    []string // added to the certificate request
+}
+
+// JoinFile returns the path of the join state file, APPJET_DAEMON_JOIN_FILE or daemon-join.json
+func JoinFile() string {
+	if file := os.Getenv("APPJET_DAEMON_JOIN_FILE"); file != "" {
+		return file
+	}
+
+	return "daemon-join.json"
+}
+
+// Join adds the daemon to the inventory of the decision manager with a one-time join token, and writes
+// the secret, the certificate and the CA it receives, and the join state used by the heartbeats
+func Join(request JoinRequest) (*models.JoinState, error) {
+	hosts := request.Hosts
+	if request.Address != "" {
+		hosts = append(hosts, request.Address)
+	}
+	csr, err := CreateCertificateRequest(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"token":     request.Token,
+		"cluster":   request.Cluster,
+		"name":      request.Name,
+		"scheme":    request.Scheme,
+		"address":   request.Address,
+		"port":      request.Port,
+		"base-path": request.BasePath,
+		"csr":       string(csr),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manager := strings.TrimSuffix(request.Manager, "/")
+	response, err := managerClient.Post(manager+"/appjet/join", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error calling the decision manager: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the decision manager refused the join (%d): %s", response.StatusCode, body)
+	}
+
+	var joined struct {
+		Cluster           string `json:"cluster"`
+		Server            string `json:"server"`
+		Secret            string `json:"secret"`
+		Certificate       string `json:"certificate"`
+		CA                string `json:"ca"`
+		HeartbeatInterval string `json:"heartbeat-interval"`
+	}
+	if err := json.Unmarshal(body, &joined); err != nil {
+		return nil, fmt.Errorf("invalid join response: %w", err)
+	}
+
+	// a daemon joining again replaces its secret, the previous one belonged to the old registration
+	if err := writeSecret(models.DaemonSecret{Secret: joined.Secret, CreatedAt: time.Now()}); err != nil {
+		return nil, err
+	}
+	_, _, certificateFile, caFile := TLSFiles()
+	if err := os.WriteFile(certificateFile, []byte(joined.Certificate), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", certificateFile, err)
+	}
+	if err := os.WriteFile(caFile, []byte(joined.CA), 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", caFile, err)
+	}
+
+	state := models.JoinState{
+		Manager:           manager,
+		Cluster:           joined.Cluster,
+		Server:            joined.Server,
+		HeartbeatInterval: joined.HeartbeatInterval,
+		JoinedAt:          time.Now(),
+	}
+	if err := writeJoinState(state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// StartHeartbeats reports the state of the daemon to the decision manager it joined, at the interval
+// the decision manager asks for. It returns ErrNotJoined when the daemon did not join one.
+func StartHeartbeats(version string) error {
+	state, err := readJoinState()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		interval := parseHeartbeatInterval(state.HeartbeatInterval)
+		for {
+			next, err := sendHeartbeat(state, version)
+			if err != nil {
+				log.Printf("Error sending the heartbeat to %s: %s", state.Manager, err)
+			} else if next > 0 {
+				interval = next
+			}
+			time.Sleep(interval)
+		}
+	}()
+
+	return nil
+}
+
+// sendHeartbeat signs the heartbeat with the daemon secret and returns the interval of the next one
+func sendHeartbeat(state *models.JoinState, version string) (time.Duration, error) {
+	hostname, _ := os.Hostname()
+	heartbeat := models.Heartbeat{Version: version, Hostname: hostname}
+	if free, err := freeDiskBytes("."); err == nil {
+		heartbeat.FreeDiskBytes = free
+	}
+	// the containers of every project, by container name
+	heartbeat.Containers = map[string]interface{}{}
+	for _, workspace := range Workspaces() {
+		containers, err := GetDockerContainersState(workspace)
+		if err != nil {
+			continue
+		}
+		for service, state := range containers {
+			heartbeat.Containers[workspace.ContainerName(service)] = state
+		}
+	}
+
+	body, err := json.Marshal(heartbeat)
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, state.Manager+"/appjet/heartbeat", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if err := signManagerRequest(request, body, state); err != nil {
+		return 0, err
+	}
+
+	response, err := managerClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		Error             string `json:"error"`
+		HeartbeatInterval string `json:"heartbeat-interval"`
+	}
+	_ = json.NewDecoder(response.Body).Decode(&result)
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("the decision manager answered %d: %s", response.StatusCode, result.Error)
+	}
+
+	return parseHeartbeatInterval(result.HeartbeatInterval), nil
+}
+
+// signManagerRequest signs a call to the decision manager the way the decision manager signs its calls
+// to the daemon, and adds the cluster and server the daemon joined as
+func signManagerRequest(request *http.Request, body []byte, state *models.JoinState) error {
+	secret, err := currentSecret()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	timestamp, nonceHex := strconv.FormatInt(time.Now().Unix(), 10), hex.EncodeToString(nonce)
+	bodyHash := HashBody(body)
+	request.Header.Set(ClusterHeader, state.Cluster)
+	request.Header.Set(ServerHeader, state.Server)
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(NonceHeader, nonceHex)
+	request.Header.Set(ContentSHA256Header, bodyHash)
+	request.Header.Set(SignatureHeader, requestSignature(secret.Secret, request.Method, request.URL.RequestURI(), timestamp, nonceHex, bodyHash))
+
+	return nil
+}
+
+func parseHeartbeatInterval(value string) time.Duration {
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return defaultHeartbeatInterval
+	}
+
+	return interval
+}
+
+func readJoinState() (*models.JoinState, error) {
+	content, err := os.ReadFile(JoinFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotJoined
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state models.JoinState
+	if err := json.Unmarshal(content, &state); err != nil || state.Manager == "" {
+		return nil, fmt.Errorf("invalid join state file %s", JoinFile())
+	}
+
+	return &state, nil
+}
+
+func writeJoinState(state models.JoinState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(JoinFile(), content, 0644); err != nil {
+		return fmt.Errorf("error writing the join state: %w", err)
+	}
+
+	return nil
+}
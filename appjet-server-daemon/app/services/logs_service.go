@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"strconv"
+)
+
+var (
+	// ErrContainerNotFound is returned for containers that do not exist on this server
+	ErrContainerNotFound = errors.New("container not found")
+	// ErrInvalidLogOptions is returned for a tail that is neither a number nor "all"
+	ErrInvalidLogOptions = errors.New("invalid log options")
+)
+
+// LogOptions selects the logs of a container
+type LogOptions struct {
+	Follow     bool
+	Tail       string // number of lines from the end, or "all"
+	Since      string // a duration such as "10m", or a timestamp
+	Timestamps bool
+}
+
+// ContainerLogs is an open log stream of a container
+type ContainerLogs struct {
+	cli    *client.Client
+	reader io.ReadCloser
+	tty    bool
+}
+
+// OpenContainerLogs opens the logs of a container with the Docker SDK. The stream ends with the logs,
+// or never with Follow, until ctx is cancelled.
+func OpenContainerLogs(ctx context.Context, name string, options LogOptions) (*ContainerLogs, error) {
+	if options.Tail != "all" {
+		if lines, err := strconv.Atoi(options.Tail); err != nil || lines < 0 {
+			return nil, fmt.Errorf("%w: tail must be a number of lines or \"all\"", ErrInvalidLogOptions)
+		}
+	}
+
+	cli, err := client.NewEnvClient()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := cli.ContainerInspect(ctx, name)
+	if client.IsErrNotFound(err) {
+		cli.Close()
+		return nil, ErrContainerNotFound
+	}
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+
+	reader, err := cli.ContainerLogs(ctx, name, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     options.Follow,
+		Tail:       options.Tail,
+		Since:      options.Since,
+		Timestamps: options.Timestamps,
+	})
+	if err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLogOptions, err)
+	}
+
+	return &ContainerLogs{cli: cli, reader: reader, tty: info.Config != nil && info.Config.Tty}, nil
+}
+
+// CopyTo writes the logs to w, stdout and stderr merged, until the stream ends
+func (l *ContainerLogs) CopyTo(w io.Writer) error {
+	// containers without a tty multiplex stdout and stderr in one stream
+	if !l.tty {
+		_, err := stdcopy.StdCopy(w, w, l.reader)
+		return err
+	}
+
+	_, err := io.Copy(w, l.reader)
+	return err
+}
+
+// Close closes the stream and the docker client
+func (l *ContainerLogs) Close() error {
+	l.reader.Close()
+	return l.cli.Close()
+}
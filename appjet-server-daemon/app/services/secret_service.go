@@ -0,0 +1,257 @@
+package services
+
+import (
+	"appjet-server-daemon/app/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of a call signed by the decision manager
+const (
+	SignatureHeader     = "X-Appjet-Signature"
+	TimestampHeader     = "X-Appjet-Timestamp"
+	NonceHeader         = "X-Appjet-Nonce"
+	ContentSHA256Header = "X-Appjet-Content-SHA256"
+)
+
+// maxClockSkew is how far the timestamp of a signed call can be from the clock of the daemon.
+// Nonces are remembered for twice as long, so a captured call cannot be replayed.
+const maxClockSkew = 5 * time.Minute
+
+// defaultMaxBodyMB is the largest body of a signed call, in MB, when APPJET_DAEMON_MAX_BODY_MB is not set
+const defaultMaxBodyMB = 100
+
+// startedAt is when the daemon started. The nonces are only kept in memory, so calls signed before are
+// refused: they could be replays of calls received by the previous process.
+var startedAt = time.Now()
+
+var (
+	// ErrNoSecret is returned when the daemon secret file does not exist
+	ErrNoSecret = errors.New("no daemon secret, run \"appjet-server-daemon secret generate\"")
+	// ErrSecretExists is returned when generating a secret would overwrite the current one
+	ErrSecretExists = errors.New("a daemon secret already exists, use \"appjet-server-daemon secret rotate\" to replace it")
+	// ErrInvalidSignature is returned for calls that are not signed with the daemon secret
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+var (
+	secretMutex   sync.Mutex
+	cachedSecret  *models.DaemonSecret
+	cachedModTime time.Time
+	seenNonces    = map[string]time.Time{}
+)
+
+// SecretFile returns the path of the daemon secret file, APPJET_DAEMON_SECRET_FILE or daemon-secret.json
+func SecretFile() string {
+	if file := os.Getenv("APPJET_DAEMON_SECRET_FILE"); file != "" {
+		return file
+	}
+
+	return "daemon-secret.json"
+}
+
+// GenerateSecret creates the daemon secret file and returns the new secret
+func GenerateSecret() (string, error) {
+	if _, err := os.Stat(SecretFile()); err == nil {
+		return "", ErrSecretExists
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+
+	return secret, writeSecret(models.DaemonSecret{Secret: secret, CreatedAt: time.Now()})
+}
+
+// RotateSecret replaces the daemon secret and keeps accepting the previous one for the grace period
+func RotateSecret(grace time.Duration) (string, error) {
+	current, err := readSecret()
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+
+	previousExpiresAt := time.Now().Add(grace)
+	return secret, writeSecret(models.DaemonSecret{
+		Secret:            secret,
+		CreatedAt:         time.Now(),
+		PreviousSecret:    current.Secret,
+		PreviousExpiresAt: &previousExpiresAt,
+	})
+}
+
+// LoadSecret checks that the daemon secret file can be read
+func LoadSecret() error {
+	_, err := currentSecret()
+	return err
+}
+
+// MaxBodySize returns the largest body of a signed call in bytes, APPJET_DAEMON_MAX_BODY_MB or 100 MB
+func MaxBodySize() int64 {
+	if megabytes, err := strconv.ParseInt(os.Getenv("APPJET_DAEMON_MAX_BODY_MB"), 10, 64); err == nil && megabytes > 0 {
+		return megabytes << 20
+	}
+
+	return defaultMaxBodyMB << 20
+}
+
+// CheckSignatureHeaders checks what can be checked before reading the body of a call: the timestamp,
+// nonce and signature are present, and the timestamp is recent and not older than the daemon
+func CheckSignatureHeaders(timestamp string, nonce string, signature string) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing or invalid timestamp", ErrInvalidSignature)
+	}
+	signedAt := time.Unix(seconds, 0)
+	if skew := time.Since(signedAt); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("%w: timestamp is more than %s away from the daemon clock", ErrInvalidSignature, maxClockSkew)
+	}
+	if signedAt.Before(startedAt.Truncate(time.Second)) {
+		return fmt.Errorf("%w: timestamp is older than the start of the daemon", ErrInvalidSignature)
+	}
+	if nonce == "" {
+		return fmt.Errorf("%w: missing nonce", ErrInvalidSignature)
+	}
+	if signature == "" {
+		return fmt.Errorf("%w: missing signature", ErrInvalidSignature)
+	}
+
+	return nil
+}
+
+// VerifySignature checks a call signed by the decision manager: the headers must pass
+// CheckSignatureHeaders, the nonce be unused and the signature made with the current secret, or the
+// previous one during its grace period. bodyHash must be the hash of the body actually received.
+func VerifySignature(method string, path string, timestamp string, nonce string, bodyHash string, signature string) error {
+	if err := CheckSignatureHeaders(timestamp, nonce, signature); err != nil {
+		return err
+	}
+
+	secret, err := currentSecret()
+	if err != nil {
+		return err
+	}
+
+	valid := hmac.Equal([]byte(signature), []byte(requestSignature(secret.Secret, method, path, timestamp, nonce, bodyHash)))
+	if !valid && secret.PreviousSecret != "" && secret.PreviousExpiresAt != nil && time.Now().Before(*secret.PreviousExpiresAt) {
+		valid = hmac.Equal([]byte(signature), []byte(requestSignature(secret.PreviousSecret, method, path, timestamp, nonce, bodyHash)))
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	return useNonce(nonce)
+}
+
+// HashBody returns the hex sha256 of a request body, as sent in X-Appjet-Content-SHA256
+func HashBody(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+// requestSignature must match the signature computed by the decision manager
+func requestSignature(secret string, method string, path string, timestamp string, nonce string, bodyHash string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n" + bodyHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// useNonce rejects nonces seen within the replay window and forgets the older ones
+func useNonce(nonce string) error {
+	secretMutex.Lock()
+	defer secretMutex.Unlock()
+
+	now := time.Now()
+	for seen, at := range seenNonces {
+		if now.Sub(at) > 2*maxClockSkew {
+			delete(seenNonces, seen)
+		}
+	}
+
+	if _, seen := seenNonces[nonce]; seen {
+		return fmt.Errorf("%w: nonce already used", ErrInvalidSignature)
+	}
+	seenNonces[nonce] = now
+
+	return nil
+}
+
+// currentSecret returns the daemon secret, read again whenever the file changes (e.g. after a rotation)
+func currentSecret() (*models.DaemonSecret, error) {
+	info, err := os.Stat(SecretFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSecret
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secretMutex.Lock()
+	defer secretMutex.Unlock()
+
+	if cachedSecret == nil || !info.ModTime().Equal(cachedModTime) {
+		secret, err := readSecret()
+		if err != nil {
+			return nil, err
+		}
+		cachedSecret = secret
+		cachedModTime = info.ModTime()
+	}
+
+	return cachedSecret, nil
+}
+
+func readSecret() (*models.DaemonSecret, error) {
+	content, err := os.ReadFile(SecretFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSecret
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var secret models.DaemonSecret
+	if err := json.Unmarshal(content, &secret); err != nil || secret.Secret == "" {
+		return nil, fmt.Errorf("invalid daemon secret file %s", SecretFile())
+	}
+
+	return &secret, nil
+}
+
+func writeSecret(secret models.DaemonSecret) error {
+	content, err := json.MarshalIndent(secret, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// the file is written next to the final one and renamed, so the daemon never reads half of it
+	temporary := SecretFile() + ".tmp"
+	if err := os.WriteFile(temporary, content, 0600); err != nil {
+		return fmt.Errorf("error writing daemon secret: %w", err)
+	}
+
+	return os.Rename(temporary, SecretFile())
+}
+
+func newSecret() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("error generating daemon secret: %w", err)
+	}
+
+	return hex.EncodeToString(random), nil
+}
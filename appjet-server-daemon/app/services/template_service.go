@@ -0,0 +1,239 @@
+package services
+
+import (
+	"appjet-server-daemon/app/models"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Files of a runtime template, in the directory named after the template
+const (
+	templateDescriptorFile = "template.json"
+	templateDockerfileFile = "Dockerfile.tmpl"
+)
+
+// ErrTemplateNotFound is returned when no runtime template builds the language and framework of a
+// configuration
+var ErrTemplateNotFound = errors.New("no runtime template")
+
+// builtinTemplates are the runtime templates shipped with the daemon
+//
+//go:embed templates
+var builtinTemplates embed.FS
+
+// RuntimeTemplate renders the Dockerfile of the application for a language, and a framework when set.
+// A template is a directory with a template.json descriptor and a Dockerfile.tmpl Go text/template.
+type RuntimeTemplate struct {
+	Name        string   `json:"name"`
+	Language    string   `json:"language"`
+	Framework   string   `json:"framework,omitempty"` // any framework of the language when empty
+	Description string   `json:"description,omitempty"`
+	Builders    []string `json:"builders,omitempty"` // builder.name must be one of them when set
+	Source      string   `json:"source"`             // "builtin" or the directory of the template
+
+	dockerfile *template.Template
+}
+
+// TemplateData is what a Dockerfile.tmpl is rendered with
+type TemplateData struct {
+	Config models.Configuration
+
+	// DatabaseAddress is the host:port of the database the application waits for, empty without one
+	DatabaseAddress string
+}
+
+// CodeCheckout returns the Dockerfile instructions fetching the code into dir, with git or from the
+// code uploaded to the workspace
+func (data TemplateData) CodeCheckout(dir string) string {
+	checkout := data.Config.Artifact.CodeCheckout
+
+	var codeCheckoutCmd string
+	if checkout.Git.Enabled {
+		codeCheckoutCmd += fmt.Sprintf("ARG GIT_USERNAME=%s\n", checkout.Git.RepoUser)
+		codeCheckoutCmd += fmt.Sprintf("ARG GIT_PASSWORD=%s\n", checkout.Git.RepoPassword)
+		codeCheckoutCmd += "RUN git config --global credential.helper '!f() { echo \"username=${GIT_USERNAME}\"; echo \"password=${GIT_PASSWORD}\"; }; f'\n"
+		codeCheckoutCmd += fmt.Sprintf("RUN git clone https://${GIT_USERNAME}:${GIT_PASSWORD}@%s %s || (echo \"Git clone failed\"; exit 1)\n", checkout.Git.RepoURL, dir)
+	}
+	if checkout.SCP.Enabled {
+		codeCheckoutCmd += PullCodeFromSCP(checkout.SCP.Configurations.Folder)
+	}
+
+	return codeCheckoutCmd
+}
+
+// BuilderCommand returns the command of the builder of the application, mvn for maven
+func (data TemplateData) BuilderCommand() string {
+	return getBuilderShortName(data.Config.Artifact.Application.Builder.Name)
+}
+
+// TemplatesDir returns the directory of the runtime templates added on this server,
+// APPJET_DAEMON_TEMPLATES_DIR or "templates"
+func TemplatesDir() string {
+	if dir := os.Getenv("APPJET_DAEMON_TEMPLATES_DIR"); dir != "" {
+		return dir
+	}
+
+	return "templates"
+}
+
+// RuntimeTemplates returns the runtime templates sorted by name: the builtin ones and the ones of
+// TemplatesDir, which replace the builtin templates of the same name. The directory is read at every
+// call, so a template is added without restarting the daemon. Invalid templates are logged and skipped.
+func RuntimeTemplates() []RuntimeTemplate {
+	templates := map[string]RuntimeTemplate{}
+
+	builtin, _ := fs.Sub(builtinTemplates, "templates")
+	for _, runtimeTemplate := range loadTemplates(builtin, "builtin") {
+		templates[runtimeTemplate.Name] = runtimeTemplate
+	}
+	if _, err := os.Stat(TemplatesDir()); err == nil {
+		for _, runtimeTemplate := range loadTemplates(os.DirFS(TemplatesDir()), TemplatesDir()) {
+			templates[runtimeTemplate.Name] = runtimeTemplate
+		}
+	}
+
+	sorted := make([]RuntimeTemplate, 0, len(templates))
+	for _, runtimeTemplate := range templates {
+		sorted = append(sorted, runtimeTemplate)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	return sorted
+}
+
+// SelectTemplate returns the runtime template of a language and framework. A template of the framework
+// is preferred to a template of the whole language.
+func SelectTemplate(language string, framework string) (*RuntimeTemplate, error) {
+	var languageTemplate *RuntimeTemplate
+	templates := RuntimeTemplates()
+	for index := range templates {
+		runtimeTemplate := &templates[index]
+		if runtimeTemplate.Language != language {
+			continue
+		}
+		if runtimeTemplate.Framework != "" && runtimeTemplate.Framework == framework {
+			return runtimeTemplate, nil
+		}
+		if runtimeTemplate.Framework == "" && languageTemplate == nil {
+			languageTemplate = runtimeTemplate
+		}
+	}
+	if languageTemplate == nil {
+		return nil, fmt.Errorf("%w for language %q and framework %q", ErrTemplateNotFound, language, framework)
+	}
+
+	return languageTemplate, nil
+}
+
+// ValidateTemplate checks the application of the configuration can be built by a runtime template,
+// with the errors of configuration validation
+func ValidateTemplate(config models.Configuration) models.ValidationErrors {
+	application := config.Artifact.Application
+	field := "artifact.application"
+
+	runtimeTemplate, err := SelectTemplate(application.Language, application.Framework)
+	if err != nil {
+		var languages, frameworks []string
+		for _, runtimeTemplate := range RuntimeTemplates() {
+			if !contains(languages, runtimeTemplate.Language) {
+				languages = append(languages, runtimeTemplate.Language)
+			}
+			if runtimeTemplate.Language == application.Language {
+				frameworks = append(frameworks, runtimeTemplate.Framework)
+			}
+		}
+		// the templates of the language are all for other frameworks
+		if len(frameworks) > 0 {
+			return models.ValidationErrors{{Field: field + ".framework", Message: fmt.Sprintf("no runtime template of %s for framework %q, expected one of %s", application.Language, application.Framework, strings.Join(frameworks, ", "))}}
+		}
+		return models.ValidationErrors{{Field: field + ".language", Message: fmt.Sprintf("no runtime template for language %q, expected one of %s", application.Language, strings.Join(languages, ", "))}}
+	}
+
+	if len(runtimeTemplate.Builders) > 0 && !contains(runtimeTemplate.Builders, application.Builder.Name) {
+		return models.ValidationErrors{{Field: field + ".builder.name", Message: fmt.Sprintf("unsupported builder %q for template %s, expected one of %s", application.Builder.Name, runtimeTemplate.Name, strings.Join(runtimeTemplate.Builders, ", "))}}
+	}
+
+	return nil
+}
+
+// Render renders the Dockerfile of the template for a configuration
+func (t *RuntimeTemplate) Render(config models.Configuration) (string, error) {
+	var dockerfile bytes.Buffer
+	if err := t.dockerfile.Execute(&dockerfile, TemplateData{Config: config, DatabaseAddress: databaseAddress(config)}); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", t.Name, err)
+	}
+
+	return dockerfile.String(), nil
+}
+
+// loadTemplates loads the templates of every directory of templatesFS
+func loadTemplates(templatesFS fs.FS, source string) []RuntimeTemplate {
+	entries, err := fs.ReadDir(templatesFS, ".")
+	if err != nil {
+		log.Printf("Error reading the runtime templates of %s: %s", source, err)
+		return nil
+	}
+
+	var templates []RuntimeTemplate
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		runtimeTemplate, err := loadTemplate(templatesFS, entry.Name())
+		if err != nil {
+			log.Printf("Skipping the runtime template %s of %s: %s", entry.Name(), source, err)
+			continue
+		}
+		runtimeTemplate.Source = source
+		templates = append(templates, *runtimeTemplate)
+	}
+
+	return templates
+}
+
+func loadTemplate(templatesFS fs.FS, name string) (*RuntimeTemplate, error) {
+	descriptor, err := fs.ReadFile(templatesFS, path.Join(name, templateDescriptorFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var runtimeTemplate RuntimeTemplate
+	if err := json.Unmarshal(descriptor, &runtimeTemplate); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", templateDescriptorFile, err)
+	}
+	if runtimeTemplate.Language == "" {
+		return nil, fmt.Errorf("%s has no language", templateDescriptorFile)
+	}
+	runtimeTemplate.Name = name
+
+	dockerfile, err := fs.ReadFile(templatesFS, path.Join(name, templateDockerfileFile))
+	if err != nil {
+		return nil, err
+	}
+	runtimeTemplate.dockerfile, err = template.New(name).Option("missingkey=error").Parse(string(dockerfile))
+	if err != nil {
+		return nil, err
+	}
+
+	return &runtimeTemplate, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
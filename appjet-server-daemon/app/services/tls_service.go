@@ -0,0 +1,156 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// decisionManagerCommonName is the subject of the client certificate of the decision manager
+const decisionManagerCommonName = "appjet-decision-manager"
+
+// TLSDir returns the directory of the daemon key pair and of the CA of the decision manager,
+// APPJET_DAEMON_TLS_DIR or "tls"
+func TLSDir() string {
+	if dir := os.Getenv("APPJET_DAEMON_TLS_DIR"); dir != "" {
+		return dir
+	}
+
+	return "tls"
+}
+
+// TLSFiles returns the paths of the daemon key, CSR, certificate and of the CA certificate
+func TLSFiles() (key string, csr string, certificate string, ca string) {
+	dir := TLSDir()
+	return filepath.Join(dir, "daemon.key"), filepath.Join(dir, "daemon.csr"), filepath.Join(dir, "daemon.crt"), filepath.Join(dir, "ca.crt")
+}
+
+// CreateCertificateRequest writes the CSR to enroll the daemon in the decision manager, for the given
+// host names and addresses. The key is created once and kept when the CSR is created again.
+func CreateCertificateRequest(hosts []string) ([]byte, error) {
+	keyFile, csrFile, _, _ := TLSFiles()
+	if err := os.MkdirAll(TLSDir(), 0700); err != nil {
+		return nil, fmt.Errorf("error creating the tls directory: %w", err)
+	}
+
+	key, err := loadOrCreateKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: hostname, Organization: []string{"appjet"}}}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the certificate request: %w", err)
+	}
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	if err := os.WriteFile(csrFile, csrPEM, 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", csrFile, err)
+	}
+
+	return csrPEM, nil
+}
+
+// ServingTLSConfig returns the TLS configuration the daemon serves with, nil to serve plain HTTP.
+// APPJET_DAEMON_TLS chooses: "on" serves HTTPS only and fails until the daemon is enrolled, "off" serves
+// HTTP, and "auto" (the default) serves HTTPS once the daemon is enrolled and HTTP until then.
+func ServingTLSConfig() (*tls.Config, error) {
+	switch mode := os.Getenv("APPJET_DAEMON_TLS"); mode {
+	case "on":
+		return ServerTLSConfig()
+	case "off":
+		return nil, nil
+	case "", "auto":
+		tlsConfig, err := ServerTLSConfig()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return tlsConfig, err
+	default:
+		return nil, fmt.Errorf("invalid APPJET_DAEMON_TLS %q, expected auto, on or off", mode)
+	}
+}
+
+// ServerTLSConfig returns the TLS configuration of the daemon: it serves its enrolled certificate and
+// only accepts clients presenting the client certificate issued to the decision manager by its CA
+func ServerTLSConfig() (*tls.Config, error) {
+	keyFile, _, certificateFile, caFile := TLSFiles()
+
+	certificate, err := tls.LoadX509KeyPair(certificateFile, keyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("the daemon is not enrolled, run \"appjet-server-daemon tls request\" and enroll it with \"./appjet certs enroll\" (%w)", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading the daemon certificate: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the CA certificate: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s does not contain a PEM certificate", caFile)
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			// the CA also issues the certificates of the other daemons, they must not be able to call this one
+			if len(state.PeerCertificates) == 0 || state.PeerCertificates[0].Subject.CommonName != decisionManagerCommonName {
+				return errors.New("the client certificate was not issued to the decision manager")
+			}
+			return nil
+		},
+	}, nil
+}
+
+func loadOrCreateKey(keyFile string) (*ecdsa.PrivateKey, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	if err == nil {
+		block, _ := pem.Decode(keyPEM)
+		if block == nil {
+			return nil, fmt.Errorf("%s is not a PEM key", keyFile)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating the daemon key: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", keyFile, err)
+	}
+
+	return key, nil
+}
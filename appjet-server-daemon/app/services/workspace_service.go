@@ -0,0 +1,317 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultProject is the project of the calls without ?project=, it works in the directory the daemon
+// runs from, as before projects existed
+const DefaultProject = "default"
+
+// ErrInvalidProject is returned for project names that cannot be used as a directory and compose project
+var ErrInvalidProject = errors.New("invalid project")
+
+// projectNamePattern matches the project names accepted by the decision manager
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// deploymentTimeFormat names the deployment directories, after the project: :project-20060102-150405.000
+const deploymentTimeFormat = "20060102-150405.000"
+
+// defaultKeptDeployments is how many deployments of a project are kept without APPJET_DAEMON_KEEP_DEPLOYMENTS
+const defaultKeptDeployments = 5
+
+// composeNameInvalid matches what docker compose drops from a directory name to make a project name
+var composeNameInvalid = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// Workspace is where the daemon keeps a project. Home holds the scripts and the deployment state of the
+// project, Dir is its current deployment: the configuration, the generated files and the code. Every
+// configure renders a new deployment, the containers of a project are isolated in their own compose project.
+type Workspace struct {
+	Project string
+	Home    string
+	Dir     string
+}
+
+// deploymentState is the deployment.json of a workspace, the deployment its containers run from
+type deploymentState struct {
+	Dir        string    `json:"dir"`
+	DeployedAt time.Time `json:"deployed-at"`
+}
+
+// ProjectsDir returns the directory holding the workspaces of the projects other than the default one,
+// APPJET_DAEMON_PROJECTS_DIR or "projects"
+func ProjectsDir() string {
+	if dir := os.Getenv("APPJET_DAEMON_PROJECTS_DIR"); dir != "" {
+		return dir
+	}
+
+	return "projects"
+}
+
+// KeptDeployments returns how many deployments of a project are kept on disk, the current one included,
+// APPJET_DAEMON_KEEP_DEPLOYMENTS or 5. 0 keeps them all.
+func KeptDeployments() int {
+	if kept, err := strconv.Atoi(os.Getenv("APPJET_DAEMON_KEEP_DEPLOYMENTS")); err == nil && kept >= 0 {
+		return kept
+	}
+
+	return defaultKeptDeployments
+}
+
+// ResolveWorkspace returns the workspace of a project, creating its home when needed. An empty project
+// is the default one. Until its first deployment the workspace works in its home, as before deployments
+// had their own directory.
+func ResolveWorkspace(project string) (Workspace, error) {
+	workspace := Workspace{Project: DefaultProject, Home: "."}
+	if project != "" && project != DefaultProject {
+		if !projectNamePattern.MatchString(project) {
+			return Workspace{}, fmt.Errorf("%w %q: expected lowercase letters, digits, - and _", ErrInvalidProject, project)
+		}
+		workspace = Workspace{Project: project, Home: filepath.Join(ProjectsDir(), project)}
+		if err := os.MkdirAll(workspace.Home, 0755); err != nil {
+			return Workspace{}, fmt.Errorf("failed to create the workspace of project %s: %w", project, err)
+		}
+	}
+
+	workspace.Dir = workspace.Home
+	if state, err := workspace.readDeploymentState(); err == nil {
+		workspace.Dir = state.Dir
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Error reading the deployment of project %s, using its home: %s", workspace.Project, err)
+	}
+
+	return workspace, nil
+}
+
+// Workspaces returns the default workspace followed by the workspaces of the projects deployed to this
+// server
+func Workspaces() []Workspace {
+	workspace, _ := ResolveWorkspace(DefaultProject)
+	workspaces := []Workspace{workspace}
+
+	entries, err := os.ReadDir(ProjectsDir())
+	if err != nil {
+		return workspaces
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && projectNamePattern.MatchString(entry.Name()) && entry.Name() != DefaultProject {
+			if workspace, err := ResolveWorkspace(entry.Name()); err == nil {
+				workspaces = append(workspaces, workspace)
+			}
+		}
+	}
+
+	return workspaces
+}
+
+// Path returns the path of a file of the current deployment of the workspace
+func (w Workspace) Path(name string) string {
+	return filepath.Join(w.Dir, name)
+}
+
+// IsWorkspaceDir reports whether name is a directory inside a deployment: relative, without "..", and not
+// the deployment itself
+func IsWorkspaceDir(name string) bool {
+	clean := filepath.Clean(name)
+	return name != "" && !filepath.IsAbs(name) && clean != "." && clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// HomePath returns the path of a file of the home of the workspace, shared by all its deployments
+func (w Workspace) HomePath(name string) string {
+	return filepath.Join(w.Home, name)
+}
+
+// NewDeployment creates the directory of a new deployment of the project, in folder (the deploy-details
+// folder of the server) or in the deployments directory of its home. The workspace returned renders the
+// deployment, it becomes the current one once activated.
+func (w Workspace) NewDeployment(folder string) (Workspace, error) {
+	if folder == "" {
+		folder = w.HomePath("deployments")
+	}
+
+	dir, err := filepath.Abs(filepath.Join(folder, w.Project+"-"+time.Now().UTC().Format(deploymentTimeFormat)))
+	if err != nil {
+		return Workspace{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return Workspace{}, fmt.Errorf("failed to create the deployments folder %s: %w", folder, err)
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return Workspace{}, fmt.Errorf("failed to create the deployment %s: %w", dir, err)
+	}
+
+	deployment := w
+	deployment.Dir = dir
+	return deployment, nil
+}
+
+// CarryOver copies a directory of the current deployment of w to the deployment, so the code uploaded
+// before a configure is still built. Nothing is copied when the directory does not exist.
+func (w Workspace) CarryOver(deployment Workspace, name string) error {
+	if !IsWorkspaceDir(name) {
+		return nil
+	}
+	source := w.Path(name)
+	if info, err := os.Stat(source); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(w.Dir, path)
+		if err != nil {
+			return err
+		}
+		target := deployment.Path(relative)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, content, info.Mode().Perm())
+	})
+}
+
+// Activate makes the deployment the current one of the project and removes the oldest deployments
+// beyond KeptDeployments. The previous deployments stay on disk for diagnosis.
+func (w Workspace) Activate(deployment Workspace) error {
+	content, err := json.MarshalIndent(deploymentState{Dir: deployment.Dir, DeployedAt: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(w.HomePath("deployment.json"), content, 0644); err != nil {
+		return fmt.Errorf("failed to write the deployment of project %s: %w", w.Project, err)
+	}
+
+	deployment.pruneDeployments()
+	return nil
+}
+
+// pruneDeployments removes the oldest deployments of the project next to the current one. Only the
+// directories named after the project and a deployment time are removed.
+func (w Workspace) pruneDeployments() {
+	kept := KeptDeployments()
+	if kept == 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(w.Dir))
+	if err != nil {
+		return
+	}
+	var deployments []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, w.Project+"-") {
+			continue
+		}
+		if _, err := time.Parse(deploymentTimeFormat, strings.TrimPrefix(name, w.Project+"-")); err == nil {
+			deployments = append(deployments, name)
+		}
+	}
+
+	// the names sort by deployment time, the current deployment is the newest
+	sort.Strings(deployments)
+	for _, name := range deployments[:max(len(deployments)-kept, 0)] {
+		dir := filepath.Join(filepath.Dir(w.Dir), name)
+		if dir == w.Dir {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("Error removing the deployment %s: %s", dir, err)
+		}
+	}
+}
+
+func (w Workspace) readDeploymentState() (*deploymentState, error) {
+	content, err := ioutil.ReadFile(w.HomePath("deployment.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var state deploymentState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, err
+	}
+	if state.Dir == "" {
+		return nil, errors.New("the deployment has no directory")
+	}
+
+	return &state, nil
+}
+
+// IsDefault reports whether this is the workspace of the default project
+func (w Workspace) IsDefault() bool {
+	return w.Project == DefaultProject
+}
+
+// ContainerName returns the name of the container of a service. Outside the default project it is
+// prefixed with the project, so the services of two projects never share a container.
+func (w Workspace) ContainerName(service string) string {
+	if w.IsDefault() {
+		return service
+	}
+
+	return w.Project + "-" + service
+}
+
+// ResolveContainer returns the container of a service of the workspace. The default project also
+// reaches any other container by name, the other projects only reach their own services.
+func (w Workspace) ResolveContainer(name string) (string, bool) {
+	if IsComposeService(w, name) {
+		return w.ContainerName(name), true
+	}
+
+	return name, w.IsDefault()
+}
+
+// ComposeProject returns the compose project of the workspace, the same for all its deployments. The
+// default project keeps the name docker compose gave it after the directory the daemon runs from.
+func (w Workspace) ComposeProject() string {
+	if !w.IsDefault() {
+		return w.Project
+	}
+
+	home, err := filepath.Abs(w.Home)
+	if err != nil {
+		return w.Project
+	}
+	if name := composeNameInvalid.ReplaceAllString(strings.ToLower(filepath.Base(home)), ""); name != "" {
+		return name
+	}
+	return w.Project
+}
+
+// Compose returns a docker compose command run on the current deployment of the workspace, in the
+// compose project of the project
+func (w Workspace) Compose(arguments ...string) *exec.Cmd {
+	dir, err := filepath.Abs(w.Dir)
+	if err != nil {
+		dir = w.Dir
+	}
+	arguments = append([]string{"compose", "-p", w.ComposeProject(), "--project-directory", dir}, arguments...)
+
+	cmd := exec.Command("docker", arguments...)
+	cmd.Dir = dir
+	return cmd
+}
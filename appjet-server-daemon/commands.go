@@ -0,0 +1,129 @@
+package main
+
+import (
+	"appjet-server-daemon/app/services"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const commandsUsage = `Usage:
+  ./appjet-server-daemon                            serve the daemon api
+  ./appjet-server-daemon secret generate            create the secret the decision manager signs its calls with
+  ./appjet-server-daemon secret rotate [--grace 1h]  replace the secret, the previous one is accepted during the grace period
+  ./appjet-server-daemon tls request [--host a,b]   create the certificate request to enroll the daemon in the decision manager
+  ./appjet-server-daemon join --manager URL --token T --cluster C [--name N] [--address A] [--base-path /p] [--host a,b]
+                                                    join the decision manager, then the daemon sends it heartbeats`
+
+// runCommand runs the command line commands of the daemon and returns the exit code
+func runCommand(arguments []string) int {
+	switch {
+	case len(arguments) >= 2 && arguments[0] == "secret":
+		return runSecretCommand(arguments[1:])
+	case len(arguments) >= 2 && arguments[0] == "tls" && arguments[1] == "request":
+		return runTLSRequestCommand(arguments[2:])
+	case len(arguments) >= 1 && arguments[0] == "join":
+		return runJoinCommand(arguments[1:])
+	default:
+		fmt.Println(commandsUsage)
+		return 2
+	}
+}
+
+func runSecretCommand(arguments []string) int {
+	var secret string
+	var err error
+
+	switch arguments[0] {
+	case "generate":
+		secret, err = services.GenerateSecret()
+	case "rotate":
+		flags := flag.NewFlagSet("rotate", flag.ContinueOnError)
+		grace := flags.Duration("grace", time.Hour, "how long the previous secret is still accepted")
+		if err := flags.Parse(arguments[1:]); err != nil {
+			return 2
+		}
+		secret, err = services.RotateSecret(*grace)
+	default:
+		fmt.Println(commandsUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+
+	fmt.Println("Secret written to", services.SecretFile())
+	fmt.Println(secret)
+	fmt.Println("Register it in the decision manager with: ./appjet secrets set :cluster :server")
+	return 0
+}
+
+func runTLSRequestCommand(arguments []string) int {
+	flags := flag.NewFlagSet("request", flag.ContinueOnError)
+	hosts := flags.String("host", "", "comma separated names and addresses the daemon is reached at, the address in the configuration is always added")
+	if err := flags.Parse(arguments); err != nil {
+		return 2
+	}
+
+	csr, err := services.CreateCertificateRequest(strings.Split(*hosts, ","))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+
+	keyFile, csrFile, certificateFile, caFile := services.TLSFiles()
+	fmt.Print(string(csr))
+	fmt.Println("Key written to", keyFile, "and certificate request to", csrFile)
+	fmt.Println("Enroll the daemon with: ./appjet certs enroll :cluster :server --csr", csrFile)
+	fmt.Println("then copy the daemon.crt and ca.crt it writes to", certificateFile, "and", caFile)
+	return 0
+}
+
+func runJoinCommand(arguments []string) int {
+	hostname, _ := os.Hostname()
+
+	flags := flag.NewFlagSet("join", flag.ContinueOnError)
+	manager := flags.String("manager", "", "url of the decision manager, e.g. https://appjet.internal")
+	token := flags.String("token", "", "join token created with ./appjet join-tokens create")
+	cluster := flags.String("cluster", "", "cluster the server joins")
+	name := flags.String("name", hostname, "name of the server in the cluster")
+	address := flags.String("address", "", "address the decision manager reaches the daemon at, the address the join comes from when empty")
+	basePath := flags.String("base-path", "", "path prefix of the daemon behind a reverse proxy")
+	hosts := flags.String("host", "", "comma separated names and addresses added to the daemon certificate")
+	if err := flags.Parse(arguments); err != nil {
+		return 2
+	}
+	if *manager == "" || *token == "" || *cluster == "" || *name == "" {
+		fmt.Println(commandsUsage)
+		return 2
+	}
+
+	port, err := strconv.Atoi(os.Getenv("port"))
+	if err != nil {
+		port = 8080
+	}
+
+	state, err := services.Join(services.JoinRequest{
+		Manager:  *manager,
+		Token:    *token,
+		Cluster:  *cluster,
+		Name:     *name,
+		Address:  *address,
+		Port:     port,
+		BasePath: *basePath,
+		Hosts:    strings.Split(*hosts, ","),
+	})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+
+	fmt.Println("Joined cluster", state.Cluster, "as", state.Server)
+	fmt.Println("Secret, certificate and join state written to", services.SecretFile()+",", services.TLSDir(), "and", services.JoinFile())
+	fmt.Println("Start the daemon, it sends a heartbeat to", state.Manager, "every", state.HeartbeatInterval)
+	return 0
+}
@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/docker/docker v25.0.1+incompatible
 	github.com/gin-gonic/gin v1.9.1
+	golang.org/x/net v0.20.0
 )
 
 require (
@@ -50,7 +51,6 @@ require (
 	go.opentelemetry.io/otel/trace v1.22.0 // indirect
 	golang.org/x/arch v0.7.0 // indirect
 	golang.org/x/crypto v0.18.0 // indirect
-	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/time v0.5.0 // indirect
@@ -3,13 +3,42 @@ package main
 
 import (
 	commandhandler "appjet-server-daemon/app/handlers"
+	"appjet-server-daemon/app/services"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
 )
 
+// version is reported in the heartbeats, set at build time with -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// every call must be signed by the decision manager, the daemon does not serve without a secret
+	if err := services.LoadSecret(); err != nil {
+		log.Fatal("Cannot load the daemon secret: ", err)
+	}
+
+	// once enrolled the daemon serves HTTPS, to clients presenting the certificate of the decision manager
+	tlsConfig, err := services.ServingTLSConfig()
+	if err != nil {
+		log.Fatal("Cannot load the daemon certificate: ", err)
+	}
+	if tlsConfig == nil {
+		log.Print("The daemon is not enrolled (or APPJET_DAEMON_TLS=off), serving plain HTTP")
+	}
+
+	// a daemon that joined a decision manager reports its state to it
+	if err := services.StartHeartbeats(version); err != nil && !errors.Is(err, services.ErrNotJoined) {
+		log.Fatal("Cannot start the heartbeats: ", err)
+	}
+
 	r := gin.Default()
 
 	portStr := os.Getenv("port")
@@ -23,6 +52,7 @@ func main() {
 	}
 
 	apiGroup := r.Group("/api")
+	apiGroup.Use(commandhandler.SignatureMiddlewareHandler, commandhandler.WorkspaceMiddlewareHandler)
 	{
 		//check if alive containers endpoint
 		apiGroup.GET("/check-alive", commandhandler.CheckAlive)
@@ -31,6 +61,8 @@ func main() {
 		apiGroup.POST("/configure", commandhandler.ConfigureHandler)
 		//returns the config.json present in the current daemon
 		apiGroup.GET("/inspect", commandhandler.InspectHandler)
+		//list the runtime templates the Dockerfile of the application is built with
+		apiGroup.GET("/templates", commandhandler.TemplatesHandler)
 
 		//start all configured docker containers
 		apiGroup.GET("/start", commandhandler.StartHandler)
@@ -46,6 +78,14 @@ func main() {
 		//stop a specific container
 		apiGroup.GET("/stop/:container", commandhandler.StopContainerHandler)
 
+		//stream the logs of a container, ?follow=true keeps the stream open
+		apiGroup.GET("/logs/:container", commandhandler.LogsHandler)
+
+		//run a one-shot command in a container and return its output and exit code
+		apiGroup.POST("/exec/:container", commandhandler.ExecHandler)
+		//run an interactive command in a container, attached to a TTY over a websocket
+		apiGroup.GET("/exec/:container/tty", commandhandler.ExecTTYHandler)
+
 		//clean all docker images, containers and volumes in this server (docker system prune -a)
 		apiGroup.GET("/clean", commandhandler.CleanHandler)
 
@@ -59,7 +99,18 @@ func main() {
 		apiGroup.POST("/code", commandhandler.SCPCodeHandler)
 	}
 
-	err = r.Run(":" + strconv.Itoa(port))
+	server := &http.Server{
+		Addr:      ":" + strconv.Itoa(port),
+		Handler:   r,
+		TLSConfig: tlsConfig,
+	}
+
+	if tlsConfig == nil {
+		err = server.ListenAndServe()
+	} else {
+		// the certificate and key are already in TLSConfig
+		err = server.ListenAndServeTLS("", "")
+	}
 	if err != nil {
 		print(err)
 		return